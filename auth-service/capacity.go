@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// --- Capacity Metrics ---
+
+const capacityRetention = 30 * 24 * time.Hour
+
+type hourStats struct {
+	Hour          time.Time `json:"hour"`
+	Logins        int64     `json:"logins"`
+	FailedLogins  int64     `json:"failed_logins"`
+	TokensIssued  int64     `json:"tokens_issued"`
+	Validations   int64     `json:"validations"`
+	PeakValidQPS  int64     `json:"peak_validation_qps"`
+	curSecond     int64
+	curSecondHits int64
+}
+
+type dayStats struct {
+	Day          string `json:"day"`
+	Logins       int64  `json:"logins"`
+	FailedLogins int64  `json:"failed_logins"`
+	TokensIssued int64  `json:"tokens_issued"`
+	Validations  int64  `json:"validations"`
+}
+
+type CapacityReport struct {
+	From                time.Time    `json:"from"`
+	To                  time.Time    `json:"to"`
+	TotalLogins         int64        `json:"total_logins"`
+	TotalTokensIssued   int64        `json:"total_tokens_issued"`
+	TotalValidations    int64        `json:"total_validations"`
+	AvgTokensPerHour    float64      `json:"avg_tokens_per_hour"`
+	AvgValidationQPS    float64      `json:"avg_validation_qps"`
+	PeakValidationQPS   int64        `json:"peak_validation_qps"`
+	PeakLoginHour       *time.Time   `json:"peak_login_hour,omitempty"`
+	PeakLoginHourLogins int64        `json:"peak_login_hour_logins"`
+	Hourly              []*hourStats `json:"hourly"`
+	Daily               []dayStats   `json:"daily"`
+}
+
+var (
+	capacityMu sync.Mutex
+	hourly     = make(map[int64]*hourStats) // Key: hour start as unix seconds
+)
+
+// bucketFor returns the stats bucket for now, pruning expired buckets.
+// Callers must hold capacityMu.
+func bucketFor(now time.Time) *hourStats {
+	hour := now.UTC().Truncate(time.Hour)
+	key := hour.Unix()
+	b, ok := hourly[key]
+	if !ok {
+		b = &hourStats{Hour: hour}
+		hourly[key] = b
+		cutoff := hour.Add(-capacityRetention).Unix()
+		for k := range hourly {
+			if k < cutoff {
+				delete(hourly, k)
+			}
+		}
+	}
+	return b
+}
+
+func recordLogin(success bool) {
+	if success {
+		loginsTotal.WithLabelValues("success").Inc()
+	} else {
+		loginsTotal.WithLabelValues("failure").Inc()
+	}
+	capacityMu.Lock()
+	defer capacityMu.Unlock()
+	b := bucketFor(time.Now())
+	if success {
+		b.Logins++
+		b.TokensIssued++
+	} else {
+		b.FailedLogins++
+	}
+}
+
+func recordValidation() {
+	validationsTotal.Inc()
+	now := time.Now()
+	capacityMu.Lock()
+	defer capacityMu.Unlock()
+	b := bucketFor(now)
+	b.Validations++
+	if sec := now.Unix(); sec != b.curSecond {
+		b.curSecond = sec
+		b.curSecondHits = 0
+	}
+	b.curSecondHits++
+	if b.curSecondHits > b.PeakValidQPS {
+		b.PeakValidQPS = b.curSecondHits
+	}
+}
+
+// capacityReport summarizes login and validation load for registration-week planning.
+func capacityReport(w http.ResponseWriter, r *http.Request) {
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days < 1 || days > 30 {
+			http.Error(w, "days must be between 1 and 30", http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	report := CapacityReport{From: now.Add(-time.Duration(days) * 24 * time.Hour).Truncate(time.Hour), To: now, Hourly: []*hourStats{}}
+	daily := map[string]*dayStats{}
+
+	capacityMu.Lock()
+	for _, b := range hourly {
+		if b.Hour.Before(report.From) {
+			continue
+		}
+		snapshot := *b
+		report.Hourly = append(report.Hourly, &snapshot)
+
+		report.TotalLogins += b.Logins
+		report.TotalTokensIssued += b.TokensIssued
+		report.TotalValidations += b.Validations
+		if b.PeakValidQPS > report.PeakValidationQPS {
+			report.PeakValidationQPS = b.PeakValidQPS
+		}
+		if b.Logins > report.PeakLoginHourLogins {
+			report.PeakLoginHourLogins = b.Logins
+			report.PeakLoginHour = &snapshot.Hour
+		}
+
+		day := b.Hour.Format("2006-01-02")
+		d, ok := daily[day]
+		if !ok {
+			d = &dayStats{Day: day}
+			daily[day] = d
+		}
+		d.Logins += b.Logins
+		d.FailedLogins += b.FailedLogins
+		d.TokensIssued += b.TokensIssued
+		d.Validations += b.Validations
+	}
+	capacityMu.Unlock()
+
+	sort.Slice(report.Hourly, func(i, j int) bool { return report.Hourly[i].Hour.Before(report.Hourly[j].Hour) })
+	for _, d := range daily {
+		report.Daily = append(report.Daily, *d)
+	}
+	sort.Slice(report.Daily, func(i, j int) bool { return report.Daily[i].Day < report.Daily[j].Day })
+
+	elapsed := now.Sub(report.From)
+	report.AvgTokensPerHour = float64(report.TotalTokensIssued) / elapsed.Hours()
+	report.AvgValidationQPS = float64(report.TotalValidations) / elapsed.Seconds()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
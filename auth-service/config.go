@@ -0,0 +1,23 @@
+package main
+
+import (
+	"slices"
+
+	"shared/config"
+	"shared/logging"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+)
+
+// --- Configuration ---
+
+// settings are everything the Auth Service can be configured with. Run it
+// with dump-config to see the values it would use.
+var settings = slices.Concat(logging.Settings, server.Settings, mtls.Settings, studentid.Settings, []config.Setting{
+	{Name: "PORT", Default: "8081", Usage: "Port to listen on", Check: config.IntBetween(1, 65535)},
+	{Name: "JWT_SECRET", Default: "fallback_secret_for_local_testing", Usage: "Key that signs tokens", Secret: true},
+	{Name: "OIDC_ISSUER_URL", Default: "http://localhost:8081", Usage: "This service's public URL, named in ID tokens", Check: config.ValidURL},
+	{Name: "PORTAL_URL", Default: "http://localhost:8080", Usage: "The portal's public URL, where users log in and consent", Check: config.ValidURL},
+	{Name: "STUDENT_ID_SEQ_PATH", Usage: "File the last issued student ID numbers are kept in; unset keeps them in memory, and a restart issues them again"},
+})
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"shared/config"
+	"shared/studentid"
+)
+
+// --- Student IDs ---
+
+var (
+	idSeqMu sync.Mutex
+	idSeq   = make(map[string]int) // Tenant -> last issued sequence number
+)
+
+// loadIDSeq reads the sequence numbers kept at STUDENT_ID_SEQ_PATH, so that
+// a restart does not issue the same IDs again. It must run before the
+// server starts.
+func loadIDSeq() {
+	path := config.Get("STUDENT_ID_SEQ_PATH")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("cannot read the student ID sequence: %v", err)
+	}
+	if err := json.Unmarshal(data, &idSeq); err != nil {
+		log.Fatalf("cannot read the student ID sequence %s: %v", path, err)
+	}
+}
+
+// saveIDSeq writes the sequence numbers to STUDENT_ID_SEQ_PATH, replacing
+// the file whole so that a crash leaves the old or the new numbers.
+func saveIDSeq() error {
+	path := config.Get("STUDENT_ID_SEQ_PATH")
+	if path == "" {
+		return nil
+	}
+	idSeqMu.Lock()
+	data, _ := json.Marshal(idSeq)
+	idSeqMu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// nextStudentID issues the next unused ID for a tenant from its prefix,
+// zero-padded sequence, and optional check digit.
+func nextStudentID(tenant string) (string, error) {
+	f, ok := studentid.Lookup(tenant)
+	if !ok {
+		return "", fmt.Errorf("unknown tenant %q", tenant)
+	}
+
+	idSeqMu.Lock()
+	defer idSeqMu.Unlock()
+
+	for attempts := 0; attempts < 100000; attempts++ {
+		idSeq[tenant]++
+		id := f.Prefix + fmt.Sprintf("%0*d", f.Width, idSeq[tenant])
+		if f.CheckDigit == "luhn" {
+			id += string(studentid.LuhnDigit(id))
+		}
+		if _, taken := lookupUser(id); taken {
+			continue
+		}
+		if err := studentid.Validate(tenant, id); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("no free student IDs for tenant %q", tenant)
+}
+
+type GenerateIDsRequest struct {
+	Count  int      `json:"count"`
+	Emails []string `json:"emails"` // One ID per admitted student's email; sets Count
+}
+
+// generateStudentIDs lets an admin reserve IDs for newly admitted students.
+// IDs issued with an email are admissions: the student can sign up for the
+// ID themselves, verifying with that email.
+func generateStudentIDs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "Forbidden: Only admins can generate student IDs", http.StatusForbidden)
+		return
+	}
+
+	req := GenerateIDsRequest{Count: 1}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Emails) > 0 {
+		req.Count = len(req.Emails)
+		for _, email := range req.Emails {
+			if err := validateEmail(email); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if req.Count < 1 || req.Count > 500 {
+		http.Error(w, "count must be between 1 and 500", http.StatusBadRequest)
+		return
+	}
+
+	tenant := studentid.Tenant(r)
+	ids := make([]string, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		id, err := nextStudentID(tenant)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+	// No ID goes out before its number is kept
+	if err := saveIDSeq(); err != nil {
+		http.Error(w, "Cannot record the issued IDs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(req.Emails) > 0 {
+		admissionsMu.Lock()
+		for i, id := range ids {
+			admissions[id] = req.Emails[i]
+		}
+		admissionsMu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tenant": tenant, "ids": ids})
+}
@@ -2,22 +2,28 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"shared/auth"
+	"shared/config"
+	"shared/health"
+	"shared/logging"
+	"shared/metrics"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+	"shared/tracing"
 )
 
 func getJWTKey() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return []byte("fallback_secret_for_local_testing")
-	}
-	return []byte(secret)
+	return []byte(config.Get("JWT_SECRET"))
 }
 
 // --- Models ---
@@ -27,21 +33,43 @@ type Credentials struct {
 }
 
 type Claims struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Program    string `json:"program,omitempty"`
+	YearLevel  int    `json:"year_level,omitempty"`
+	AuthTime   int64  `json:"auth_time,omitempty"`    // When the user logged in; kept across refreshes
+	AuthTimeUs int64  `json:"auth_time_us,omitempty"` // The same in microseconds, to tell logins from revocations
 	jwt.RegisteredClaims
 }
 
+type StudentProfile struct {
+	Program   string
+	YearLevel int
+	Email     string
+	Advisor   string
+}
+
 // --- Data ---
 var users = map[string]string{
-	"student1": "pass123",
-	"student2": "pass123",
-	"faculty1": "pass123",
+	"student1":   "pass123",
+	"student2":   "pass123",
+	"faculty1":   "pass123",
+	"admin1":     "pass123",
+	"registrar1": "pass123",
 }
 var roles = map[string]string{
-	"student1": "student",
-	"student2": "student",
-	"faculty1": "faculty",
+	"student1":   "student",
+	"student2":   "student",
+	"faculty1":   "faculty",
+	"admin1":     "admin",
+	"registrar1": "registrar",
+}
+
+// Program and year level travel in the token so other nodes can apply
+// cohort rules (e.g. reserved seats) without a registrar lookup.
+var profiles = map[string]StudentProfile{
+	"student1": {Program: "BSCS", YearLevel: 2, Email: "student1@example.edu", Advisor: "faculty1"},
+	"student2": {Program: "BSIT", YearLevel: 1, Email: "student2@example.edu", Advisor: "faculty1"},
 }
 
 func login(w http.ResponseWriter, r *http.Request) {
@@ -51,39 +79,66 @@ func login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expectedPassword, ok := users[creds.Username]
-	if !ok || expectedPassword != creds.Password {
+	account, ok := lookupUser(creds.Username)
+	if !ok || !checkPassword(creds.Username, creds.Password) {
+		recordLogin(false)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	logging.SetUser(r.Context(), account.Username)
 
-	// Token valid for 1 HOUR
-	expirationTime := time.Now().Add(1 * time.Hour)
+	tokenString, _, err := issueToken(account, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	recordLogin(true)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"token": "` + tokenString + `", "role": "` + account.Role + `"}`))
+}
+
+// issueToken signs a tokenTTL token for an account. authTime is when the
+// user logged in.
+func issueToken(account UserAccount, authTime time.Time) (string, time.Time, error) {
+	expirationTime := time.Now().Add(tokenTTL)
 	claims := &Claims{
-		Username: creds.Username,
-		Role:     roles[creds.Username],
+		Username:   account.Username,
+		Role:       account.Role,
+		Program:    account.Program,
+		YearLevel:  account.YearLevel,
+		AuthTime:   authTime.Unix(),
+		AuthTimeUs: authTime.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(getJWTKey())
+	return token, expirationTime, err
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(getJWTKey())
+// claimsFromRequest parses and verifies the bearer token on a request. Only
+// session tokens pass: an OIDC access token names its client as audience
+// and is good for /oidc/userinfo alone.
+func claimsFromRequest(r *http.Request) (*Claims, error) {
+	claims, err := bearerClaims(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"token": "` + tokenString + `", "role": "` + roles[creds.Username] + `"}`))
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("token was issued to an OIDC client")
+	}
+	logging.SetUser(r.Context(), claims.Username)
+	return claims, nil
 }
 
-func validate(w http.ResponseWriter, r *http.Request) {
+// bearerClaims parses and verifies the bearer token on a request, whoever
+// it was issued to.
+func bearerClaims(r *http.Request) (*Claims, error) {
 	// 1. Get token from Header (Authorization: Bearer <token>)
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return nil, errors.New("missing token")
 	}
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
@@ -92,22 +147,78 @@ func validate(w http.ResponseWriter, r *http.Request) {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return getJWTKey(), nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if revoked(claims) {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
+}
 
-	if err != nil || !token.Valid {
+func validate(w http.ResponseWriter, r *http.Request) {
+	recordValidation()
+	claims, err := claimsFromRequest(r)
+	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized) // Token expired or invalid
 		return
 	}
 
 	// 3. Token is good
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status": "valid", "username": "` + claims.Username + `", "role": "` + claims.Role + `"}`))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.User{
+		Status:    "valid",
+		Username:  claims.Username,
+		Role:      claims.Role,
+		Program:   claims.Program,
+		YearLevel: claims.YearLevel,
+	})
+}
+
+// readyz always reports ok: the Auth Service signs and verifies tokens locally
+// and has no downstream dependencies.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	health.Write(w, health.NewReport(map[string]health.DependencyStatus{}))
 }
 
 func main() {
+	config.Load("auth", settings...)
+	logging.Setup("auth")
+	mtls.Setup()
+	tracing.Setup("auth")
 	mux := http.NewServeMux()
 	mux.HandleFunc("/login", login)
 	mux.HandleFunc("/validate", validate) // Register the new route
-
-	fmt.Println("Node 2 (Auth Service) running on port 8081...")
-	log.Fatal(http.ListenAndServe("0.0.0.0:8081", mux))
+	mux.HandleFunc("/refresh", refresh)
+	mux.HandleFunc("/sessions/revoke", revokeSessions)
+	mux.HandleFunc("/admin/student-ids", generateStudentIDs)
+	mux.HandleFunc("/admin/users", usersHandler)
+	mux.HandleFunc("/profile", profileHandler)
+	mux.HandleFunc("/password", changePassword)
+	mux.HandleFunc("/register", register)
+	mux.HandleFunc("/register/verify", verifySignup)
+	mux.HandleFunc("/healthz", health.Liveness)
+	mux.HandleFunc("/readyz", readyz)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/reports/capacity", capacityReport)
+	mux.HandleFunc("/oidc/clients", oidcClientsHandler)
+	mux.HandleFunc("/oidc/clients/info", oidcClientInfo)
+	mux.HandleFunc("/oidc/authorize", authorize)
+	mux.HandleFunc("/oidc/token", token)
+	mux.HandleFunc("/oidc/userinfo", userinfo)
+	mux.HandleFunc("/.well-known/openid-configuration", discovery)
+
+	studentid.Setup()
+	loadIDSeq()
+	server.OnShutdown("tracing", tracing.Shutdown)
+
+	port := config.Get("PORT")
+	slog.Info("Node 2 (Auth Service) running", "port", port)
+	srv := &http.Server{Addr: "0.0.0.0:" + port, Handler: tracing.Middleware(logging.Middleware(metrics.Middleware(mtls.Middleware(mux))))}
+	if err := mtls.Run(srv); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// --- Prometheus Metrics ---
+
+// The same events the capacity report counts, as counters for /metrics.
+
+var (
+	loginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Login attempts, by result (success or failure).",
+	}, []string{"result"})
+
+	validationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_token_validations_total",
+		Help: "Tokens checked at /validate.",
+	})
+)
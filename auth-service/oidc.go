@@ -0,0 +1,444 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"shared/config"
+)
+
+// --- OpenID Connect Provider ---
+
+// Small campus apps register as clients and sign users in with the
+// authorization code flow:
+//
+//  1. The app sends the browser to GET /oidc/authorize.
+//  2. We check the client and redirect URI, then hand the browser to the
+//     portal's consent screen, which logs the user in if needed.
+//  3. The portal posts the user's decision back to POST /oidc/authorize and
+//     redirects the browser to the app with a one-time code.
+//  4. The app exchanges the code at /oidc/token for an ID token (HS256,
+//     signed with the client secret) and an access token for /oidc/userinfo.
+//
+// Access tokens name the client as audience. Session tokens have none, and
+// /validate and every other endpoint refuse a token that has one, so an
+// app's access token never works as the user's session.
+
+type OIDCClient struct {
+	ID           string    `json:"client_id"`
+	Secret       string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ClientRegistration struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+// AuthorizationRequest carries the query of /oidc/authorize through the
+// consent screen and back.
+type AuthorizationRequest struct {
+	ClientID    string `json:"client_id"`
+	RedirectURI string `json:"redirect_uri"`
+	Scope       string `json:"scope"`
+	State       string `json:"state"`
+	Nonce       string `json:"nonce"`
+	Approve     bool   `json:"approve"`
+}
+
+type authCode struct {
+	ClientID    string
+	RedirectURI string
+	Username    string
+	Scope       string
+	Nonce       string
+	AuthTime    int64 // When the user logged in, from their session token
+	AuthTimeUs  int64
+	ExpiresAt   time.Time
+}
+
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username"`
+	Role              string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+const authCodeTTL = 60 * time.Second
+
+var (
+	oidcMu      sync.Mutex
+	oidcClients = make(map[string]*OIDCClient) // Key: client ID
+	authCodes   = make(map[string]*authCode)   // Key: code, single use
+)
+
+func issuerURL() string {
+	return strings.TrimSuffix(config.Get("OIDC_ISSUER_URL"), "/")
+}
+
+func portalURL() string {
+	return strings.TrimSuffix(config.Get("PORTAL_URL"), "/")
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// checkAuthorization validates the client, redirect URI and scope. Errors here
+// must be shown to the user, never redirected to an unverified URI.
+func checkAuthorization(req *AuthorizationRequest) string {
+	oidcMu.Lock()
+	client, ok := oidcClients[req.ClientID]
+	oidcMu.Unlock()
+	if !ok {
+		return "Unknown client"
+	}
+	registered := false
+	for _, uri := range client.RedirectURIs {
+		if uri == req.RedirectURI {
+			registered = true
+		}
+	}
+	if !registered {
+		return "redirect_uri is not registered for this client"
+	}
+	if !strings.Contains(" "+req.Scope+" ", " openid ") {
+		return "scope must include openid"
+	}
+	return ""
+}
+
+func redirectWith(base string, params url.Values) string {
+	if strings.Contains(base, "?") {
+		return base + "&" + params.Encode()
+	}
+	return base + "?" + params.Encode()
+}
+
+// oidcClientsHandler registers (POST) and lists (GET) relying parties. Admin only.
+func oidcClientsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		oidcMu.Lock()
+		list := []OIDCClient{}
+		for _, c := range oidcClients {
+			listed := *c
+			listed.Secret = "" // Only shown once, at registration
+			list = append(list, listed)
+		}
+		oidcMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var reg ClientRegistration
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if reg.Name == "" || len(reg.RedirectURIs) == 0 {
+			http.Error(w, "name and redirect_uris are required", http.StatusBadRequest)
+			return
+		}
+		for _, uri := range reg.RedirectURIs {
+			u, err := url.Parse(uri)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.Fragment != "" {
+				http.Error(w, "Invalid redirect URI: "+uri, http.StatusBadRequest)
+				return
+			}
+		}
+
+		client := &OIDCClient{
+			ID:           randomToken(12),
+			Secret:       randomToken(24),
+			Name:         reg.Name,
+			RedirectURIs: reg.RedirectURIs,
+			CreatedBy:    claims.Username,
+			CreatedAt:    time.Now().UTC(),
+		}
+		oidcMu.Lock()
+		oidcClients[client.ID] = client
+		oidcMu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// oidcClientInfo exposes a client's display name for the consent screen.
+func oidcClientInfo(w http.ResponseWriter, r *http.Request) {
+	oidcMu.Lock()
+	client, ok := oidcClients[r.URL.Query().Get("client_id")]
+	oidcMu.Unlock()
+	if !ok {
+		http.Error(w, "Unknown client", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"client_id": client.ID, "name": client.Name})
+}
+
+// authorize starts the flow (GET, from the app) or records the user's
+// consent decision (POST, from the portal with the user's token).
+func authorize(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req := AuthorizationRequest{
+			ClientID:    q.Get("client_id"),
+			RedirectURI: q.Get("redirect_uri"),
+			Scope:       q.Get("scope"),
+			State:       q.Get("state"),
+			Nonce:       q.Get("nonce"),
+		}
+		if msg := checkAuthorization(&req); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if q.Get("response_type") != "code" {
+			http.Redirect(w, r, redirectWith(req.RedirectURI, url.Values{"error": {"unsupported_response_type"}, "state": {req.State}}), http.StatusFound)
+			return
+		}
+		consent := url.Values{
+			"client_id":    {req.ClientID},
+			"redirect_uri": {req.RedirectURI},
+			"scope":        {req.Scope},
+			"state":        {req.State},
+			"nonce":        {req.Nonce},
+		}
+		http.Redirect(w, r, portalURL()+"/oidc/consent?"+consent.Encode(), http.StatusFound)
+
+	case http.MethodPost:
+		claims, err := claimsFromRequest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req AuthorizationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if msg := checkAuthorization(&req); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		params := url.Values{}
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		if !req.Approve {
+			params.Set("error", "access_denied")
+		} else {
+			code := randomToken(16)
+			now := time.Now()
+			oidcMu.Lock()
+			dropExpiredCodes(now)
+			authCodes[code] = &authCode{
+				ClientID:    req.ClientID,
+				RedirectURI: req.RedirectURI,
+				Username:    claims.Username,
+				Scope:       req.Scope,
+				Nonce:       req.Nonce,
+				AuthTime:    claims.AuthTime,
+				AuthTimeUs:  claims.AuthTimeUs,
+				ExpiresAt:   now.Add(authCodeTTL),
+			}
+			oidcMu.Unlock()
+			params.Set("code", code)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"redirect": redirectWith(req.RedirectURI, params)})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// dropExpiredCodes forgets codes that were never redeemed. Only codes issued
+// within authCodeTTL are live, so there are few to look at. Callers must hold
+// oidcMu.
+func dropExpiredCodes(now time.Time) {
+	for code, c := range authCodes {
+		if now.After(c.ExpiresAt) {
+			delete(authCodes, code)
+		}
+	}
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error": "` + code + `"}`))
+}
+
+// token exchanges an authorization code for an ID token and access token.
+func token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 1. Authenticate the client (HTTP Basic or form fields)
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	client, ok := oidcClients[clientID]
+	if !ok || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(secret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.FormValue("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// 2. Redeem the code exactly once
+	code, ok := authCodes[r.FormValue("code")]
+	delete(authCodes, r.FormValue("code"))
+	if !ok || time.Now().After(code.ExpiresAt) || code.ClientID != clientID || code.RedirectURI != r.FormValue("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	account, ok := lookupUser(code.Username)
+	if !ok {
+		tokenError(w, http.StatusBadRequest, "invalid_grant") // Account deleted since the code was issued
+		return
+	}
+
+	// 3. Mint the tokens
+	now := time.Now()
+	expires := now.Add(1 * time.Hour)
+	access := &Claims{
+		Username:   code.Username,
+		Role:       account.Role,
+		AuthTime:   code.AuthTime, // Revoking the login's sessions revokes the app's token too
+		AuthTimeUs: code.AuthTimeUs,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuerURL(),
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, access).SignedString(getJWTKey())
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	id := &IDTokenClaims{
+		Nonce:             code.Nonce,
+		PreferredUsername: code.Username,
+		Role:              account.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuerURL(),
+			Subject:   code.Username,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, id).SignedString([]byte(client.Secret))
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	recordLogin(true)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(expires).Seconds()),
+		"id_token":     idToken,
+		"scope":        code.Scope,
+	})
+}
+
+// accessClaimsFromRequest verifies the OIDC access token on a request: one
+// issued to a client that is still registered.
+func accessClaimsFromRequest(r *http.Request) (*Claims, error) {
+	claims, err := bearerClaims(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims.Audience) != 1 {
+		return nil, errors.New("not an OIDC access token")
+	}
+	oidcMu.Lock()
+	_, ok := oidcClients[claims.Audience[0]]
+	oidcMu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown client")
+	}
+	return claims, nil
+}
+
+func userinfo(w http.ResponseWriter, r *http.Request) {
+	claims, err := accessClaimsFromRequest(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"sub":                claims.Username,
+		"preferred_username": claims.Username,
+		"role":               claims.Role,
+	})
+}
+
+func discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := issuerURL()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oidc/authorize",
+		"token_endpoint":                        issuer + "/oidc/token",
+		"userinfo_endpoint":                     issuer + "/oidc/userinfo",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"HS256"},
+		"scopes_supported":                      []string{"openid", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"claims_supported":                      []string{"sub", "preferred_username", "role", "nonce"},
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+)
+
+// --- Profiles ---
+
+// Every user can read their own profile at /profile. The contact email and
+// password are theirs to change; program, year level and advisor are
+// registrar data and only change through /admin/users.
+
+// profileHandler returns (GET) or updates (PUT) the caller's profile.
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		account, ok := lookupUser(claims.Username)
+		if !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(account)
+
+	case http.MethodPut:
+		var update struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateEmail(update.Email); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		usersMu.Lock()
+		defer usersMu.Unlock()
+		if _, ok := users[claims.Username]; !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		p := profiles[claims.Username]
+		p.Email = update.Email
+		profiles[claims.Username] = p
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "profile updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// changePassword sets the caller's password (POST /password) once they
+// prove they know the current one. Tokens already issued stay valid.
+func changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var change PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(change.NewPassword) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("The new password must have at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	current, ok := users[claims.Username]
+	if !ok {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if current != change.CurrentPassword {
+		http.Error(w, "The current password is wrong", http.StatusForbidden)
+		return
+	}
+	users[claims.Username] = change.NewPassword
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "password changed"}`))
+}
+
+// validateEmail accepts a bare address such as "ana@example.edu".
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("email must be a plain address such as name@example.edu")
+	}
+	return nil
+}
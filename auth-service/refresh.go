@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// --- Token Refresh ---
+
+// Tokens last an hour. Before one lapses its holder can trade it at
+// POST /refresh for a new one, so a user who keeps working stays logged in.
+// The new token is built from the account as it is now, so a changed role
+// or program takes effect, and a removed account cannot refresh. Refreshing
+// never stretches a login past maxSessionAge; after that the user must log
+// in again.
+
+const (
+	tokenTTL      = 1 * time.Hour
+	maxSessionAge = 12 * time.Hour
+)
+
+// refresh exchanges a valid token for a fresh one.
+func refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized) // Token expired or invalid
+		return
+	}
+	authTime := loginTime(claims)
+	if claims.AuthTime == 0 || time.Since(authTime) > maxSessionAge {
+		http.Error(w, "Session too old: log in again", http.StatusUnauthorized)
+		return
+	}
+	account, ok := lookupUser(claims.Username)
+	if !ok {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	token, expires, err := issueToken(account, authTime)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"role":       account.Role,
+		"expires_in": int(time.Until(expires).Seconds()),
+	})
+}
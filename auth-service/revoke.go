@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- Session Revocation ---
+
+// POST /sessions/revoke logs the caller out everywhere: every token from a
+// login up to now stops validating, refreshed ones included, since they keep
+// the login's auth_time. Logging in again starts a new session, even within
+// the same second: the cutoff and the login time are kept to the
+// microsecond. Cutoffs are kept in memory, so a restart forgets them; tokens
+// still expire within the hour.
+
+var (
+	revokedMu     sync.Mutex
+	revokedBefore = make(map[string]time.Time) // Username -> logins before this are revoked
+)
+
+// loginTime is when a token's session began. Tokens from before auth_time_us
+// only have the second, and are taken to be from its start.
+func loginTime(claims *Claims) time.Time {
+	if claims.AuthTimeUs != 0 {
+		return time.UnixMicro(claims.AuthTimeUs)
+	}
+	return time.Unix(claims.AuthTime, 0)
+}
+
+// revoked reports whether a token's login has been revoked. Tokens without
+// auth_time cannot be placed, so any cutoff revokes them.
+func revoked(claims *Claims) bool {
+	revokedMu.Lock()
+	cutoff, ok := revokedBefore[claims.Username]
+	revokedMu.Unlock()
+	return ok && (claims.AuthTime == 0 || loginTime(claims).Before(cutoff))
+}
+
+// revokeSessions revokes all of the caller's sessions, this one included.
+func revokeSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	revokedMu.Lock()
+	revokedBefore[claims.Username] = time.Now().Truncate(time.Microsecond)
+	revokedMu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "sessions revoked"}`))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/studentid"
+)
+
+// --- Self-Registration ---
+
+// Admitted students sign up with the student ID admissions gave them. A
+// signup waits in pendingSignups until the student follows the link sent to
+// their email; only then does the account exist. There is no mailer yet, so
+// the link is written to the log.
+//
+// Only IDs admissions issued can be signed up for, and only with the email
+// the admission letter went to (see generateStudentIDs), so the link proves
+// the student is the one admitted, not just someone who guessed a free ID.
+
+const (
+	signupTTL         = 24 * time.Hour
+	minPasswordLength = 8
+)
+
+type Signup struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type pendingSignup struct {
+	Signup
+	expires time.Time
+}
+
+var (
+	signupMu       sync.Mutex
+	pendingSignups = make(map[string]*pendingSignup) // Key: verification token
+
+	admissionsMu sync.Mutex
+	admissions   = make(map[string]string) // Student ID -> email the admission letter went to
+)
+
+// admittedEmail returns the email admissions has on file for a student ID.
+func admittedEmail(id string) (string, bool) {
+	admissionsMu.Lock()
+	defer admissionsMu.Unlock()
+	email, ok := admissions[id]
+	return email, ok
+}
+
+// validateSignup returns a message for each field that is wrong.
+func validateSignup(tenant string, s Signup) map[string]string {
+	problems := map[string]string{}
+	admitted, ok := admittedEmail(s.Username)
+	if err := studentid.Validate(tenant, s.Username); err != nil || !ok {
+		problems["username"] = "Use the student ID from your admission letter."
+	} else if _, taken := lookupUser(s.Username); taken {
+		problems["username"] = "An account already exists for this student ID."
+	}
+	if len(s.Password) < minPasswordLength {
+		problems["password"] = fmt.Sprintf("Use at least %d characters.", minPasswordLength)
+	}
+	if err := validateEmail(s.Email); err != nil {
+		problems["email"] = "Enter an email address such as name@example.edu."
+	} else if ok && !strings.EqualFold(s.Email, admitted) {
+		problems["email"] = "Use the email address your admission letter was sent to."
+	}
+	return problems
+}
+
+// register starts a signup (POST /register). Invalid fields get a 400 with
+// {"errors": {field: message}} so a form can show each next to its field.
+func register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var s Signup
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.Username = strings.TrimSpace(s.Username)
+	s.Email = strings.TrimSpace(s.Email)
+	if problems := validateSignup(studentid.Tenant(r), s); len(problems) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": problems})
+		return
+	}
+
+	token := randomToken(16)
+	signupMu.Lock()
+	for t, p := range pendingSignups {
+		// A second signup for the same ID replaces the first, e.g. after a typo in the email
+		if p.Username == s.Username || time.Now().After(p.expires) {
+			delete(pendingSignups, t)
+		}
+	}
+	pendingSignups[token] = &pendingSignup{Signup: s, expires: time.Now().Add(signupTTL)}
+	signupMu.Unlock()
+
+	slog.InfoContext(r.Context(), "signup: verification link", "email", s.Email, "username", s.Username, "url", portalURL()+"/signup/verify?token="+token)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "verification sent", "email": s.Email})
+}
+
+// verifySignup creates the account for a verification token (POST
+// /register/verify?token=). Each token works once.
+func verifySignup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	signupMu.Lock()
+	p, ok := pendingSignups[token]
+	delete(pendingSignups, token)
+	signupMu.Unlock()
+	if !ok || time.Now().After(p.expires) {
+		http.Error(w, "This verification link is invalid or has expired. Please sign up again.", http.StatusNotFound)
+		return
+	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	if _, taken := users[p.Username]; taken {
+		http.Error(w, "An account already exists for this student ID.", http.StatusConflict)
+		return
+	}
+	users[p.Username] = p.Password
+	roles[p.Username] = "student"
+	profiles[p.Username] = StudentProfile{Email: p.Email}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "account verified", "username": p.Username})
+}
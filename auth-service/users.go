@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"shared/studentid"
+)
+
+// --- User Management ---
+
+// Admins manage accounts through /admin/users. Accounts live in the users,
+// roles and profiles maps, so they last until the service restarts. Tokens
+// already issued stay valid until they expire, even for a deleted account.
+
+var validRoles = map[string]bool{"student": true, "faculty": true, "admin": true, "registrar": true}
+
+// usersMu guards users, roles and profiles.
+var usersMu sync.RWMutex
+
+type UserAccount struct {
+	Username  string `json:"username"`
+	Password  string `json:"password,omitempty"` // Only ever sent in, never out
+	Role      string `json:"role"`
+	Program   string `json:"program,omitempty"`
+	YearLevel int    `json:"year_level,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Advisor   string `json:"advisor,omitempty"`
+}
+
+// lookupUser returns an account without its password.
+func lookupUser(username string) (UserAccount, bool) {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	if _, ok := users[username]; !ok {
+		return UserAccount{}, false
+	}
+	p := profiles[username]
+	return UserAccount{Username: username, Role: roles[username], Program: p.Program, YearLevel: p.YearLevel, Email: p.Email, Advisor: p.Advisor}, true
+}
+
+// checkPassword reports whether password is the user's.
+func checkPassword(username, password string) bool {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	expected, ok := users[username]
+	return ok && expected == password
+}
+
+// usersHandler lists (GET), creates (POST), updates (PUT) or deletes
+// (DELETE ?username=) accounts. Admin only. A PUT changes only the fields
+// it sends.
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := claimsFromRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "Forbidden: Only admins can manage users", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		usersMu.RLock()
+		list := []UserAccount{}
+		for username := range users {
+			p := profiles[username]
+			list = append(list, UserAccount{Username: username, Role: roles[username], Program: p.Program, YearLevel: p.YearLevel, Email: p.Email, Advisor: p.Advisor})
+		}
+		usersMu.RUnlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var u UserAccount
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if u.Username == "" || u.Password == "" {
+			http.Error(w, "username and password are required", http.StatusBadRequest)
+			return
+		}
+		if !validRoles[u.Role] {
+			http.Error(w, "role must be student, faculty, registrar or admin", http.StatusBadRequest)
+			return
+		}
+		if u.Role == "student" {
+			if err := studentid.Validate(studentid.Tenant(r), u.Username); err != nil {
+				http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if u.Email != "" {
+			if err := validateEmail(u.Email); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		usersMu.Lock()
+		if _, taken := users[u.Username]; taken {
+			usersMu.Unlock()
+			http.Error(w, "User already exists", http.StatusConflict)
+			return
+		}
+		users[u.Username] = u.Password
+		roles[u.Username] = u.Role
+		if u.Program != "" || u.YearLevel != 0 || u.Email != "" || u.Advisor != "" {
+			profiles[u.Username] = StudentProfile{Program: u.Program, YearLevel: u.YearLevel, Email: u.Email, Advisor: u.Advisor}
+		}
+		usersMu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status": "user created"}`))
+
+	case http.MethodPut:
+		var u UserAccount
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if u.Role != "" && !validRoles[u.Role] {
+			http.Error(w, "role must be student, faculty, registrar or admin", http.StatusBadRequest)
+			return
+		}
+		if u.Email != "" {
+			if err := validateEmail(u.Email); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if u.Username == claims.Username && u.Role != "" && u.Role != "admin" {
+			http.Error(w, "You cannot take away your own admin role", http.StatusConflict)
+			return
+		}
+		usersMu.Lock()
+		defer usersMu.Unlock()
+		if _, ok := users[u.Username]; !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if u.Password != "" {
+			users[u.Username] = u.Password
+		}
+		if u.Role != "" {
+			roles[u.Username] = u.Role
+		}
+		if u.Program != "" || u.YearLevel != 0 || u.Email != "" || u.Advisor != "" {
+			p := profiles[u.Username]
+			if u.Program != "" {
+				p.Program = u.Program
+			}
+			if u.YearLevel != 0 {
+				p.YearLevel = u.YearLevel
+			}
+			if u.Email != "" {
+				p.Email = u.Email
+			}
+			if u.Advisor != "" {
+				p.Advisor = u.Advisor
+			}
+			profiles[u.Username] = p
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "user updated"}`))
+
+	case http.MethodDelete:
+		username := r.URL.Query().Get("username")
+		if username == claims.Username {
+			http.Error(w, "You cannot delete your own account", http.StatusConflict)
+			return
+		}
+		usersMu.Lock()
+		defer usersMu.Unlock()
+		if _, ok := users[username]; !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		delete(users, username)
+		delete(roles, username)
+		delete(profiles, username)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "user deleted"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// --- Course Archival ---
+
+// Courses are never deleted. Archiving takes a course out of the active
+// catalog and closes it to new students, but its enrollments, roster and
+// history stay queryable for transcripts and audits.
+
+type ArchiveRequest struct {
+	CourseID string `json:"course_id"`
+	Reason   string `json:"reason"`
+}
+
+// isArchived reports whether a course has been archived. Callers must hold mu.
+func isArchived(c *Course) bool {
+	return c.ArchivedAt != nil
+}
+
+// archiveCourse (POST /courses/archive) archives a course; POST
+// /courses/restore brings it back. Registrar or admin only.
+func archiveCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	var req ArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+
+	restoring := r.URL.Path == "/courses/restore"
+	if restoring != isArchived(c) {
+		http.Error(w, "Course is already in that state", http.StatusConflict)
+		return
+	}
+
+	if restoring {
+		c.ArchivedAt = nil
+		touch(c)
+		recordEvent(EnrollmentEvent{Type: "restore", CourseID: c.ID, Actor: user.Username, Detail: req.Reason},
+			announceCatalog(DomainEvent{Type: "CourseRestored", CourseID: c.ID}))
+		w.Header().Set("ETag", versionTag(c))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "restored"}`))
+		return
+	}
+
+	// Pending seats can no longer turn into enrollments
+	for _, h := range holds {
+		if h.CourseID == c.ID {
+			releaseHold(h)
+		}
+	}
+	delete(waitlists, c.ID)
+
+	now := time.Now().UTC()
+	c.ArchivedAt = &now
+	touch(c)
+	recordEvent(EnrollmentEvent{Type: "archive", CourseID: c.ID, Actor: user.Username, Detail: req.Reason},
+		announceCatalog(DomainEvent{Type: "CourseArchived", CourseID: c.ID}))
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "archived"}`))
+}
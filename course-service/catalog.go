@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"shared/models"
+)
+
+// --- Catalog Editing ---
+
+// The registrar and admins add offerings with POST /courses and edit their
+// title, credits and department with PUT /courses/details. Capacity,
+// instructor and archival keep their own endpoints. Both changes go into the
+// enrollment history as "create" and "update" events.
+
+type CourseRequest struct {
+	Code         string `json:"code"`
+	Term         string `json:"term"` // Defaults to the current term
+	Title        string `json:"title"`
+	Credits      int    `json:"credits"`
+	Capacity     int    `json:"capacity"`
+	DepartmentID string `json:"department_id"`
+	Instructor   string `json:"instructor"`
+}
+
+type CourseDetailsRequest struct {
+	CourseID     string `json:"course_id"`
+	Title        string `json:"title"`
+	Credits      int    `json:"credits"`
+	DepartmentID string `json:"department_id"`
+}
+
+// coursesHandler lists courses (GET) or adds one (POST).
+func coursesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		createCourse(w, r)
+		return
+	}
+	getCourses(w, r)
+}
+
+func createCourse(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "registrar", "admin")
+	if !ok {
+		return
+	}
+	var req CourseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.Code == "" || strings.ContainsAny(req.Code, "@:, ") {
+		http.Error(w, "code is required and may not contain @, :, commas or spaces", http.StatusBadRequest)
+		return
+	}
+	if req.Title == "" || req.Credits < 1 || req.Capacity < 0 {
+		http.Error(w, "title is required, credits must be positive and capacity cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Term == "" {
+		req.Term = currentTerm()
+	}
+	if !termExists(req.Term) {
+		http.Error(w, "Term not found", http.StatusNotFound)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if req.DepartmentID != "" && findDepartment(req.DepartmentID) == nil {
+		http.Error(w, "Department not found", http.StatusNotFound)
+		return
+	}
+	c := &Course{Course: models.Course{
+		ID:           offeringID(req.Code, req.Term),
+		Code:         req.Code,
+		Term:         req.Term,
+		Title:        req.Title,
+		Credits:      req.Credits,
+		Capacity:     req.Capacity,
+		DepartmentID: req.DepartmentID,
+		Instructor:   req.Instructor,
+	}}
+	if findCourse(c.ID) != nil {
+		http.Error(w, "Course already exists", http.StatusConflict)
+		return
+	}
+	c.OpenSlots = sellableSeats(c)
+	if err := seats.add(c); err != nil {
+		http.Error(w, "Seat store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	courses = append(courses, c)
+	recordEvent(EnrollmentEvent{Type: "create", CourseID: c.ID, Actor: user.Username, Detail: c.Title})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(courseView(c, ""))
+}
+
+// updateCourseDetails (PUT /courses/details) changes the fields it is sent.
+// Needs If-Match like every other course update.
+func updateCourseDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "registrar", "admin")
+	if !ok {
+		return
+	}
+	var req CourseDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Credits < 0 {
+		http.Error(w, "credits must be positive", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	if req.DepartmentID != "" && findDepartment(req.DepartmentID) == nil {
+		http.Error(w, "Department not found", http.StatusNotFound)
+		return
+	}
+	var changed []string
+	if req.Title != "" && req.Title != c.Title {
+		c.Title = req.Title
+		changed = append(changed, "title")
+	}
+	if req.Credits != 0 && req.Credits != c.Credits {
+		c.Credits = req.Credits
+		changed = append(changed, "credits")
+	}
+	if req.DepartmentID != "" && req.DepartmentID != c.DepartmentID {
+		c.DepartmentID = req.DepartmentID
+		changed = append(changed, "department")
+	}
+	if len(changed) > 0 {
+		touch(c)
+		recordEvent(EnrollmentEvent{Type: "update", CourseID: c.ID, Actor: user.Username, Detail: strings.Join(changed, ", ")},
+			announceCatalog(DomainEvent{Type: "CourseUpdated", CourseID: c.ID, Data: map[string]string{"changed": strings.Join(changed, ", ")}}))
+	}
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "course updated"}`))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+
+	"shared/auth"
+	"shared/config"
+	"shared/discovery"
+	"shared/events"
+	"shared/idempotency"
+	"shared/logging"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+)
+
+// --- Configuration ---
+
+// settings are everything the Course Service can be configured with. Run it
+// with dump-config to see the values it would use.
+var settings = slices.Concat(logging.Settings, server.Settings, mtls.Settings, studentid.Settings, events.Settings, auth.Settings, idempotency.Settings, []config.Setting{
+	{Name: "PORT", Default: "8082", Usage: "Port to listen on", Check: config.IntBetween(1, 65535)},
+	{Name: "GRADE_SERVICE_URL", Default: "http://node_grade:8083", Usage: "Where the Grade Service is, for prerequisite checks: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "BILLING_SERVICE_URL", Usage: "Where the billing service is: a base URL, a comma-separated list, or srv://name; unset charges nothing", Check: discovery.ValidTarget},
+	{Name: "FIXTURE_PATH", Flag: "fixture", Usage: "YAML file or directory of CSV files to load at startup"},
+	{Name: "CURRENT_TERM", Default: "2026-T1", Usage: "Term the built-in catalog is offered in"},
+	{Name: "REGISTRATION_OPENS_AT", Usage: "When enrollment opens (RFC3339); unset for no start", Check: config.ValidTime},
+	{Name: "REGISTRATION_CLOSES_AT", Usage: "When enrollment closes (RFC3339); unset for no end", Check: config.ValidTime},
+	{Name: "CLASSES_START", Usage: "First day of classes (RFC3339), for calendars", Check: config.ValidTime},
+	{Name: "CLASSES_END", Usage: "Last day of classes (RFC3339), for calendars", Check: config.ValidTime},
+	{Name: "DROP_REFUND_UNTIL", Usage: "Last moment a drop is refunded (RFC3339); unset to always refund", Check: config.ValidTime},
+	{Name: "MAX_CREDITS_PER_TERM", Default: "18", Usage: "Credits a student may take in one term", Check: config.Positive},
+	{Name: "SEAT_HOLD_TTL_SECONDS", Default: "120", Usage: "How long a seat hold lasts unless the request asks for less", Check: config.Positive},
+	{Name: "WAITLIST_OFFER_TTL_SECONDS", Default: "86400", Usage: "How long a promoted student has to accept the seat", Check: config.Positive},
+	{Name: "SEAT_STORE_URL", Usage: "Redis URL of the seat store shared between replicas; unset keeps seats in memory", Check: config.ValidURL},
+	{Name: "ENROLLMENT_LOG_PATH", Usage: "File the enrollment history is appended to and enrollments are replayed from; unset keeps it in memory"},
+	{Name: "EVENT_OUTBOX_PATH", Usage: "File events wait in until they are published; unset keeps them in memory"},
+})
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// --- Read-Your-Writes ---
+
+// Every seat write bumps a store-wide version. Successful enroll, drop, swap
+// and hold confirmations return the version after the write in the
+// X-Enrollment-Version header. A client that passes it back as
+// GET /courses?min_version=<n> gets a view that includes its own write, even
+// from a replica whose cache has not synced yet: the replica syncs with the
+// seat store before answering. Without a shared store the single instance is
+// always current and the parameter is a no-op.
+
+// syncedVersion is the store version this replica's cache reflects.
+var syncedVersion atomic.Int64
+
+// catchUpMu makes concurrent catch-ups share one sync.
+var catchUpMu sync.Mutex
+
+// noteVersion raises syncedVersion, never lowering it.
+func noteVersion(v int64) {
+	for {
+		current := syncedVersion.Load()
+		if v <= current || syncedVersion.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// stampVersion tells the client which version its write produced. It must
+// run before the status line is written.
+func stampVersion(w http.ResponseWriter) {
+	w.Header().Set("X-Enrollment-Version", strconv.FormatInt(seats.version(), 10))
+}
+
+// awaitVersion honors ?min_version= and writes the error response when the
+// replica cannot catch up. Callers must not hold mu.
+func awaitVersion(w http.ResponseWriter, r *http.Request) bool {
+	raw := r.URL.Query().Get("min_version")
+	if raw == "" {
+		return true
+	}
+	want, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid min_version", http.StatusBadRequest)
+		return false
+	}
+	if !seats.catchUp(want) {
+		http.Error(w, "Course data is still catching up; retry shortly", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/models"
+	"shared/studentid"
+)
+
+// --- Co-requisites ---
+
+type BatchEnrollRequest struct {
+	StudentID string   `json:"student_id"`
+	CourseIDs []string `json:"course_ids"`
+}
+
+// missingCoRequisites lists the co-requisites of c that the student neither
+// holds already nor is taking in the same batch. Callers must hold mu (shared
+// is enough).
+func missingCoRequisites(c *Course, studentID string, batch []string) []string {
+	var missing []string
+	for _, id := range c.CoRequisites {
+		if isEnrolled(id, studentID) || contains(batch, id) {
+			continue
+		}
+		missing = append(missing, id)
+	}
+	return missing
+}
+
+// enrollBatch enrolls a student in several sections all-or-nothing, which is
+// the only way to take sections that are co-requisites of each other.
+func enrollBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BatchEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		failEnrollment(w, http.StatusBadRequest, EnrollmentFailure{Code: models.CodeInvalidRequest, Message: err.Error()})
+		return
+	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		failEnrollment(w, http.StatusBadRequest, EnrollmentFailure{Code: models.CodeInvalidRequest, Message: "Invalid student ID: " + err.Error()})
+		return
+	}
+	if len(req.CourseIDs) == 0 {
+		failEnrollment(w, http.StatusBadRequest, EnrollmentFailure{Code: models.CodeInvalidRequest, Message: "course_ids is empty"})
+		return
+	}
+	profile, ok := enrollingProfile(w, r, req.StudentID)
+	if !ok {
+		return
+	}
+	if f := registrationClosed(time.Now()); f != nil {
+		failEnrollment(w, http.StatusForbidden, *f)
+		return
+	}
+	unlock, lockErr := lockSeats(r.Context(), req.StudentID, req.CourseIDs...)
+	if lockErr != nil {
+		failEnrollment(w, lockErr.Status, lockErr.failure(""))
+		return
+	}
+	defer unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if h := activeRegistrationHold(req.StudentID); h != nil {
+		failEnrollment(w, http.StatusForbidden, EnrollmentFailure{Code: models.CodeHoldPresent, Message: "Registration blocked by " + h.Type + " hold"})
+		return
+	}
+
+	// 1. Resolve every course and check co-requisites against the whole batch
+	var batch []*Course
+	for _, id := range req.CourseIDs {
+		c := findCourse(id)
+		if c == nil {
+			failEnrollment(w, http.StatusNotFound, EnrollmentFailure{Code: models.CodeNotFound, Message: "Course not found", CourseID: id})
+			return
+		}
+		if missing := missingCoRequisites(c, req.StudentID, req.CourseIDs); len(missing) > 0 {
+			failEnrollment(w, http.StatusConflict, EnrollmentFailure{Code: models.CodePrereqMissing, Message: c.ID + " requires co-requisite " + strings.Join(missing, ", "), CourseID: c.ID})
+			return
+		}
+		batch = append(batch, c)
+	}
+
+	// 2. Admit one by one, undoing everything if any section is refused
+	for i, c := range batch {
+		if err := admit(c, req.StudentID, profile); err != nil {
+			for _, done := range batch[:i] {
+				unenroll(done, req.StudentID)
+			}
+			failEnrollment(w, err.Status, err.failure(c.ID))
+			return
+		}
+	}
+	for _, c := range batch {
+		recordEvent(EnrollmentEvent{Type: "enroll", StudentID: req.StudentID, CourseID: c.ID, Detail: "batch"},
+			announce(DomainEvent{Type: "EnrollmentCreated", StudentID: req.StudentID, CourseID: c.ID}))
+	}
+
+	enrollmentAttempts.WithLabelValues("enrolled").Inc()
+	stampVersion(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "enrolled"}`))
+}
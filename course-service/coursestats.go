@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// --- Section Statistics ---
+
+type SectionStats struct {
+	CourseID      string  `json:"course_id"`
+	Instructor    string  `json:"instructor,omitempty"`
+	Capacity      int     `json:"capacity"`
+	Enrolled      int     `json:"enrolled"`
+	Held          int     `json:"held"`      // Seats in live holds, not yet confirmed
+	FillRate      float64 `json:"fill_rate"` // Enrolled / Capacity
+	Enrollments   int     `json:"enrollments"`
+	Drops         int     `json:"drops"`
+	DropRate      float64 `json:"drop_rate"` // Drops / Enrollments
+	WaitlistDepth int     `json:"waitlist_depth"`
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(d)*1000) / 1000
+}
+
+// courseStats (GET /courses/{id}/stats) reports how a section is filling up
+// and how many students leave it. Enrollment and drop totals come from the
+// enrollment history; the rest is the section's current state. Restricted to
+// the instructor, the registrar and admins.
+func courseStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	flows := enrollmentFlows()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(r.PathValue("id"))
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !canManageCourse(user, c) {
+		http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+		return
+	}
+
+	n := flows[c.ID]
+	stats := SectionStats{
+		CourseID:      c.ID,
+		Instructor:    c.Instructor,
+		Capacity:      c.Capacity,
+		Enrollments:   n.Enrollments,
+		Drops:         n.Drops,
+		DropRate:      ratio(n.Drops, n.Enrollments),
+		WaitlistDepth: len(waitlists[c.ID]),
+	}
+	for key := range enrollments {
+		if strings.HasPrefix(key, c.ID+":") {
+			stats.Enrolled++
+		}
+	}
+	for _, h := range holds {
+		if h.CourseID == c.ID {
+			stats.Held++
+		}
+	}
+	stats.FillRate = ratio(stats.Enrolled, c.Capacity)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
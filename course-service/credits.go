@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shared/config"
+	"shared/studentid"
+)
+
+// --- Credit Limits ---
+
+var creditOverrides = make(map[string]int) // Key: StudentID, guarded by mu
+
+type CreditLimitRequest struct {
+	StudentID  string `json:"student_id"`
+	MaxCredits int    `json:"max_credits"` // 0 removes the override
+}
+
+type CreditLoad struct {
+	StudentID  string `json:"student_id"`
+	Term       string `json:"term"`
+	Credits    int    `json:"credits"`
+	MaxCredits int    `json:"max_credits"`
+	Overridden bool   `json:"overridden"`
+}
+
+// studentCredits sums the credits a student currently holds in one term,
+// counting both enrollments and live seat holds. Callers must hold mu (shared
+// is enough).
+func studentCredits(studentID, term string) int {
+	total := 0
+	enrollMu.Lock()
+	for key := range enrollments {
+		courseID, sid, _ := strings.Cut(key, ":")
+		if sid != studentID {
+			continue
+		}
+		if c := findCourse(courseID); c != nil && c.Term == term {
+			total += c.Credits
+		}
+	}
+	enrollMu.Unlock()
+	for _, h := range holds {
+		if h.StudentID != studentID {
+			continue
+		}
+		if c := findCourse(h.CourseID); c != nil && c.Term == term {
+			total += c.Credits
+		}
+	}
+	return total
+}
+
+// creditLimit returns the cap for a student. Callers must hold mu.
+func creditLimit(studentID string) int {
+	if limit, ok := creditOverrides[studentID]; ok {
+		return limit
+	}
+	return config.Int("MAX_CREDITS_PER_TERM")
+}
+
+// exceedsCreditLimit reports whether taking on extra credits in a term would
+// push the student over their cap. Callers must hold mu.
+func exceedsCreditLimit(studentID, term string, extra int) bool {
+	return studentCredits(studentID, term)+extra > creditLimit(studentID)
+}
+
+// creditLimits shows a student's load (GET), to the student themselves or
+// staff, or lets the registrar set a per-student override (PUT).
+func creditLimits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		user, ok := requireRole(w, r, "student", "registrar", "admin")
+		if !ok {
+			return
+		}
+		studentID := r.URL.Query().Get("student_id")
+		if user.Role == "student" && studentID != user.Username {
+			http.Error(w, "Forbidden: Students can only view their own credit load", http.StatusForbidden)
+			return
+		}
+		if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		_, overridden := creditOverrides[studentID]
+		load := CreditLoad{
+			StudentID:  studentID,
+			Term:       requestedTerm(r),
+			Credits:    studentCredits(studentID, requestedTerm(r)),
+			MaxCredits: creditLimit(studentID),
+			Overridden: overridden,
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(load)
+
+	case http.MethodPut:
+		user, ok := requireRole(w, r, "registrar")
+		if !ok {
+			return
+		}
+		var req CreditLimitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.MaxCredits < 0 {
+			http.Error(w, "max_credits cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		if req.MaxCredits == 0 {
+			delete(creditOverrides, req.StudentID)
+		} else {
+			creditOverrides[req.StudentID] = req.MaxCredits
+		}
+		mu.Unlock()
+		recordEvent(EnrollmentEvent{
+			Type:      "override",
+			StudentID: req.StudentID,
+			Actor:     user.Username,
+			Detail:    "credit limit set to " + strconv.Itoa(req.MaxCredits),
+		})
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "credit limit updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// --- Cross-Listing ---
+
+// A cross-listed class is offered under several course codes, e.g. by CS and
+// by Math. One code is the primary: it owns the seats, pools, capacity and
+// overbooking. The others point at it through CrossListOf, so enrolling under
+// any code takes a seat from the same count, and each listing mirrors the
+// primary's seat figures. Enrollments stay under the code the student used;
+// the roster of any listing shows the whole class.
+
+type CrossListRequest struct {
+	CourseID  string `json:"course_id"`  // The listing to attach
+	PrimaryID string `json:"primary_id"` // The course that owns the seats
+}
+
+// seatOwner returns the course whose seats a listing sells. Callers must hold
+// mu (shared is enough).
+func seatOwner(c *Course) *Course {
+	if c.CrossListOf != "" {
+		if owner := findCourse(c.CrossListOf); owner != nil {
+			return owner
+		}
+	}
+	return c
+}
+
+// crossListGroup returns every listing of a class, primary first. Callers
+// must hold mu (shared is enough).
+func crossListGroup(c *Course) []*Course {
+	owner := seatOwner(c)
+	group := []*Course{owner}
+	for _, other := range courses {
+		if other.CrossListOf == owner.ID {
+			group = append(group, other)
+		}
+	}
+	return group
+}
+
+// mirrorSeats copies the primary's seat figures onto its other listings.
+// Callers must hold mu, or the primary's course lock on the shared hot path.
+func mirrorSeats(owner *Course) {
+	for _, c := range courses {
+		if c.CrossListOf != owner.ID {
+			continue
+		}
+		if c.OpenSlots != owner.OpenSlots || c.Capacity != owner.Capacity ||
+			c.OverbookFactor != owner.OverbookFactor || c.PermitSeats != owner.PermitSeats {
+			c.OpenSlots, c.Capacity = owner.OpenSlots, owner.Capacity
+			c.OverbookFactor, c.PermitSeats = owner.OverbookFactor, owner.PermitSeats
+			touch(c)
+		}
+	}
+}
+
+// enrolledInClass reports whether a student is enrolled under any listing.
+// Callers must hold mu (shared is enough).
+func enrolledInClass(c *Course, studentID string) bool {
+	for _, listing := range crossListGroup(c) {
+		if isEnrolled(listing.ID, studentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsClass reports whether a student holds a seat under any listing.
+// Callers must hold mu.
+func holdsClass(c *Course, studentID string) bool {
+	for _, listing := range crossListGroup(c) {
+		if studentHoldsCourse(listing.ID, studentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossList (PUT /courses/cross-list) attaches a listing to a primary course.
+// The listing must be empty, since its own seats are given up. Registrar or
+// admin only; If-Match carries the listing's version.
+func crossList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	var req CrossListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	primary := findCourse(req.PrimaryID)
+	if c == nil || primary == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+
+	// 1. Only one level: a primary with listings of its own cannot be attached
+	switch {
+	case c.ID == primary.ID:
+		http.Error(w, "A course cannot be cross-listed with itself", http.StatusBadRequest)
+		return
+	case c.CrossListOf != "":
+		http.Error(w, "Course is already cross-listed with "+c.CrossListOf, http.StatusConflict)
+		return
+	case primary.CrossListOf != "":
+		http.Error(w, "Primary is itself a listing of "+primary.CrossListOf, http.StatusConflict)
+		return
+	case len(crossListGroup(c)) > 1:
+		http.Error(w, "Course is the primary of other listings", http.StatusConflict)
+		return
+	}
+
+	// 2. The listing's own seats are discarded, so nobody may be using them
+	for key := range enrollments {
+		if strings.HasPrefix(key, c.ID+":") {
+			http.Error(w, "Course has enrollments; only an empty course can be cross-listed", http.StatusConflict)
+			return
+		}
+	}
+	for _, h := range holds {
+		if h.CourseID == c.ID {
+			http.Error(w, "Course has seat holds; only an empty course can be cross-listed", http.StatusConflict)
+			return
+		}
+	}
+
+	c.CrossListOf = primary.ID
+	c.SeatPools = nil
+	touch(c)
+	mirrorSeats(primary)
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "cross-listed"}`))
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// --- Departments & Colleges ---
+
+// The catalog is organised as colleges, which contain departments, which
+// offer courses. Course.DepartmentID links a course into the hierarchy.
+
+type College struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+type Department struct {
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	CollegeID string `json:"college_id" yaml:"college_id"`
+	Chair     string `json:"chair,omitempty" yaml:"chair"` // Faculty username
+}
+
+type CollegeListing struct {
+	College
+	Departments []Department `json:"departments"`
+}
+
+type ChairAssignment struct {
+	DepartmentID string `json:"department_id"`
+	Chair        string `json:"chair"` // Empty leaves the department without a chair
+}
+
+type CourseDepartmentRequest struct {
+	CourseID     string `json:"course_id"`
+	DepartmentID string `json:"department_id"`
+}
+
+// SeatRollup sums seat figures over a set of courses.
+type SeatRollup struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Courses    int     `json:"courses"`
+	Capacity   int     `json:"capacity"`
+	Occupied   int     `json:"occupied"`
+	OpenSlots  int     `json:"open_slots"`
+	Waitlisted int     `json:"waitlisted"`
+	FillRate   float64 `json:"fill_rate"`
+}
+
+type DepartmentStats struct {
+	Departments []SeatRollup `json:"departments"`
+	Colleges    []SeatRollup `json:"colleges"`
+}
+
+// Both guarded by mu
+var (
+	colleges = []*College{
+		{ID: "CCS", Name: "College of Computer Studies"},
+		{ID: "COS", Name: "College of Science"},
+	}
+	departments = []*Department{
+		{ID: "CS", Name: "Computer Science", CollegeID: "CCS"},
+		{ID: "MATH", Name: "Mathematics and Statistics", CollegeID: "COS"},
+	}
+)
+
+// findDepartment returns the department with the given ID. Callers must hold mu.
+func findDepartment(id string) *Department {
+	for _, d := range departments {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// findCollege returns the college with the given ID. Callers must hold mu.
+func findCollege(id string) *College {
+	for _, c := range colleges {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// collegesHandler lists colleges with their departments (GET) or adds a college (POST, admin).
+func collegesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mu.Lock()
+		list := []CollegeListing{}
+		for _, c := range colleges {
+			listing := CollegeListing{College: *c, Departments: []Department{}}
+			for _, d := range departments {
+				if d.CollegeID == c.ID {
+					listing.Departments = append(listing.Departments, *d)
+				}
+			}
+			list = append(list, listing)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		if _, ok := requireRole(w, r, "admin"); !ok {
+			return
+		}
+		var c College
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if c.ID == "" || c.Name == "" {
+			http.Error(w, "id and name are required", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if findCollege(c.ID) != nil {
+			http.Error(w, "College already exists", http.StatusConflict)
+			return
+		}
+		colleges = append(colleges, &c)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status": "college created"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// departmentsHandler lists departments (GET, optionally ?college_id=) or adds one (POST, admin).
+func departmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		collegeID := r.URL.Query().Get("college_id")
+		mu.Lock()
+		list := []Department{}
+		for _, d := range departments {
+			if collegeID == "" || d.CollegeID == collegeID {
+				list = append(list, *d)
+			}
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		if _, ok := requireRole(w, r, "admin"); !ok {
+			return
+		}
+		var d Department
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if d.ID == "" || d.Name == "" {
+			http.Error(w, "id and name are required", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if findCollege(d.CollegeID) == nil {
+			http.Error(w, "College not found", http.StatusNotFound)
+			return
+		}
+		if findDepartment(d.ID) != nil {
+			http.Error(w, "Department already exists", http.StatusConflict)
+			return
+		}
+		departments = append(departments, &d)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status": "department created"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// assignChair sets who chairs a department. Registrar or admin only.
+func assignChair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	var req ChairAssignment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	d := findDepartment(req.DepartmentID)
+	if d == nil {
+		http.Error(w, "Department not found", http.StatusNotFound)
+		return
+	}
+	d.Chair = req.Chair
+	emitCatalog(DomainEvent{Type: "DepartmentUpdated", Data: map[string]string{"department_id": d.ID, "changed": "chair"}})
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "chair assigned"}`))
+}
+
+// assignDepartment moves a course into a department. Registrar or admin only.
+func assignDepartment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	var req CourseDepartmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if findDepartment(req.DepartmentID) == nil {
+		http.Error(w, "Department not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	c.DepartmentID = req.DepartmentID
+	touch(c)
+	emitCatalog(DomainEvent{Type: "CourseUpdated", CourseID: c.ID, Data: map[string]string{"changed": "department"}})
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "department assigned"}`))
+}
+
+// addToRollup folds one course into a rollup. A cross-listing counts as a
+// course but its seats are counted once, under the primary. Callers must hold mu.
+func addToRollup(roll *SeatRollup, c *Course) {
+	roll.Courses++
+	roll.Waitlisted += len(waitlists[c.ID])
+	if c.CrossListOf != "" {
+		return
+	}
+	roll.Capacity += c.Capacity
+	roll.Occupied += occupiedSeats(c)
+	roll.OpenSlots += c.OpenSlots
+}
+
+// departmentStats rolls seat statistics up per department and per college.
+func departmentStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	term := requestedTerm(r)
+
+	mu.Lock()
+	byDept := make(map[string]*SeatRollup)
+	byCollege := make(map[string]*SeatRollup)
+	stats := DepartmentStats{}
+	for _, d := range departments {
+		byDept[d.ID] = &SeatRollup{ID: d.ID, Name: d.Name}
+	}
+	for _, c := range colleges {
+		byCollege[c.ID] = &SeatRollup{ID: c.ID, Name: c.Name}
+	}
+	for _, c := range courses {
+		d := findDepartment(c.DepartmentID)
+		if d == nil || isArchived(c) || c.Term != term {
+			continue
+		}
+		addToRollup(byDept[d.ID], c)
+		if roll, ok := byCollege[d.CollegeID]; ok {
+			addToRollup(roll, c)
+		}
+	}
+	for _, d := range departments {
+		stats.Departments = append(stats.Departments, *byDept[d.ID])
+	}
+	for _, c := range colleges {
+		stats.Colleges = append(stats.Colleges, *byCollege[c.ID])
+	}
+	mu.Unlock()
+
+	for _, list := range [][]SeatRollup{stats.Departments, stats.Colleges} {
+		for i := range list {
+			if list[i].Capacity > 0 {
+				list[i].FillRate = float64(list[i].Occupied) / float64(list[i].Capacity)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+
+	"shared/models"
+)
+
+// --- Distributed Seat Locks ---
+
+// The seat store's scripts make each claim atomic, but an enrollment decides
+// more than whether a seat is left before it claims one: the student's term
+// credits, co-requisites and holds are checked against this replica's cache.
+// Two replicas enrolling one student in two courses at once could both pass
+// the credit limit, so with a shared seat store every seat sale first takes
+// the student's lock and then the lock of each course's seat owner in Redis,
+// in that order on every replica, and brings the cache up to date before
+// checking anything.
+//
+// Locks are leased for seatLockLease, so a replica that dies holding one
+// only blocks the course for that long; a sale that outlives its lease is
+// still kept from overselling by the claim script. A request that cannot get
+// its locks within seatLockWait is refused with SERVICE_UNAVAILABLE.
+//
+// A single instance keeps the in-process locks of locking.go, which already
+// serialize everything, and takes no further lock.
+
+type seatLocker interface {
+	// acquire takes the named locks in order and returns the func that
+	// releases them all.
+	acquire(ctx context.Context, names []string) (func(), error)
+}
+
+var seatLocks seatLocker = localSeatLocks{}
+
+const (
+	seatLockLease = 5 * time.Second
+	seatLockWait  = 2 * time.Second
+	seatLockRetry = 10 * time.Millisecond
+)
+
+var seatLockAcquisitions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "seat_lock_acquisitions_total",
+	Help: "Distributed seat lock requests, by result: acquired or timeout.",
+}, []string{"result"})
+
+// lockSeats takes the allocation locks for a student claiming seats in
+// courses, then catches the cache up to the seat store. Callers must not
+// hold mu, and release the locks with the returned func once the sale is
+// recorded.
+func lockSeats(ctx context.Context, studentID string, courseIDs ...string) (func(), *enrollError) {
+	if _, local := seatLocks.(localSeatLocks); local {
+		return func() {}, nil
+	}
+
+	// Cross-listed codes sell their primary's seats, so they share its lock
+	mu.RLock()
+	var owners []string
+	for _, id := range courseIDs {
+		if c := findCourse(id); c != nil {
+			owners = append(owners, "course:"+seatOwner(c).ID)
+		}
+	}
+	mu.RUnlock()
+	slices.Sort(owners)
+	names := append([]string{"student:" + studentID}, slices.Compact(owners)...)
+
+	unlock, err := seatLocks.acquire(ctx, names)
+	if err != nil {
+		seatLockAcquisitions.WithLabelValues("timeout").Inc()
+		slog.WarnContext(ctx, "seat lock: not acquired", "student_id", studentID, "courses", courseIDs, "err", err)
+		return nil, &enrollError{http.StatusServiceUnavailable, models.CodeUnavailable, "Seats are busy; try again"}
+	}
+	seatLockAcquisitions.WithLabelValues("acquired").Inc()
+	if !seats.catchUp(seats.version()) {
+		unlock()
+		return nil, &enrollError{http.StatusServiceUnavailable, models.CodeUnavailable, "Course data is still catching up; try again"}
+	}
+	return unlock, nil
+}
+
+// localSeatLocks is the single-instance locker: it takes nothing.
+type localSeatLocks struct{}
+
+func (localSeatLocks) acquire(ctx context.Context, names []string) (func(), error) {
+	return func() {}, nil
+}
+
+// redisSeatLocks keeps each lock as the key lock:<name>, set to a token only
+// its holder knows, so a holder whose lease lapsed cannot release a lock
+// another replica has since taken.
+type redisSeatLocks struct {
+	client *redis.Client
+}
+
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0
+`)
+
+func (l redisSeatLocks) acquire(ctx context.Context, names []string) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, seatLockWait)
+	defer cancel()
+	token := newHoldID()
+	var held []string
+	release := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		for _, key := range held {
+			if err := unlockScript.Run(ctx, l.client, []string{key}, token).Err(); err != nil {
+				slog.Warn("seat lock: release failed, the lease will lapse", "lock", key, "err", err)
+			}
+		}
+	}
+
+	for _, name := range names {
+		key := "lock:" + name
+		for {
+			ok, err := l.client.SetNX(ctx, key, token, seatLockLease).Result()
+			if err == nil && ok {
+				held = append(held, key)
+				break
+			}
+			if err == nil {
+				select {
+				case <-time.After(seatLockRetry):
+					continue
+				case <-ctx.Done():
+					err = ctx.Err()
+				}
+			}
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"shared/studentid"
+)
+
+// --- Drop Preview ---
+
+// GET /drop/preview?student_id=&course_id= says what POST /drop would do
+// without doing it, so the portal can ask the student to confirm: which
+// co-requisites go too, how many credits that frees, whether the drop is
+// still refunded, and whether the student could enroll again. Drops made
+// before DROP_REFUND_UNTIL are refunded in full and later ones not at all;
+// unset means every drop is refunded.
+
+var dropRefundUntil time.Time // Set by loadCalendar
+
+type DropPreview struct {
+	CourseID           string     `json:"course_id"`
+	Dropped            []string   `json:"dropped"` // The course and the co-requisites that go with it
+	Credits            int        `json:"credits"`
+	Refund             string     `json:"refund"` // "full" or "none"
+	RefundUntil        *time.Time `json:"refund_until,omitempty"`
+	RegistrationCloses *time.Time `json:"registration_closes,omitempty"`
+	CanReEnroll        bool       `json:"can_re_enroll"` // False once registration has closed
+	Waitlisted         int        `json:"waitlisted"`    // Students waiting for the seat
+}
+
+func previewDrop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID, courseID := r.URL.Query().Get("student_id"), r.URL.Query().Get("course_id")
+	if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := enrollingProfile(w, r, studentID); !ok {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(courseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !enrollments[c.ID+":"+studentID] {
+		http.Error(w, "Student not enrolled", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	p := DropPreview{CourseID: c.ID, Dropped: []string{c.ID}, Credits: c.Credits, Refund: "full", CanReEnroll: registrationClosed(now) == nil, Waitlisted: len(waitlists[c.ID])}
+	for _, id := range c.CoRequisites {
+		if linked := findCourse(id); linked != nil && enrollments[linked.ID+":"+studentID] {
+			p.Dropped = append(p.Dropped, linked.ID)
+			p.Credits += linked.Credits
+		}
+	}
+	if !dropRefundUntil.IsZero() {
+		p.RefundUntil = &dropRefundUntil
+		if now.After(dropRefundUntil) {
+			p.Refund = "none"
+		}
+	}
+	if !registrationCloses.IsZero() {
+		p.RegistrationCloses = &registrationCloses
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
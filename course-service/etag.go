@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Conditional GET ---
+
+// catalogVersion remembers when a catalog view last changed so clients can
+// revalidate with If-None-Match or If-Modified-Since instead of refetching.
+type catalogVersion struct {
+	ETag     string
+	Modified time.Time
+}
+
+var (
+	etagMu          sync.Mutex
+	catalogVersions = make(map[string]catalogVersion) // Key: normalized query
+	catalogKeys     []string                          // The same keys, oldest first
+)
+
+// Each distinct query gets an entry, so only the newest are kept. A view
+// whose entry was evicted gets a new Last-Modified; its ETag is unchanged.
+const maxCatalogVersions = 10000
+
+// versionFor returns the ETag and Last-Modified time for a rendered view,
+// bumping the timestamp whenever the content changes.
+func versionFor(key string, body []byte) catalogVersion {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	etagMu.Lock()
+	defer etagMu.Unlock()
+	v, ok := catalogVersions[key]
+	if !ok || v.ETag != etag {
+		v = catalogVersion{ETag: etag, Modified: time.Now().UTC().Truncate(time.Second)}
+		catalogVersions[key] = v
+	}
+	if !ok {
+		catalogKeys = append(catalogKeys, key)
+		if len(catalogKeys) > maxCatalogVersions {
+			delete(catalogVersions, catalogKeys[0])
+			catalogKeys = catalogKeys[1:]
+		}
+	}
+	return v
+}
+
+// etagMatches implements the weak comparison used by If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the client's cached copy is still current.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+func notModified(r *http.Request, v catalogVersion) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, v.ETag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !v.Modified.After(t)
+		}
+	}
+	return false
+}
+
+// writeConditional sends body, or a bare 304 if the client already has it.
+func writeConditional(w http.ResponseWriter, r *http.Request, v catalogVersion, body *bytes.Buffer) {
+	w.Header().Set("ETag", v.ETag)
+	w.Header().Set("Last-Modified", v.Modified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if notModified(r, v) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,35 @@
+package main
+
+import "shared/events"
+
+// --- Domain Events ---
+
+// DomainEvent is published through the event bus on subject
+// "enrollment.<Type>". The schema is documented in the README.
+type DomainEvent = events.Event
+
+// emit writes an event to the outbox. Callers usually hold mu, so the event
+// is written in the same critical section as the change it announces. An
+// event announcing a change that goes into the enrollment history is passed
+// to recordEvent instead, made with announce.
+func emit(ev DomainEvent) {
+	outbox.Add(0, announce(ev))
+	events.Kick()
+}
+
+// emitCatalog announces a change to a course or department that other
+// services cache, on subject "catalog.<Type>". Callers may hold mu.
+func emitCatalog(ev DomainEvent) {
+	outbox.Add(0, announceCatalog(ev))
+	events.Kick()
+}
+
+// announce makes the message for an enrollment event.
+func announce(ev DomainEvent) events.Message {
+	return events.NewMessage("enrollment."+ev.Type, ev)
+}
+
+// announceCatalog makes the message for a catalog event.
+func announceCatalog(ev DomainEvent) events.Message {
+	return events.NewMessage("catalog."+ev.Type, ev)
+}
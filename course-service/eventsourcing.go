@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Event-Sourced Enrollments ---
+
+// The enrollment history is the record of who is enrolled where. Every
+// enrollment, drop and swap is appended to it as an "enroll", "drop" or
+// "swap" event, together with the seat pool it took, and the enrollments
+// map is a projection of those events. At startup the projection is rebuilt
+// by replaying ENROLLMENT_LOG_PATH, so enrollments survive a restart, and
+// replaying the trail only up to a moment answers who was enrolled then
+// (GET /enrollments/as-of).
+//
+// With a shared seat store the store is the record instead: enrollments made
+// on other replicas are not in this replica's log, so nothing is replayed and
+// runSeatSync fills the map.
+
+// Enrollment is one student's place in a course, as replayed from the trail.
+type Enrollment struct {
+	CourseID  string    `json:"course_id"`
+	StudentID string    `json:"student_id"`
+	Pool      string    `json:"pool,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// loggedEvents is how many events openHistory read back from the log, and
+// so how many restoreEnrollments replays. Guarded by historyMu.
+var loggedEvents int
+
+// applyEnrollmentEvent folds one event into a projection keyed by
+// "CourseID:StudentID". Events that move no student are ignored.
+func applyEnrollmentEvent(state map[string]Enrollment, ev EnrollmentEvent) {
+	switch ev.Type {
+	case "enroll":
+		state[ev.CourseID+":"+ev.StudentID] = Enrollment{CourseID: ev.CourseID, StudentID: ev.StudentID, Pool: ev.Pool, Since: ev.At}
+	case "drop":
+		delete(state, ev.CourseID+":"+ev.StudentID)
+	case "swap":
+		delete(state, ev.FromCourseID+":"+ev.StudentID)
+		state[ev.CourseID+":"+ev.StudentID] = Enrollment{CourseID: ev.CourseID, StudentID: ev.StudentID, Pool: ev.Pool, Since: ev.At}
+	}
+}
+
+// enrollmentsAt replays the trail up to and including at.
+func enrollmentsAt(at time.Time) map[string]Enrollment {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	state := make(map[string]Enrollment)
+	for _, ev := range history {
+		if ev.At.After(at) {
+			break
+		}
+		applyEnrollmentEvent(state, ev)
+	}
+	return state
+}
+
+// inEnrollmentLog reports whether the log read at startup ever enrolled the
+// student in the course, so a fixture does not enroll them again after they
+// dropped.
+func inEnrollmentLog(courseID, studentID string) bool {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	for _, ev := range history[:loggedEvents] {
+		if (ev.Type == "enroll" || ev.Type == "swap") && ev.CourseID == courseID && ev.StudentID == studentID {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreEnrollments rebuilds the enrollments, and the seats they take, from
+// the events read back from the log. It runs once at startup, after the
+// catalog and any fixture are loaded. An enrollment in a course that is no
+// longer in the catalog is skipped; one the course no longer has a seat for
+// is kept, since the student was admitted.
+func restoreEnrollments() {
+	if _, shared := seats.(redisSeats); shared {
+		return
+	}
+	historyMu.Lock()
+	state := make(map[string]Enrollment)
+	for _, ev := range history[:loggedEvents] {
+		applyEnrollmentEvent(state, ev)
+	}
+	historyMu.Unlock()
+	if len(state) == 0 {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	restored := 0
+	for key, e := range state {
+		c := findCourse(e.CourseID)
+		if c == nil {
+			slog.Warn("enrollment log: course not in the catalog, enrollment skipped", "course_id", e.CourseID, "student_id", e.StudentID)
+			continue
+		}
+		if enrollments[key] {
+			continue
+		}
+		pool, err := seats.take(c, []string{e.Pool}, e.StudentID)
+		if err != nil {
+			slog.Warn("enrollment log: course is over capacity", "course_id", c.ID, "student_id", e.StudentID)
+		} else {
+			seatsChanged(c)
+		}
+		enrollments[key] = true
+		if pool != "" {
+			enrollmentPools[key] = pool
+		}
+		restored++
+	}
+	slog.Info("enrollments restored from log", "events", loggedEvents, "enrollments", restored)
+}
+
+// enrollmentsAsOf (GET /enrollments/as-of?at=&course_id=&student_id=) lists
+// who was enrolled at a moment, by replaying the trail up to it. at is
+// RFC3339 and defaults to now. Restricted to the registrar and admins.
+func enrollmentsAsOf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	at := time.Now()
+	if v := q.Get("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid at: expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+	courseID, studentID := q.Get("course_id"), q.Get("student_id")
+
+	list := []Enrollment{}
+	for _, e := range enrollmentsAt(at) {
+		if (courseID == "" || e.CourseID == courseID) && (studentID == "" || e.StudentID == studentID) {
+			list = append(list, e)
+		}
+	}
+	slices.SortFunc(list, func(a, b Enrollment) int {
+		if n := strings.Compare(a.CourseID, b.CourseID); n != 0 {
+			return n
+		}
+		return strings.Compare(a.StudentID, b.StudentID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"at": at.UTC(), "enrollments": list})
+}
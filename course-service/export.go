@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Enrollment Export ---
+
+// Nightly extracts for the registrar. Rows are produced one course at a time
+// and flushed to the client as they go, so the export never holds mu for
+// longer than it takes to read a single course.
+
+type ExportRow struct {
+	Term         string `json:"term"`
+	CourseID     string `json:"course_id"`
+	StudentID    string `json:"student_id"`
+	DepartmentID string `json:"department_id,omitempty"`
+	Credits      int    `json:"credits"`
+	SeatPool     string `json:"seat_pool,omitempty"`
+	Archived     bool   `json:"archived"`
+}
+
+var exportHeader = []string{"term", "course_id", "student_id", "department_id", "credits", "seat_pool", "archived"}
+
+// courseRows snapshots one course's enrollments, sorted by student.
+func courseRows(courseID string) []ExportRow {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(courseID)
+	if c == nil {
+		return nil
+	}
+	var rows []ExportRow
+	prefix := c.ID + ":"
+	for key, enrolled := range enrollments {
+		if !enrolled || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rows = append(rows, ExportRow{
+			Term:         c.Term,
+			CourseID:     c.ID,
+			StudentID:    strings.TrimPrefix(key, prefix),
+			DepartmentID: c.DepartmentID,
+			Credits:      c.Credits,
+			SeatPool:     enrollmentPools[key],
+			Archived:     isArchived(c),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].StudentID < rows[j].StudentID })
+	return rows
+}
+
+// exportEnrollments streams every enrollment as CSV (default) or JSON Lines
+// (?format=jsonl), optionally filtered by ?course_id= and ?term=.
+func exportEnrollments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		http.Error(w, "format must be csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	var courseIDs []string
+	term := requestedTerm(r)
+	mu.Lock()
+	for _, c := range courses {
+		if id := q.Get("course_id"); c.Term == term && (id == "" || id == c.ID) {
+			courseIDs = append(courseIDs, c.ID)
+		}
+	}
+	mu.Unlock()
+
+	filename := "enrollments-" + time.Now().UTC().Format("20060102") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	flusher, _ := w.(http.Flusher)
+
+	if format == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, id := range courseIDs {
+			for _, row := range courseRows(id) {
+				if err := enc.Encode(row); err != nil {
+					return // Client went away
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write(exportHeader)
+	for _, id := range courseIDs {
+		for _, row := range courseRows(id) {
+			cw.Write([]string{row.Term, row.CourseID, row.StudentID, row.DepartmentID,
+				strconv.Itoa(row.Credits), row.SeatPool, strconv.FormatBool(row.Archived)})
+		}
+		cw.Flush()
+		if cw.Error() != nil {
+			return // Client went away
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"shared/models"
+	"shared/studentid"
+)
+
+// --- Fixture Loader ---
+
+// Local and dev environments start from a fixture instead of the handful of
+// built-in courses: go run . -fixture dev.yaml (or FIXTURE_PATH). The fixture
+// is either one YAML file or a directory of CSV files named after the YAML
+// sections (colleges.csv, departments.csv, courses.csv, sections.csv,
+// enrollments.csv) whose header row uses the same field names. List fields
+// such as prerequisites, co_requisites and meetings are separated by ";" in
+// CSV.
+//
+//	terms: [2026-T2]
+//	colleges:    [{id: CCS, name: College of Computer Studies}]
+//	departments: [{id: CS, name: Computer Science, college_id: CCS}]
+//	courses:     [{code: CSALGCM, title: Algorithms, credits: 3, department_id: CS, prerequisites: [CCPROG2]}]
+//	sections:    [{course: CSALGCM, term: 2026-T2, capacity: 40, instructor: faculty1, meetings: ["Tue 09:15-10:45 G304"]}]
+//	enrollments: [{student_id: student1, course_id: CSALGCM@2026-T2}]
+//
+// Fixture data is added to the built-in catalog. A section's ID defaults to
+// its course code in the seed term and CODE@TERM in any other. Enrollments go
+// through the normal admission checks, so a fixture cannot overfill a section.
+
+type Fixture struct {
+	Terms       []string            `yaml:"terms"`
+	Colleges    []College           `yaml:"colleges"`
+	Departments []Department        `yaml:"departments"`
+	Courses     []FixtureCourse     `yaml:"courses"`
+	Sections    []FixtureSection    `yaml:"sections"`
+	Enrollments []FixtureEnrollment `yaml:"enrollments"`
+}
+
+type FixtureCourse struct {
+	Code          string   `yaml:"code"`
+	Title         string   `yaml:"title"`
+	Credits       int      `yaml:"credits"`
+	DepartmentID  string   `yaml:"department_id"`
+	Prerequisites []string `yaml:"prerequisites"` // Catalog codes, checked against the Grade Service
+}
+
+type FixtureSection struct {
+	ID             string   `yaml:"id"`
+	Course         string   `yaml:"course"`
+	Term           string   `yaml:"term"`
+	Capacity       int      `yaml:"capacity"`
+	OverbookFactor float64  `yaml:"overbook_factor"`
+	Instructor     string   `yaml:"instructor"`
+	CoRequisites   []string `yaml:"co_requisites"`
+	Meetings       []string `yaml:"meetings"` // e.g. "Mon 09:15-10:45 G304"
+}
+
+type FixtureEnrollment struct {
+	StudentID string `yaml:"student_id"`
+	CourseID  string `yaml:"course_id"`
+}
+
+// readFixture parses a YAML file or a directory of CSV files.
+func readFixture(path string) (*Fixture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if !info.IsDir() {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &f, nil
+	}
+
+	sections := map[string]interface{}{
+		"colleges.csv":    &f.Colleges,
+		"departments.csv": &f.Departments,
+		"courses.csv":     &f.Courses,
+		"sections.csv":    &f.Sections,
+		"enrollments.csv": &f.Enrollments,
+	}
+	for name, target := range sections {
+		err := decodeCSV(filepath.Join(path, name), target)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// csvListFields are the columns that hold a ";"-separated list.
+var csvListFields = map[string]bool{"prerequisites": true, "co_requisites": true, "meetings": true}
+
+// decodeCSV turns rows into a YAML sequence of mappings and decodes that, so
+// CSV and YAML fixtures share one set of field names and type conversions.
+func decodeCSV(path string, target interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, row := range rows[1:] {
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		for i, header := range rows[0] {
+			if i >= len(row) || row[i] == "" {
+				continue
+			}
+			header = strings.TrimSpace(header)
+			value := &yaml.Node{Kind: yaml.ScalarNode, Value: row[i]}
+			if csvListFields[header] {
+				value = &yaml.Node{Kind: yaml.SequenceNode}
+				for _, item := range strings.Split(row[i], ";") {
+					value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(item)})
+				}
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: header}, value)
+		}
+		seq.Content = append(seq.Content, m)
+	}
+	if err := seq.Decode(target); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyFixture adds a fixture's catalog and enrollments. It runs at startup,
+// after the seat store is open, and stops at the first invalid entry.
+func applyFixture(f *Fixture) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// 1. Terms and the college/department hierarchy
+	for _, term := range f.Terms {
+		if !termExists(term) {
+			termMu.Lock()
+			termIDs = append(termIDs, term)
+			termMu.Unlock()
+		}
+	}
+	for _, c := range f.Colleges {
+		if findCollege(c.ID) != nil {
+			return fmt.Errorf("college %s already exists", c.ID)
+		}
+		college := c
+		colleges = append(colleges, &college)
+	}
+	for _, d := range f.Departments {
+		if findDepartment(d.ID) != nil {
+			return fmt.Errorf("department %s already exists", d.ID)
+		}
+		if findCollege(d.CollegeID) == nil {
+			return fmt.Errorf("department %s: college %s not found", d.ID, d.CollegeID)
+		}
+		dept := d
+		departments = append(departments, &dept)
+	}
+
+	// 2. Sections, each an offering of a catalog course in one term
+	catalog := make(map[string]FixtureCourse)
+	for _, c := range f.Courses {
+		if c.DepartmentID != "" && findDepartment(c.DepartmentID) == nil {
+			return fmt.Errorf("course %s: department %s not found", c.Code, c.DepartmentID)
+		}
+		catalog[c.Code] = c
+	}
+	var added []*Course
+	for _, s := range f.Sections {
+		course, ok := catalog[s.Course]
+		if !ok {
+			return fmt.Errorf("section of %s: course not in fixture", s.Course)
+		}
+		if s.Term == "" {
+			s.Term = currentTerm()
+		}
+		if !termExists(s.Term) {
+			return fmt.Errorf("section of %s: term %s not found", s.Course, s.Term)
+		}
+		if s.ID == "" {
+			s.ID = offeringID(s.Course, s.Term)
+		}
+		if findCourse(s.ID) != nil {
+			return fmt.Errorf("section %s already exists", s.ID)
+		}
+		c := &Course{Course: models.Course{
+			ID:             s.ID,
+			Code:           course.Code,
+			Term:           s.Term,
+			Title:          course.Title,
+			Credits:        course.Credits,
+			DepartmentID:   course.DepartmentID,
+			Instructor:     s.Instructor,
+			Capacity:       s.Capacity,
+			OverbookFactor: s.OverbookFactor,
+			CoRequisites:   s.CoRequisites,
+		}}
+		if len(course.Prerequisites) > 0 {
+			c.Rules = &EnrollmentRules{Prerequisites: course.Prerequisites}
+		}
+		for _, raw := range s.Meetings {
+			m, err := parseMeeting(raw)
+			if err != nil {
+				return fmt.Errorf("section %s: %w", s.ID, err)
+			}
+			c.Meetings = append(c.Meetings, m)
+		}
+		c.OpenSlots = sellableSeats(c)
+		if err := seats.add(c); err != nil {
+			return err
+		}
+		courses = append(courses, c)
+		added = append(added, c)
+	}
+	for _, c := range added {
+		for _, id := range c.CoRequisites {
+			if findCourse(id) == nil {
+				return fmt.Errorf("section %s: co-requisite %s not found", c.ID, id)
+			}
+		}
+	}
+
+	// 3. Mock enrollments, admitted like any other
+	for _, e := range f.Enrollments {
+		c := findCourse(e.CourseID)
+		if c == nil {
+			return fmt.Errorf("enrollment of %s: course %s not found", e.StudentID, e.CourseID)
+		}
+		if err := studentid.Validate("default", e.StudentID); err != nil {
+			return fmt.Errorf("enrollment in %s: %w", e.CourseID, err)
+		}
+		if inEnrollmentLog(c.ID, e.StudentID) {
+			continue // The enrollment log decides, even if the student dropped since
+		}
+		err := admit(c, e.StudentID, nil)
+		if err != nil && err.Code == models.CodeAlreadyEnrolled {
+			continue // Another replica sharing the seat store loaded it first
+		}
+		if err != nil {
+			return fmt.Errorf("enrollment of %s in %s: %s", e.StudentID, e.CourseID, err.Message)
+		}
+		recordEvent(EnrollmentEvent{Type: "enroll", StudentID: e.StudentID, CourseID: c.ID, Actor: "fixture"})
+	}
+
+	slog.Info("fixture loaded", "colleges", len(f.Colleges), "departments", len(f.Departments),
+		"sections", len(added), "enrollments", len(f.Enrollments))
+	return nil
+}
+
+// loadFixture reads and applies the fixture at path, if one was given.
+func loadFixture(path string) {
+	if path == "" {
+		return
+	}
+	f, err := readFixture(path)
+	if err != nil {
+		log.Fatalf("cannot read fixture: %v", err)
+	}
+	if err := applyFixture(f); err != nil {
+		log.Fatalf("invalid fixture %s: %v", path, err)
+	}
+}
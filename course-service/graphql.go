@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+
+	"shared/studentid"
+)
+
+// --- GraphQL ---
+
+// A read-only GraphQL view over the catalog so clients can fetch courses,
+// seat counts and one student's enrollment status in a single round trip,
+// selecting only the fields they need. For example:
+//
+//	{ courses(studentId: "student1") { id openSlots isEnrolled } }
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+var courseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Course",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: courseField(func(c Course) interface{} { return c.ID })},
+		"title":            &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.Title })},
+		"credits":          &graphql.Field{Type: graphql.Int, Resolve: courseField(func(c Course) interface{} { return c.Credits })},
+		"openSlots":        &graphql.Field{Type: graphql.Int, Resolve: courseField(func(c Course) interface{} { return c.OpenSlots })},
+		"capacity":         &graphql.Field{Type: graphql.Int, Resolve: courseField(func(c Course) interface{} { return c.Capacity })},
+		"instructor":       &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.Instructor })},
+		"departmentId":     &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.DepartmentID })},
+		"archived":         &graphql.Field{Type: graphql.Boolean, Resolve: courseField(func(c Course) interface{} { return c.ArchivedAt != nil })},
+		"coRequisites":     &graphql.Field{Type: graphql.NewList(graphql.String), Resolve: courseField(func(c Course) interface{} { return c.CoRequisites })},
+		"code":             &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.Code })},
+		"term":             &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.Term })},
+		"crossListOf":      &graphql.Field{Type: graphql.String, Resolve: courseField(func(c Course) interface{} { return c.CrossListOf })},
+		"waitlisted":       &graphql.Field{Type: graphql.Int, Resolve: courseField(func(c Course) interface{} { return c.Waitlisted })},
+		"isEnrolled":       &graphql.Field{Type: graphql.Boolean, Resolve: courseField(func(c Course) interface{} { return c.IsEnrolled })},
+		"waitlistPosition": &graphql.Field{Type: graphql.Int, Resolve: courseField(func(c Course) interface{} { return c.WaitlistPosition })},
+	},
+})
+
+func courseField(get func(Course) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(Course)), nil
+	}
+}
+
+// studentArg reads and validates the optional studentId argument.
+func studentArg(p graphql.ResolveParams) (string, error) {
+	studentID, _ := p.Args["studentId"].(string)
+	if studentID == "" {
+		return "", nil
+	}
+	tenant, _ := p.Context.Value(tenantKey{}).(string)
+	if err := studentid.Validate(tenant, studentID); err != nil {
+		return "", err
+	}
+	return studentID, nil
+}
+
+type tenantKey struct{}
+
+var courseSchema = mustSchema(graphql.SchemaConfig{
+	Query: graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"courses": &graphql.Field{
+				Type: graphql.NewList(courseType),
+				Args: graphql.FieldConfigArgument{
+					"studentId":       &graphql.ArgumentConfig{Type: graphql.String},
+					"includeArchived": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					"term":            &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					studentID, err := studentArg(p)
+					if err != nil {
+						return nil, err
+					}
+					includeArchived, _ := p.Args["includeArchived"].(bool)
+					term, _ := p.Args["term"].(string)
+					if term == "" {
+						term = currentTerm()
+					}
+					mu.RLock()
+					defer mu.RUnlock()
+					list := []Course{}
+					for _, c := range courses {
+						if c.Term != term || (isArchived(c) && !includeArchived) {
+							continue
+						}
+						list = append(list, courseView(c, studentID))
+					}
+					return list, nil
+				},
+			},
+			"course": &graphql.Field{
+				Type: courseType,
+				Args: graphql.FieldConfigArgument{
+					"id":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"studentId": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					studentID, err := studentArg(p)
+					if err != nil {
+						return nil, err
+					}
+					mu.RLock()
+					defer mu.RUnlock()
+					c := findCourse(p.Args["id"].(string))
+					if c == nil {
+						return nil, nil
+					}
+					return courseView(c, studentID), nil
+				},
+			},
+			"enrollments": &graphql.Field{
+				Type:        graphql.NewList(courseType),
+				Description: "Courses the student is enrolled in",
+				Args: graphql.FieldConfigArgument{
+					"studentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					studentID, err := studentArg(p)
+					if err != nil {
+						return nil, err
+					}
+					mu.RLock()
+					defer mu.RUnlock()
+					list := []Course{}
+					for _, c := range courses {
+						if isEnrolled(c.ID, studentID) {
+							list = append(list, courseView(c, studentID))
+						}
+					}
+					return list, nil
+				},
+			},
+		},
+	}),
+})
+
+// mustSchema panics at startup if the schema definition above is inconsistent.
+func mustSchema(config graphql.SchemaConfig) graphql.Schema {
+	schema, err := graphql.NewSchema(config)
+	if err != nil {
+		panic("graphql schema: " + err.Error())
+	}
+	return schema
+}
+
+// graphqlHandler serves queries by GET (?query=) or POST (JSON body).
+func graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         courseSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(r.Context(), tenantKey{}, studentid.Tenant(r)),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
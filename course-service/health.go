@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+
+	"shared/events"
+	"shared/health"
+)
+
+// --- Readiness ---
+
+// readyz reports "degraded" rather than "down" when the Auth Service is
+// unreachable: the catalog and enrollment keep working, only the
+// role-protected admin endpoints fail. The shared seat store is hard, since
+// no seat can be sold without it; the event broker is soft, since events fall
+// back to the log.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	deps := map[string]health.DependencyStatus{
+		"auth": health.Probe(authServiceURL()+"/readyz", false),
+	}
+	if store, ok := seats.(redisSeats); ok {
+		deps["seat_store"] = health.Check(true, store.ping)
+	}
+	if broker := events.Broker(); broker != "" {
+		deps[broker] = health.Check(false, events.Ping)
+	}
+	health.Write(w, health.NewReport(deps))
+}
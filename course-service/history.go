@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"shared/config"
+	"shared/events"
+)
+
+// --- Enrollment History ---
+
+// EnrollmentEvent is one entry in the append-only audit trail.
+type EnrollmentEvent struct {
+	Seq          int64            `json:"seq"`
+	Type         string           `json:"type"` // "enroll", "drop", "swap", "override", "permission", "create", "update", "archive" or "restore"
+	StudentID    string           `json:"student_id"`
+	CourseID     string           `json:"course_id,omitempty"`
+	FromCourseID string           `json:"from_course_id,omitempty"`
+	Pool         string           `json:"pool,omitempty"`      // Seat pool an enroll or swap took, for replays
+	Announced    []events.Message `json:"announced,omitempty"` // Domain events announcing the change, written with it
+	Actor        string           `json:"actor"`
+	Detail       string           `json:"detail,omitempty"`
+	At           time.Time        `json:"at"`
+}
+
+// historyMu guards the log separately from mu so events can be recorded
+// from inside enrollment critical sections (lock order: mu, then historyMu).
+var (
+	historyMu   sync.Mutex
+	history     []EnrollmentEvent
+	historyFile *os.File
+)
+
+// openHistory replays and then appends to ENROLLMENT_LOG_PATH when set;
+// otherwise the trail lives in memory only.
+func openHistory() {
+	path := config.Get("ENROLLMENT_LOG_PATH")
+	if path == "" {
+		return
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var ev EnrollmentEvent
+			if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+				log.Fatalf("corrupt enrollment log %s: %v", path, err)
+			}
+			history = append(history, ev)
+		}
+		f.Close()
+		loggedEvents = len(history)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Fatalf("cannot open enrollment log %s: %v", path, err)
+	}
+	historyFile = f
+}
+
+// closeHistory syncs the log to disk and closes it at shutdown; anything
+// recorded afterwards stays in memory.
+func closeHistory(ctx context.Context) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if historyFile == nil {
+		return nil
+	}
+	f := historyFile
+	historyFile = nil
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordEvent appends an event to the trail, stamping its sequence number and
+// time, and for an enroll or swap the seat pool the student now holds. The
+// domain events that announce the change are written in the same record and
+// then handed to the outbox, so a crash in between cannot keep the change
+// and lose its events: openOutbox takes them back from the log. Callers must
+// not hold enrollMu.
+func recordEvent(ev EnrollmentEvent, announced ...events.Message) {
+	if ev.Type == "enroll" || ev.Type == "swap" {
+		enrollMu.Lock()
+		ev.Pool = enrollmentPools[ev.CourseID+":"+ev.StudentID]
+		enrollMu.Unlock()
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	ev.Seq = int64(len(history)) + 1
+	ev.At = time.Now().UTC()
+	if ev.Actor == "" {
+		ev.Actor = ev.StudentID
+	}
+	ev.Announced = announced
+	history = append(history, ev)
+
+	if historyFile != nil {
+		line, _ := json.Marshal(ev)
+		if _, err := historyFile.Write(append(line, '\n')); err != nil {
+			slog.Error("failed to persist enrollment event", "seq", ev.Seq, "err", err)
+		}
+	}
+	if len(announced) > 0 {
+		outbox.Add(ev.Seq, announced...)
+		events.Kick()
+	}
+}
+
+// flowCounts is how many students have entered and left a course over the
+// whole trail. A swap counts as a drop from one course and an enrollment in
+// the other.
+type flowCounts struct {
+	Enrollments int
+	Drops       int
+}
+
+// enrollmentFlows tallies enrollments and drops per course from the trail.
+func enrollmentFlows() map[string]flowCounts {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	counts := make(map[string]flowCounts)
+	for _, ev := range history {
+		switch ev.Type {
+		case "enroll":
+			n := counts[ev.CourseID]
+			n.Enrollments++
+			counts[ev.CourseID] = n
+		case "drop":
+			n := counts[ev.CourseID]
+			n.Drops++
+			counts[ev.CourseID] = n
+		case "swap":
+			to, from := counts[ev.CourseID], counts[ev.FromCourseID]
+			to.Enrollments++
+			from.Drops++
+			counts[ev.CourseID], counts[ev.FromCourseID] = to, from
+		}
+	}
+	return counts
+}
+
+// enrollmentHistory lets the registrar query the trail by student, course and time range.
+func enrollmentHistory(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	studentID := q.Get("student_id")
+	courseID := q.Get("course_id")
+	var from, to time.Time
+	var err error
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid from: expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid to: expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	historyMu.Lock()
+	matches := []EnrollmentEvent{}
+	for _, ev := range history {
+		if studentID != "" && ev.StudentID != studentID {
+			continue
+		}
+		if courseID != "" && ev.CourseID != courseID && ev.FromCourseID != courseID {
+			continue
+		}
+		if !from.IsZero() && ev.At.Before(from) {
+			continue
+		}
+		if !to.IsZero() && ev.At.After(to) {
+			continue
+		}
+		matches = append(matches, ev)
+	}
+	historyMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
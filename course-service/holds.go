@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/config"
+	"shared/models"
+	"shared/studentid"
+)
+
+// --- Seat Holds ---
+
+// SeatHold reserves one slot of a course for a student until ExpiresAt.
+// The slot is taken out of OpenSlots while the hold is live, so other
+// students cannot grab it during a multi-step checkout.
+type SeatHold struct {
+	ID        string    `json:"hold_id"`
+	CourseID  string    `json:"course_id"`
+	StudentID string    `json:"student_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Pool      string    `json:"seat_pool,omitempty"`
+
+	saga string // The enrollment saga the seat is held for, if any; only it may confirm or release the hold
+}
+
+type HoldRequest struct {
+	CourseID   string `json:"course_id"`
+	StudentID  string `json:"student_id"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+type HoldActionRequest struct {
+	HoldID string `json:"hold_id"`
+}
+
+const maxHoldTTL = 15 * time.Minute
+
+var holds = make(map[string]*SeatHold) // Key: hold ID, guarded by mu
+
+func defaultHoldTTL() time.Duration {
+	return time.Duration(config.Int("SEAT_HOLD_TTL_SECONDS")) * time.Second
+}
+
+func newHoldID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// releaseHold returns a held slot to the pool. Callers must hold mu.
+func releaseHold(h *SeatHold) {
+	delete(holds, h.ID)
+	if c := findCourse(h.CourseID); c != nil {
+		releaseSeat(c, h.Pool, "")
+	}
+}
+
+// studentHoldsCourse reports whether a live hold exists. Callers must hold mu.
+func studentHoldsCourse(courseID, studentID string) bool {
+	for _, h := range holds {
+		if h.CourseID == courseID && h.StudentID == studentID {
+			return true
+		}
+	}
+	return false
+}
+
+// expireHolds runs forever, returning the slots of lapsed holds to their courses.
+func expireHolds(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		mu.Lock()
+		for _, h := range holds {
+			if now.After(h.ExpiresAt) {
+				releaseHold(h)
+			}
+		}
+		promoteWaitlists(now)
+		mu.Unlock()
+	}
+}
+
+func placeHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ttl := defaultHoldTTL()
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxHoldTTL {
+		ttl = maxHoldTTL
+	}
+
+	profile, ok := enrollingProfile(w, r, req.StudentID)
+	if !ok {
+		return
+	}
+	if f := registrationClosed(time.Now()); f != nil {
+		http.Error(w, f.Message, http.StatusForbidden)
+		return
+	}
+	unlock, lockErr := lockSeats(r.Context(), req.StudentID, req.CourseID)
+	if lockErr != nil {
+		http.Error(w, lockErr.Message, lockErr.Status)
+		return
+	}
+	defer unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if h := activeRegistrationHold(req.StudentID); h != nil {
+		http.Error(w, "Registration blocked by "+h.Type+" hold", http.StatusForbidden)
+		return
+	}
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if isArchived(c) || isArchived(seatOwner(c)) {
+		http.Error(w, "Course is archived", http.StatusGone)
+		return
+	}
+	if enrolledInClass(c, req.StudentID) {
+		http.Error(w, "Student already enrolled", http.StatusConflict)
+		return
+	}
+	if holdsClass(c, req.StudentID) {
+		http.Error(w, "Student already holds a seat", http.StatusConflict)
+		return
+	}
+	if exceedsCreditLimit(req.StudentID, c.Term, c.Credits) {
+		http.Error(w, "Credit limit exceeded", http.StatusConflict)
+		return
+	}
+	if missing := missingCoRequisites(c, req.StudentID, nil); len(missing) > 0 {
+		http.Error(w, "Co-requisite required: enroll together with "+strings.Join(missing, ", ")+" via /enroll-batch", http.StatusConflict)
+		return
+	}
+	pool, err := takeSeat(c, profile, "")
+	if err != nil {
+		e := seatError(err)
+		http.Error(w, e.Message, e.Status)
+		return
+	}
+	h := &SeatHold{ID: newHoldID(), CourseID: c.ID, StudentID: req.StudentID, ExpiresAt: time.Now().Add(ttl), Pool: pool}
+	holds[h.ID] = h
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h)
+}
+
+// confirmHold turns a live hold into a real enrollment. The student the hold
+// is for confirms it, or the registrar or an admin on their behalf. Holds in
+// courses that need other services are confirmed by a saga, which checks the
+// prerequisites and charges the course first.
+func confirmHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	var req HoldActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	h := ownHold(user, req.HoldID)
+	if h == nil {
+		mu.Unlock()
+		http.Error(w, "Hold not found", http.StatusNotFound)
+		return
+	}
+	studentID, courseID := h.StudentID, h.CourseID
+	mu.Unlock()
+
+	if needsSaga(courseID) {
+		s := newSaga(r, studentID, courseID, nil)
+		s.adopt = req.HoldID
+		answerSaga(w, s, runSaga(s))
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	h = ownHold(user, req.HoldID)
+	if h == nil {
+		http.Error(w, "Hold not found", http.StatusNotFound)
+		return
+	}
+	if err := confirmSeat(h, "confirmed seat hold "+h.ID); err != nil {
+		http.Error(w, err.Message, err.Status)
+		return
+	}
+
+	stampVersion(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "enrolled"}`))
+}
+
+// ownHold finds a hold the user may confirm or release: their own as a
+// student, any as staff. Holds a saga is working on are nobody's to touch.
+// Callers must hold mu.
+func ownHold(user *AuthResponse, id string) *SeatHold {
+	h, ok := holds[id]
+	if !ok || h.saga != "" || (user.Role == "student" && h.StudentID != user.Username) {
+		return nil
+	}
+	return h
+}
+
+// confirmSeat enrolls the student a live hold is for; detail goes into the
+// enrollment history. A lapsed hold is released instead. Callers must hold
+// mu.
+func confirmSeat(h *SeatHold, detail string) *enrollError {
+	if time.Now().After(h.ExpiresAt) {
+		releaseHold(h)
+		return &enrollError{http.StatusGone, models.CodeHoldExpired, "Hold expired"}
+	}
+
+	// The slot was already taken out of OpenSlots when the hold was placed.
+	// The hold leaves the map first so that nothing else confirms or sweeps
+	// it while the seat store answers.
+	delete(holds, h.ID)
+	if c := findCourse(h.CourseID); c != nil {
+		switch err := seats.confirm(c, h.Pool, h.StudentID); err {
+		case nil:
+		case errSeatStore:
+			holds[h.ID] = h
+			return seatError(err)
+		default:
+			// Another replica enrolled the student meanwhile; give the seat back
+			releaseHold(h)
+			return seatError(err)
+		}
+	}
+	enrollments[h.CourseID+":"+h.StudentID] = true
+	if h.Pool != "" {
+		enrollmentPools[h.CourseID+":"+h.StudentID] = h.Pool
+	}
+	if c := findCourse(h.CourseID); c != nil {
+		checkRoomCapacity(c)
+	}
+	recordEvent(EnrollmentEvent{Type: "enroll", StudentID: h.StudentID, CourseID: h.CourseID, Detail: detail},
+		announce(DomainEvent{Type: "EnrollmentCreated", StudentID: h.StudentID, CourseID: h.CourseID, Data: map[string]string{"hold_id": h.ID}}))
+	return nil
+}
+
+func releaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	var req HoldActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	h := ownHold(user, req.HoldID)
+	if h == nil {
+		http.Error(w, "Hold not found", http.StatusNotFound)
+		return
+	}
+	releaseHold(h)
+	promoteWaitlists(time.Now())
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "released"}`))
+}
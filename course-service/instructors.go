@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// --- Instructor Assignment ---
+
+type InstructorAssignment struct {
+	CourseID   string `json:"course_id"`
+	Instructor string `json:"instructor"` // Empty unassigns the course
+}
+
+type Roster struct {
+	CourseID   string   `json:"course_id"`
+	Term       string   `json:"term"`
+	Instructor string   `json:"instructor"`
+	Students   []string `json:"students"`
+}
+
+// canManageCourse reports whether a user may see a course's roster and grades.
+func canManageCourse(user *AuthResponse, c *Course) bool {
+	switch user.Role {
+	case "registrar", "admin":
+		return true
+	case "faculty":
+		return c.Instructor == user.Username
+	}
+	return false
+}
+
+// assignInstructor lets the registrar or an admin set who teaches a course.
+func assignInstructor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	var req InstructorAssignment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	c.Instructor = req.Instructor
+	touch(c)
+	emitCatalog(DomainEvent{Type: "CourseUpdated", CourseID: c.ID, Data: map[string]string{"changed": "instructor"}})
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "instructor assigned"}`))
+}
+
+// myCourses lists the courses taught by the calling faculty member.
+func myCourses(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "faculty")
+	if !ok {
+		return
+	}
+
+	mu.Lock()
+	taught := []Course{}
+	for _, c := range courses {
+		if c.Instructor == user.Username {
+			taught = append(taught, *c)
+		}
+	}
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(taught)
+}
+
+// roster lists the students enrolled in a course, for its instructor or the registrar.
+func roster(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+	courseID := r.URL.Query().Get("course_id")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(courseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !canManageCourse(user, c) {
+		http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+		return
+	}
+
+	// Cross-listed codes share one class, so their rosters are merged
+	listed := make(map[string]bool)
+	for _, listing := range crossListGroup(c) {
+		listed[listing.ID] = true
+	}
+	out := Roster{CourseID: c.ID, Term: c.Term, Instructor: c.Instructor, Students: []string{}}
+	for key := range enrollments {
+		if cid, sid, _ := strings.Cut(key, ":"); listed[cid] {
+			out.Students = append(out.Students, sid)
+		}
+	}
+	sort.Strings(out.Students)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// --- Lock Sharding ---
+
+// mu is a read/write lock over the whole catalog. Anything that changes the
+// catalog's structure, or touches several courses at once (batches, swaps,
+// drops, holds, waitlist promotion, admin edits), takes it exclusively and
+// may then read and write all state directly.
+//
+// The registration-day hot path (single-course /enroll and catalog reads)
+// only takes mu shared, so enrollments in different courses run in parallel.
+// Those callers serialize on finer locks instead, acquired in this order:
+//
+//  1. studentLock(id) - one student's credit load across courses
+//  2. courseLock(id)  - one course's seats, pools and version; cross-listed
+//     codes take their primary's lock, since they share its seats
+//  3. enrollMu        - the enrollments, enrollmentPools and waitlists maps
+//
+// Course and student locks are striped, so two IDs may share a stripe. That
+// only costs parallelism as long as no caller ever holds two course locks,
+// or two student locks, at once: the second could be the stripe it already
+// holds. Code that needs several courses takes mu exclusively instead.
+//
+// With a shared seat store, an exclusive holder of mu lets readers back in
+// while it waits on a round-trip (see shareMu), so a slow Redis does not
+// stall catalog reads and enrollments elsewhere. It keeps other writers out
+// throughout, so a batch, swap or drop stays atomic against them. The
+// distributed seat locks of distlock.go keep its student and courses from
+// the readers. A reader never lets go of mu with a stripe held: it waits on
+// Redis with everything it holds.
+
+const lockStripes = 64
+
+var (
+	courseLocks  [lockStripes]sync.Mutex
+	studentLocks [lockStripes]sync.Mutex
+	enrollMu     sync.Mutex
+)
+
+// catalogLock is mu's type: a read/write lock whose exclusive holder can
+// step down to letting readers in without letting another writer in.
+type catalogLock struct {
+	rw        sync.RWMutex
+	writers   sync.Mutex // Held by the exclusive holder until Unlock
+	exclusive bool       // Whether rw is held for writing; only written then
+}
+
+func (l *catalogLock) Lock() {
+	l.writers.Lock()
+	l.rw.Lock()
+	l.exclusive = true
+}
+
+func (l *catalogLock) Unlock() {
+	l.exclusive = false
+	l.rw.Unlock()
+	l.writers.Unlock()
+}
+
+func (l *catalogLock) RLock()   { l.rw.RLock() }
+func (l *catalogLock) RUnlock() { l.rw.RUnlock() }
+
+// shareMu runs fn, which must not touch the catalog. If the caller holds mu
+// exclusively, readers may take it meanwhile; the caller has it back to
+// itself before shareMu returns, and no other writer ran in between. A
+// caller holding mu shared keeps it as it is. Callers must hold mu.
+func shareMu(fn func()) {
+	if !mu.exclusive {
+		fn()
+		return
+	}
+	mu.exclusive = false
+	mu.rw.Unlock()
+	defer func() {
+		mu.rw.Lock()
+		mu.exclusive = true
+	}()
+	fn()
+}
+
+func stripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % lockStripes)
+}
+
+func courseLock(courseID string) *sync.Mutex {
+	return &courseLocks[stripe(courseID)]
+}
+
+func studentLock(studentID string) *sync.Mutex {
+	return &studentLocks[stripe(studentID)]
+}
+
+// isEnrolled reads one enrollment. Callers must hold mu (shared is enough).
+func isEnrolled(courseID, studentID string) bool {
+	enrollMu.Lock()
+	defer enrollMu.Unlock()
+	return enrollments[courseID+":"+studentID]
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"shared/config"
+	"shared/events"
+	"shared/studentid"
+	"shared/testfixtures"
+)
+
+// The benchmarks drive the /enroll handler from parallel clients, once as it
+// ships, with mu shared plus the striped locks of locking.go, and once with
+// every request behind one mutex, as the service did before. Every
+// enrollment succeeds, so they measure seats sold, not rejections. The gap
+// only shows with more than one CPU:
+//
+//	go test -run '^$' -bench Enroll -cpu 1,4,8 ./
+
+func TestMain(m *testing.M) {
+	config.Load("course", settings...)
+	studentid.Setup()
+	os.Exit(m.Run())
+}
+
+// benchCourses is how many courses the enrollments spread over, and
+// benchRound how many are made before the catalog is reset, so that the
+// per-student credit scans stay the size they are on registration day.
+const (
+	benchCourses = 32
+	benchRound   = 2000
+)
+
+// resetBenchCatalog replaces the catalog with benchCourses empty courses,
+// each with room for every enrollment of a round. Callers must hold mu
+// exclusively.
+func resetBenchCatalog() {
+	courses = nil
+	for i := 0; i < benchCourses; i++ {
+		c := testfixtures.NewCourse().WithID(fmt.Sprintf("BENCH%02d", i)).InTerm(currentTerm()).WithSlots(benchRound).Build()
+		courses = append(courses, &Course{Course: c})
+	}
+	enrollments = make(map[string]bool)
+	enrollmentPools = make(map[string]string)
+	historyMu.Lock()
+	history = nil
+	outbox = memoryOutbox{&events.MemoryOutbox{}} // No relay drains it here
+	historyMu.Unlock()
+}
+
+// benchmarkEnroll posts b.N enrollments of distinct students, one course
+// each, to handler from b.RunParallel's clients.
+func benchmarkEnroll(b *testing.B, handler http.Handler) {
+	mu.Lock()
+	resetBenchCatalog()
+	mu.Unlock()
+	var next atomic.Int64
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := next.Add(1)
+			if i%benchRound == 0 {
+				mu.Lock()
+				resetBenchCatalog()
+				mu.Unlock()
+			}
+			body := fmt.Sprintf(`{"student_id": "student%d", "course_id": "BENCH%02d"}`, i, i%benchCourses)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", strings.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				b.Errorf("enroll student%d: %d %s", i, rec.Code, rec.Body.String())
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkEnrollGlobalLock(b *testing.B) {
+	var global sync.Mutex
+	benchmarkEnroll(b, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		global.Lock()
+		defer global.Unlock()
+		enroll(w, r)
+	}))
+}
+
+func BenchmarkEnrollStripedLocks(b *testing.B) {
+	benchmarkEnroll(b, http.HandlerFunc(enroll))
+}
@@ -1,67 +1,164 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
-	"sync"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/config"
+	"shared/events"
+	"shared/health"
+	"shared/idempotency"
+	"shared/logging"
+	"shared/metrics"
+	"shared/models"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+	"shared/tracing"
 )
 
 // --- Domain Models ---
+// Course is the shared wire model plus what only the Course Service keeps.
 type Course struct {
-	ID         string `json:"id"`
-	Title      string `json:"title"`
-	Credits    int    `json:"credits"`
-	OpenSlots  int    `json:"open_slots"`
-	IsEnrolled bool   `json:"is_enrolled"`
+	models.Course
+
+	Rules     *EnrollmentRules `json:"rules,omitempty"`
+	SeatPools []*SeatPool      `json:"seat_pools,omitempty"`
 }
 
 type EnrollRequest struct {
-	CourseID  string `json:"course_id"`
-	StudentID string `json:"student_id"`
+	CourseID         string `json:"course_id"`
+	StudentID        string `json:"student_id"`
+	PermissionNumber string `json:"permission_number,omitempty"` // Lets the student into a full or restricted section
+}
+
+type SwapRequest struct {
+	StudentID    string `json:"student_id"`
+	FromCourseID string `json:"from_course_id"`
+	ToCourseID   string `json:"to_course_id"`
 }
 
 // --- In-Memory Database ---
 var (
-	mu          sync.Mutex
+	mu          catalogLock             // See locking.go
 	enrollments = make(map[string]bool) // Key: "CourseID:StudentID"
 
 	// Define courses as pointers so we can modify them easily in the loop
+	courses []*Course
+)
+
+// seedCourses loads the built-in catalog, offered in seedTerm.
+func seedCourses() {
 	courses = []*Course{
-		{ID: "CCPROG2", Title: "Programming with Structured Data Types", Credits: 3, OpenSlots: 20},
-		{ID: "STDISCM", Title: "Distributed Computing", Credits: 4, OpenSlots: 15},
-		{ID: "CSMATH1", Title: "Differential Calculus for Computer Science Students", Credits: 3, OpenSlots: 30},
+		{Course: models.Course{ID: "CCPROG2", Code: "CCPROG2", Term: seedTerm, Title: "Programming with Structured Data Types", Credits: 3, OpenSlots: 20, Capacity: 20, Instructor: "faculty1", DepartmentID: "CS",
+			Meetings: []Meeting{{Day: "Mon", Start: "09:15", End: "10:45", Room: "G304"}, {Day: "Wed", Start: "09:15", End: "10:45", Room: "G304"}}}},
+		{Course: models.Course{ID: "STDISCM", Code: "STDISCM", Term: seedTerm, Title: "Distributed Computing", Credits: 4, OpenSlots: 15, Capacity: 15, Instructor: "faculty1", DepartmentID: "CS", CoRequisites: []string{"STDISCL"},
+			Meetings: []Meeting{{Day: "Tue", Start: "11:00", End: "12:30", Room: "G205"}, {Day: "Thu", Start: "11:00", End: "12:30", Room: "G205"}}},
+			SeatPools: []*SeatPool{{Name: "BSCS majors", Programs: []string{"BSCS"}, Seats: 5}}},
+		{Course: models.Course{ID: "STDISCL", Code: "STDISCL", Term: seedTerm, Title: "Distributed Computing Laboratory", Credits: 1, OpenSlots: 15, Capacity: 15, Instructor: "faculty1", DepartmentID: "CS", CoRequisites: []string{"STDISCM"},
+			Meetings: []Meeting{{Day: "Fri", Start: "13:00", End: "16:00", Room: "Lab 2"}}}},
+		{Course: models.Course{ID: "CSMATH1", Code: "CSMATH1", Term: seedTerm, Title: "Differential Calculus for Computer Science Students", Credits: 3, OpenSlots: 30, Capacity: 30, DepartmentID: "MATH",
+			Meetings: []Meeting{{Day: "Mon", Start: "14:30", End: "16:00", Room: "Y402"}, {Day: "Wed", Start: "14:30", End: "16:00", Room: "Y402"}}}},
 	}
-)
+}
 
 // --- Handlers ---
 
+// courseView snapshots a course as seen by one student. Callers must hold mu
+// (shared is enough).
+func courseView(c *Course, studentID string) Course {
+	lock := courseLock(seatOwner(c).ID)
+	lock.Lock()
+	view := *c
+	lock.Unlock()
+
+	enrollMu.Lock()
+	defer enrollMu.Unlock()
+	view.Waitlisted = len(waitlists[c.ID])
+	if studentID != "" {
+		view.IsEnrolled = enrollments[c.ID+":"+studentID]
+		view.WaitlistPosition = waitlistPosition(c.ID, studentID)
+	}
+	return view
+}
+
 func getCourses(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Check who is asking
 	studentID := r.URL.Query().Get("student_id")
+	departmentID := r.URL.Query().Get("department_id")
+	collegeID := r.URL.Query().Get("college_id")
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	term := requestedTerm(r)
+	search := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	openOnly := r.URL.Query().Get("open") == "true"
+	var credits int
+	if raw := r.URL.Query().Get("credits"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "credits must be a number", http.StatusBadRequest)
+			return
+		}
+		credits = n
+	}
+	if studentID != "" {
+		if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	// A client that just enrolled passes its version so it sees its own write
+	if !awaitVersion(w, r) {
+		return
+	}
+	w.Header().Set("X-Enrollment-Version", strconv.FormatInt(syncedVersion.Load(), 10))
 
-	mu.Lock()
-	defer mu.Unlock()
+	mu.RLock()
+	defer mu.RUnlock()
 
 	// Dynamic Response: Calculate 'IsEnrolled' for this specific student
 	// We create a temporary list so we don't mess up the global state for other users
 	var responseList []Course
 	for _, c := range courses {
-		tempCourse := *c // Copy value
-		if studentID != "" {
-			// Check if this student is in the map
-			if enrollments[c.ID+":"+studentID] {
-				tempCourse.IsEnrolled = true
+		if c.Term != term || (isArchived(c) && !includeArchived) {
+			continue
+		}
+		// Optional catalog browsing by department or college
+		if departmentID != "" && c.DepartmentID != departmentID {
+			continue
+		}
+		if collegeID != "" {
+			if d := findDepartment(c.DepartmentID); d == nil || d.CollegeID != collegeID {
+				continue
 			}
 		}
-		responseList = append(responseList, tempCourse)
+		// Search matches the code or title, ignoring case
+		if search != "" && !strings.Contains(strings.ToLower(c.ID+" "+c.Title), search) {
+			continue
+		}
+		if credits != 0 && c.Credits != credits {
+			continue
+		}
+		listed := courseView(c, studentID)
+		if openOnly && listed.OpenSlots <= 0 {
+			continue
+		}
+		responseList = append(responseList, listed)
 	}
 
-	json.NewEncoder(w).Encode(responseList)
+	// Render first so the ETag reflects exactly what this caller would receive
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(responseList)
+	view := r.URL.Query()
+	view.Del("min_version")
+	writeConditional(w, r, versionFor(view.Encode(), body.Bytes()), &body)
 }
 
 func enroll(w http.ResponseWriter, r *http.Request) {
@@ -71,49 +168,404 @@ func enroll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req EnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		failEnrollment(w, http.StatusBadRequest, EnrollmentFailure{Code: models.CodeInvalidRequest, Message: err.Error()})
+		return
+	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		failEnrollment(w, http.StatusBadRequest, EnrollmentFailure{Code: models.CodeInvalidRequest, Message: "Invalid student ID: " + err.Error()})
+		return
+	}
+	profile, ok := enrollingProfile(w, r, req.StudentID)
+	if !ok {
+		return
+	}
+	if f := registrationClosed(time.Now()); f != nil {
+		failEnrollment(w, http.StatusForbidden, *f)
+		return
+	}
+	if needsSaga(req.CourseID) {
+		enrollBySaga(w, r, req, profile)
+		return
+	}
+	if req.PermissionNumber != "" {
+		enrollWithPermission(w, r, req, profile)
+		return
+	}
+
+	unlock, lockErr := lockSeats(r.Context(), req.StudentID, req.CourseID)
+	if lockErr != nil {
+		failEnrollment(w, lockErr.Status, lockErr.failure(req.CourseID))
+		return
+	}
+	defer unlock()
+
+	// Shared lock: enrollments in other courses proceed in parallel
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if h := activeRegistrationHold(req.StudentID); h != nil {
+		failEnrollment(w, http.StatusForbidden, EnrollmentFailure{Code: models.CodeHoldPresent, Message: "Registration blocked by " + h.Type + " hold"})
+		return
+	}
+
+	// 1. Find Course
+	c := findCourse(req.CourseID)
+	if c == nil {
+		failEnrollment(w, http.StatusNotFound, EnrollmentFailure{Code: models.CodeNotFound, Message: "Course not found", CourseID: req.CourseID})
+		return
+	}
+	studentLock(req.StudentID).Lock()
+	defer studentLock(req.StudentID).Unlock()
+	// Cross-listed codes share their primary's seats, so they share its lock too
+	seatLock := courseLock(seatOwner(c).ID)
+	seatLock.Lock()
+	defer seatLock.Unlock()
+	if missing := missingCoRequisites(c, req.StudentID, nil); len(missing) > 0 {
+		failEnrollment(w, http.StatusConflict, EnrollmentFailure{
+			Code:     models.CodePrereqMissing,
+			Message:  "Co-requisite required: enroll together with " + strings.Join(missing, ", ") + " via /enroll-batch",
+			CourseID: c.ID,
+		})
+		return
+	}
+
+	// 2. Check Duplication & Decrement
+	if err := admit(c, req.StudentID, profile); err != nil {
+		failEnrollment(w, err.Status, err.failure(c.ID))
+		return
+	}
+	recordEvent(EnrollmentEvent{Type: "enroll", StudentID: req.StudentID, CourseID: c.ID},
+		announce(DomainEvent{Type: "EnrollmentCreated", StudentID: req.StudentID, CourseID: c.ID}))
+
+	enrollmentAttempts.WithLabelValues("enrolled").Inc()
+	stampVersion(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "enrolled"}`))
+}
+
+// findCourse looks up a course by ID. Callers must hold mu.
+func findCourse(id string) *Course {
+	for _, c := range courses {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// enrollError carries the HTTP status for a rejected enrollment.
+type enrollError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *enrollError) failure(courseID string) EnrollmentFailure {
+	return EnrollmentFailure{Code: e.Code, Message: e.Message, CourseID: courseID}
+}
+
+// admit runs the per-course enrollment checks and claims a seat. Callers must
+// hold mu exclusively, or hold it shared together with the student's and the
+// course's locks.
+func admit(c *Course, studentID string, profile *StudentProfile) *enrollError {
+	if err := admissionError(c, studentID); err != nil {
+		return err
+	}
+	pool, err := takeSeat(c, profile, studentID)
+	if err != nil {
+		return seatError(err)
+	}
+	placeStudent(c, studentID, pool)
+	return nil
+}
+
+// admissionError runs the checks every enrollment must pass before a seat is
+// claimed. Callers must hold the same locks as for admit.
+func admissionError(c *Course, studentID string) *enrollError {
+	if isArchived(c) || isArchived(seatOwner(c)) {
+		return &enrollError{http.StatusGone, models.CodeArchived, "Course is archived"}
+	}
+	if enrolledInClass(c, studentID) {
+		return &enrollError{http.StatusConflict, models.CodeAlreadyEnrolled, "Student already enrolled"}
+	}
+	if holdsClass(c, studentID) {
+		return &enrollError{http.StatusConflict, models.CodeSeatHeld, "Student already holds a seat; confirm the hold instead"}
+	}
+	if exceedsCreditLimit(studentID, c.Term, c.Credits) {
+		return &enrollError{http.StatusConflict, models.CodeCreditLimit, "Credit limit exceeded"}
+	}
+	return nil
+}
+
+// placeStudent records an enrollment once its seat has been claimed. Callers
+// must hold the same locks as for admit.
+func placeStudent(c *Course, studentID, pool string) {
+	key := c.ID + ":" + studentID
+	enrollMu.Lock()
+	removeFromWaitlist(c.ID, studentID)
+	enrollments[key] = true
+	if pool != "" {
+		enrollmentPools[key] = pool
+	}
+	enrollMu.Unlock()
+	checkRoomCapacity(c)
+}
+
+// unenroll removes an enrollment and returns its seat, reporting which pool
+// the seat went back to. Callers must hold mu.
+func unenroll(c *Course, studentID string) string {
+	key := c.ID + ":" + studentID
+	pool := enrollmentPools[key]
+	delete(enrollments, key)
+	delete(enrollmentPools, key)
+	releaseSeat(c, pool, studentID)
+	return pool
+}
+
+// drop removes a student from a course together with any co-requisites they
+// hold, since linked sections must be dropped as a unit.
+func drop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req EnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := enrollingProfile(w, r, req.StudentID); !ok {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !enrollments[c.ID+":"+req.StudentID] {
+		http.Error(w, "Student not enrolled", http.StatusConflict)
+		return
+	}
+
+	dropped := []string{c.ID}
+	unenroll(c, req.StudentID)
+	recordEvent(EnrollmentEvent{Type: "drop", StudentID: req.StudentID, CourseID: c.ID},
+		announce(DomainEvent{Type: "EnrollmentDropped", StudentID: req.StudentID, CourseID: c.ID}))
+	for _, id := range c.CoRequisites {
+		linked := findCourse(id)
+		if linked == nil || !enrollments[linked.ID+":"+req.StudentID] {
+			continue
+		}
+		unenroll(linked, req.StudentID)
+		recordEvent(EnrollmentEvent{Type: "drop", StudentID: req.StudentID, CourseID: linked.ID, Detail: "co-requisite of " + c.ID},
+			announce(DomainEvent{Type: "EnrollmentDropped", StudentID: req.StudentID, CourseID: linked.ID, Data: map[string]string{"reason": "co-requisite"}}))
+		dropped = append(dropped, linked.ID)
+	}
+	promoteWaitlists(time.Now())
+
+	stampVersion(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "dropped", "dropped": dropped})
+}
+
+// swap moves a student from one course to another in a single critical section,
+// so the student never ends up holding neither seat.
+func swap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SwapRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.FromCourseID == req.ToCourseID {
+		http.Error(w, "Cannot swap a course with itself", http.StatusBadRequest)
+		return
+	}
+	profile, ok := enrollingProfile(w, r, req.StudentID)
+	if !ok {
+		return
+	}
+	if f := registrationClosed(time.Now()); f != nil {
+		http.Error(w, f.Message, http.StatusForbidden)
+		return
+	}
+	unlock, lockErr := lockSeats(r.Context(), req.StudentID, req.FromCourseID, req.ToCourseID)
+	if lockErr != nil {
+		http.Error(w, lockErr.Message, lockErr.Status)
+		return
+	}
+	defer unlock()
 
 	mu.Lock()
 	defer mu.Unlock()
 
-	// 1. Check Duplication
-	enrollKey := req.CourseID + ":" + req.StudentID
-	if enrollments[enrollKey] {
+	if h := activeRegistrationHold(req.StudentID); h != nil {
+		http.Error(w, "Registration blocked by "+h.Type+" hold", http.StatusForbidden)
+		return
+	}
+
+	from := findCourse(req.FromCourseID)
+	to := findCourse(req.ToCourseID)
+	if from == nil || to == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	if from.Term != to.Term {
+		http.Error(w, "Courses are in different terms", http.StatusBadRequest)
+		return
+	}
+
+	fromKey := from.ID + ":" + req.StudentID
+	toKey := to.ID + ":" + req.StudentID
+	if !enrollments[fromKey] {
+		http.Error(w, "Student not enrolled in source course", http.StatusConflict)
+		return
+	}
+	if enrollments[toKey] {
 		http.Error(w, "Student already enrolled", http.StatusConflict)
 		return
 	}
+	if len(from.CoRequisites) > 0 || len(to.CoRequisites) > 0 {
+		http.Error(w, "Courses with co-requisites cannot be swapped; use /drop and /enroll-batch", http.StatusConflict)
+		return
+	}
 
-	// 2. Find Course & Decrement
-	for _, c := range courses {
-		if c.ID == req.CourseID {
-			if c.OpenSlots > 0 {
-				c.OpenSlots--
-				enrollments[enrollKey] = true
-
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"status": "enrolled"}`))
-				return
-			}
-			http.Error(w, "Course full", http.StatusConflict)
+	// 1. Drop the old seat
+	fromPool := unenroll(from, req.StudentID)
+
+	// 2. Take the new seat, rolling back the drop if it is refused
+	if err := admit(to, req.StudentID, profile); err != nil {
+		if !reclaimSeat(from, fromPool, req.StudentID) {
+			slog.WarnContext(r.Context(), "swap: seat lost to another replica", "student_id", req.StudentID, "course_id", from.ID)
+			recordEvent(EnrollmentEvent{Type: "drop", StudentID: req.StudentID, CourseID: from.ID, Detail: "swap failed and seat was resold"},
+				announce(DomainEvent{Type: "EnrollmentDropped", StudentID: req.StudentID, CourseID: from.ID, Data: map[string]string{"reason": "swap"}}))
+			http.Error(w, err.Message+"; the original seat could not be restored", http.StatusConflict)
 			return
 		}
+		enrollments[fromKey] = true
+		if fromPool != "" {
+			enrollmentPools[fromKey] = fromPool
+		}
+		http.Error(w, err.Message, err.Status)
+		return
 	}
-	http.Error(w, "Course not found", http.StatusNotFound)
+	recordEvent(EnrollmentEvent{Type: "swap", StudentID: req.StudentID, CourseID: to.ID, FromCourseID: from.ID},
+		announce(DomainEvent{Type: "EnrollmentDropped", StudentID: req.StudentID, CourseID: from.ID, Data: map[string]string{"reason": "swap"}}),
+		announce(DomainEvent{Type: "EnrollmentCreated", StudentID: req.StudentID, CourseID: to.ID, Data: map[string]string{"reason": "swap"}}))
+	promoteWaitlists(time.Now())
+
+	stampVersion(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "swapped"}`))
 }
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
-	}
+	config.Load("course", settings...)
+	logging.Setup("course")
+	mtls.Setup()
+	tracing.Setup("course")
+	idempotency.Setup("course")
+	loadTerms()
+	seedCourses()
+	loadCalendar()
+	studentid.Setup()
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/courses", getCourses)
+	mux.HandleFunc("/courses", coursesHandler)
+	mux.HandleFunc("/courses/details", updateCourseDetails)
 	mux.HandleFunc("/enroll", enroll)
+	mux.HandleFunc("/enroll-batch", enrollBatch)
+	mux.HandleFunc("/drop", drop)
+	mux.HandleFunc("/drop/preview", previewDrop)
+	mux.HandleFunc("/swap", swap)
+	mux.HandleFunc("/my-courses", myCourses)
+	mux.HandleFunc("/roster", roster)
+	mux.HandleFunc("/courses/instructor", assignInstructor)
+	mux.HandleFunc("/courses/overbooking", setOverbooking)
+	mux.HandleFunc("/courses/capacity", updateCapacity)
+	mux.HandleFunc("/courses/meetings", setMeetings)
+	mux.HandleFunc("/schedule", studentSchedule)
+	mux.HandleFunc("/graphql", graphqlHandler)
+	mux.HandleFunc("/colleges", collegesHandler)
+	mux.HandleFunc("/departments", departmentsHandler)
+	mux.HandleFunc("/departments/stats", departmentStats)
+	mux.HandleFunc("/departments/chair", assignChair)
+	mux.HandleFunc("/courses/department", assignDepartment)
+	mux.HandleFunc("/courses/cross-list", crossList)
+	mux.HandleFunc("/terms", termsHandler)
+	mux.HandleFunc("/terms/current", setCurrentTerm)
+	mux.HandleFunc("/courses/archive", archiveCourse)
+	mux.HandleFunc("/courses/restore", archiveCourse)
+	mux.HandleFunc("/export/enrollments", exportEnrollments)
+	mux.HandleFunc("/courses/seats/stream", seatStream)
+	mux.HandleFunc("/courses/{id}/stats", courseStats)
+	mux.HandleFunc("/analytics/melt", meltAnalytics)
+	mux.HandleFunc("/no-shows", noShowsHandler)
+	mux.HandleFunc("/no-shows/clear", clearNoShows)
+	mux.HandleFunc("/no-shows/preview", previewNoShowPurge)
+	mux.HandleFunc("/no-shows/purge", purgeNoShows)
+	mux.HandleFunc("/waitlist", waitlistHandler)
+	mux.HandleFunc("/waitlist/leave", leaveWaitlist)
+	mux.HandleFunc("/notifications", notificationsHandler)
+	mux.HandleFunc("/notifications/read", markNotificationsRead)
+	mux.HandleFunc("/permissions", permissionsHandler)
+	mux.HandleFunc("/holds", placeHold)
+	mux.HandleFunc("/holds/confirm", confirmHold)
+	mux.HandleFunc("/holds/release", releaseHoldHandler)
+	mux.HandleFunc("/enrollment-sagas", listSagas)
+	mux.HandleFunc("/enrollment-sagas/{id}", sagaHandler)
+	mux.HandleFunc("/credit-limits", creditLimits)
+	mux.HandleFunc("/enrollment-history", enrollmentHistory)
+	mux.HandleFunc("/enrollments/as-of", enrollmentsAsOf)
+	mux.HandleFunc("/registration-holds", registrationHoldsHandler)
+	mux.HandleFunc("/registration-holds/release", releaseRegistrationHold)
+	mux.HandleFunc("/admin/rules-preview", previewRules)
+	mux.HandleFunc("/healthz", health.Liveness)
+	mux.HandleFunc("/readyz", readyz)
+	mux.Handle("/metrics", metrics.Handler())
+
+	openHistory()
+	openOutbox()
+	openSeatStore()
+	loadFixture(config.Get("FIXTURE_PATH"))
+	restoreEnrollments()
+	go runSeatSync(time.Second)
+	go expireHolds(time.Second)
+	go runWaitlistWorker(time.Second)
+	go runSeatBroadcaster(500 * time.Millisecond)
+	go resolveSagas(sagaRetryInterval)
+	events.Start("course-service", outbox)
 
-	fmt.Printf("Node 3 (Course Service) running on port %s...\n", port)
-	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, mux))
+	server.OnShutdown("events", events.Flush)
+	server.OnShutdown("event outbox", closeOutbox)
+	server.OnShutdown("seat store", closeSeatStore)
+	server.OnShutdown("history", closeHistory)
+	server.OnShutdown("idempotency keys", idempotency.Close)
+	server.OnShutdown("tracing", tracing.Shutdown)
+
+	port := config.Get("PORT")
+	slog.Info("Node 3 (Course Service) running", "port", port)
+	srv := &http.Server{Addr: "0.0.0.0:" + port, Handler: tracing.Middleware(logging.Middleware(metrics.Middleware(mtls.Middleware(idempotency.Middleware(mux)))))}
+	if err := mtls.Run(srv); err != nil {
+		log.Fatal(err)
+	}
 }
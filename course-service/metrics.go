@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// --- Prometheus Metrics ---
+
+// Enrollment attempts at /enroll and /enroll-batch are counted by outcome:
+// "enrolled" or the refusal's error code. Seat counts are read from the
+// catalog at each scrape, one series per active course.
+
+var enrollmentAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "enrollment_attempts_total",
+	Help: "Enrollment requests, by result: enrolled or the refusal's error code.",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(seatCollector{})
+}
+
+var (
+	openSeatsDesc = prometheus.NewDesc("course_open_seats", "Seats still open in a course.", []string{"course_id"}, nil)
+	capacityDesc  = prometheus.NewDesc("course_capacity", "Room capacity of a course.", []string{"course_id"}, nil)
+	waitlistDesc  = prometheus.NewDesc("course_waitlisted", "Students on a course's waitlist.", []string{"course_id"}, nil)
+)
+
+type seatCollector struct{}
+
+func (seatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openSeatsDesc
+	ch <- capacityDesc
+	ch <- waitlistDesc
+}
+
+func (seatCollector) Collect(ch chan<- prometheus.Metric) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range courses {
+		if isArchived(c) {
+			continue
+		}
+		view := courseView(c, "")
+		ch <- prometheus.MustNewConstMetric(openSeatsDesc, prometheus.GaugeValue, float64(view.OpenSlots), c.ID)
+		ch <- prometheus.MustNewConstMetric(capacityDesc, prometheus.GaugeValue, float64(view.Capacity), c.ID)
+		ch <- prometheus.MustNewConstMetric(waitlistDesc, prometheus.GaugeValue, float64(view.Waitlisted), c.ID)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- No-show Purge ---
+
+type NoShowMark struct {
+	CourseID  string    `json:"course_id"`
+	StudentID string    `json:"student_id"`
+	MarkedBy  string    `json:"marked_by"`
+	MarkedAt  time.Time `json:"marked_at"`
+}
+
+type NoShowRequest struct {
+	CourseID   string   `json:"course_id"`
+	StudentIDs []string `json:"student_ids"`
+}
+
+type PurgePreview struct {
+	CourseID       string   `json:"course_id"`
+	Students       []string `json:"students"`
+	AlsoDropped    []string `json:"also_dropped,omitempty"` // Co-requisite sections dropped alongside, as "CourseID:StudentID"
+	SeatsFreed     int      `json:"seats_freed"`
+	WaitlistOffers []string `json:"waitlist_offers,omitempty"`
+	Confirmation   string   `json:"confirmation"`
+}
+
+type PurgeRequest struct {
+	CourseID     string `json:"course_id"`
+	Confirmation string `json:"confirmation"`
+}
+
+var noShows = make(map[string]*NoShowMark) // Key: "CourseID:StudentID", guarded by mu
+
+// authorizeCourseStaff resolves the course and checks the caller teaches it
+// or is the registrar. Callers must hold mu.
+func authorizeCourseStaff(w http.ResponseWriter, user *AuthResponse, courseID string) *Course {
+	c := findCourse(courseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return nil
+	}
+	if !canManageCourse(user, c) {
+		http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+		return nil
+	}
+	return c
+}
+
+// buildPurgePreview lists what a purge of a course would do right now. The
+// confirmation fingerprints the student list so a purge only goes ahead if
+// nothing changed since the preview. Callers must hold mu.
+func buildPurgePreview(c *Course) PurgePreview {
+	p := PurgePreview{CourseID: c.ID, Students: []string{}}
+	for key, mark := range noShows {
+		if mark.CourseID == c.ID && enrollments[key] {
+			p.Students = append(p.Students, mark.StudentID)
+		}
+	}
+	sort.Strings(p.Students)
+
+	for _, sid := range p.Students {
+		for _, id := range c.CoRequisites {
+			if enrollments[id+":"+sid] {
+				p.AlsoDropped = append(p.AlsoDropped, id+":"+sid)
+			}
+		}
+	}
+	p.SeatsFreed = len(p.Students)
+	for i, e := range waitlists[c.ID] {
+		if i >= p.SeatsFreed {
+			break
+		}
+		p.WaitlistOffers = append(p.WaitlistOffers, e.StudentID)
+	}
+
+	sum := sha256.Sum256([]byte(c.ID + "|" + strings.Join(p.Students, ",")))
+	p.Confirmation = hex.EncodeToString(sum[:8])
+	return p
+}
+
+// noShowsHandler marks (POST) or lists (GET) no-show students for a course.
+func noShowsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mu.Lock()
+		defer mu.Unlock()
+		c := authorizeCourseStaff(w, user, r.URL.Query().Get("course_id"))
+		if c == nil {
+			return
+		}
+		marks := []NoShowMark{}
+		for _, mark := range noShows {
+			if mark.CourseID == c.ID {
+				marks = append(marks, *mark)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(marks)
+
+	case http.MethodPost:
+		var req NoShowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		c := authorizeCourseStaff(w, user, req.CourseID)
+		if c == nil {
+			return
+		}
+		for _, sid := range req.StudentIDs {
+			if !enrollments[c.ID+":"+sid] {
+				http.Error(w, "Student not enrolled: "+sid, http.StatusConflict)
+				return
+			}
+		}
+		for _, sid := range req.StudentIDs {
+			noShows[c.ID+":"+sid] = &NoShowMark{CourseID: c.ID, StudentID: sid, MarkedBy: user.Username, MarkedAt: time.Now()}
+			recordEvent(EnrollmentEvent{Type: "no-show", StudentID: sid, CourseID: c.ID, Actor: user.Username})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildPurgePreview(c))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// clearNoShows removes marks, e.g. when a student turns up late.
+func clearNoShows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+	var req NoShowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	c := authorizeCourseStaff(w, user, req.CourseID)
+	if c == nil {
+		return
+	}
+	for _, sid := range req.StudentIDs {
+		delete(noShows, c.ID+":"+sid)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "cleared"}`))
+}
+
+func previewNoShowPurge(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	c := authorizeCourseStaff(w, user, r.URL.Query().Get("course_id"))
+	if c == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPurgePreview(c))
+}
+
+// purgeNoShows bulk-drops every marked student, provided the caller echoes
+// the confirmation from a preview of the same list.
+func purgeNoShows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+	var req PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	c := authorizeCourseStaff(w, user, req.CourseID)
+	if c == nil {
+		return
+	}
+	preview := buildPurgePreview(c)
+	if req.Confirmation != preview.Confirmation {
+		http.Error(w, "Confirmation does not match the current no-show list; preview again", http.StatusPreconditionFailed)
+		return
+	}
+
+	for _, sid := range preview.Students {
+		unenroll(c, sid)
+		delete(noShows, c.ID+":"+sid)
+		recordEvent(EnrollmentEvent{Type: "drop", StudentID: sid, CourseID: c.ID, Actor: user.Username, Detail: "no-show purge"},
+			announce(DomainEvent{Type: "EnrollmentDropped", StudentID: sid, CourseID: c.ID, Data: map[string]string{"reason": "no-show"}}))
+
+		for _, id := range c.CoRequisites {
+			linked := findCourse(id)
+			if linked == nil || !enrollments[linked.ID+":"+sid] {
+				continue
+			}
+			unenroll(linked, sid)
+			recordEvent(EnrollmentEvent{Type: "drop", StudentID: sid, CourseID: linked.ID, Actor: user.Username, Detail: "co-requisite of no-show purge in " + c.ID},
+				announce(DomainEvent{Type: "EnrollmentDropped", StudentID: sid, CourseID: linked.ID, Data: map[string]string{"reason": "no-show co-requisite"}}))
+		}
+	}
+	promoteWaitlists(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "purged", "dropped": preview.Students})
+}
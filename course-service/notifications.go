@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// --- Student Notifications ---
+
+// Besides the WaitlistPromoted event, a student offered a seat from the
+// waitlist gets a note in their portal inbox with the hold to accept.
+// Deadline reminders are added the first time a student opens their inbox
+// within deadlineReminder of registration closing or of the refund deadline.
+// Notes stay unread until marked. Inboxes are kept in memory.
+
+const (
+	maxInbox         = 50 // Older notes are dropped
+	deadlineReminder = 72 * time.Hour
+)
+
+type Notification struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"` // "waitlist_offer" or "deadline"
+	CourseID string    `json:"course_id,omitempty"`
+	HoldID   string    `json:"hold_id,omitempty"` // Waitlist offers: confirm it at /holds/confirm
+	Message  string    `json:"message"`
+	At       time.Time `json:"at"`
+	Read     bool      `json:"read"`
+}
+
+var (
+	noticeMu  sync.Mutex
+	inboxes   = make(map[string][]Notification) // Key: student; newest first
+	reminded  = make(map[string]bool)           // Key: "student:deadline"
+	noticeSeq int64
+)
+
+// notify puts a note at the top of a student's inbox.
+func notify(studentID string, note Notification) {
+	noticeMu.Lock()
+	defer noticeMu.Unlock()
+	noticeSeq++
+	note.ID = strconv.FormatInt(noticeSeq, 10)
+	note.At = time.Now().UTC()
+	inbox := append([]Notification{note}, inboxes[studentID]...)
+	if len(inbox) > maxInbox {
+		inbox = inbox[:maxInbox]
+	}
+	inboxes[studentID] = inbox
+}
+
+// remindDeadlines adds the reminders now due for a student. Callers must
+// hold mu (shared is enough).
+func remindDeadlines(studentID string, now time.Time) {
+	enrolled := false
+	enrollMu.Lock()
+	for _, c := range courses {
+		enrolled = enrolled || enrollments[c.ID+":"+studentID]
+	}
+	enrollMu.Unlock()
+	deadlines := []struct {
+		key     string
+		at      time.Time
+		applies bool
+		message string
+	}{
+		{"registration", registrationCloses, true, "Registration closes " + registrationCloses.Format("Jan 2, 15:04 MST") + "."},
+		{"refund", dropRefundUntil, enrolled, "Drops are refunded in full until " + dropRefundUntil.Format("Jan 2, 15:04 MST") + "."},
+	}
+	for _, d := range deadlines {
+		if d.at.IsZero() || !d.applies || now.After(d.at) || d.at.Sub(now) > deadlineReminder {
+			continue
+		}
+		noticeMu.Lock()
+		due := !reminded[studentID+":"+d.key]
+		reminded[studentID+":"+d.key] = true
+		noticeMu.Unlock()
+		if due {
+			notify(studentID, Notification{Type: "deadline", Message: d.message})
+		}
+	}
+}
+
+// notificationsHandler returns the caller's inbox. Students only.
+func notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student")
+	if !ok {
+		return
+	}
+	mu.RLock()
+	remindDeadlines(user.Username, time.Now())
+	mu.RUnlock()
+
+	noticeMu.Lock()
+	inbox := append([]Notification{}, inboxes[user.Username]...)
+	noticeMu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inbox)
+}
+
+type MarkReadRequest struct {
+	IDs []string `json:"ids"` // Empty marks the whole inbox
+}
+
+// markNotificationsRead marks notes in the caller's inbox as read (POST
+// /notifications/read). Students only.
+func markNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student")
+	if !ok {
+		return
+	}
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids := make(map[string]bool)
+	for _, id := range req.IDs {
+		ids[id] = true
+	}
+	noticeMu.Lock()
+	for i, note := range inboxes[user.Username] {
+		if len(ids) == 0 || ids[note.ID] {
+			inboxes[user.Username][i].Read = true
+		}
+	}
+	noticeMu.Unlock()
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "notifications read"}`))
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"shared/config"
+	"shared/events"
+)
+
+// --- Event Outbox ---
+
+// emit writes events to the outbox inside the critical section that made
+// the change, before the caller answers, and the relay in shared/events
+// publishes them from there. With EVENT_OUTBOX_PATH set the outbox is a
+// file, one JSON entry per line, so events written before a crash are
+// published after the restart. How far the relay has got is kept in
+// <path>.sent; once it has caught up, the file is emptied. Without the
+// setting the outbox is in memory.
+//
+// Events announcing a change to the enrollment history are written with it,
+// in its record, and only then added here, tagged with the record. The
+// outbox remembers the last record it has the events of, so after a crash
+// between the two writes openOutbox adds the events of later records, and
+// the change is never kept without them.
+
+type outboxStore interface {
+	events.Outbox
+	// Add writes msgs, the events of history record logged or of none (0).
+	Add(logged int64, msgs ...events.Message)
+}
+
+var outbox outboxStore = memoryOutbox{&events.MemoryOutbox{}}
+
+// memoryOutbox is the outbox without EVENT_OUTBOX_PATH. Nothing survives a
+// restart, so there is nothing to take back from the history.
+type memoryOutbox struct {
+	*events.MemoryOutbox
+}
+
+func (o memoryOutbox) Add(logged int64, msgs ...events.Message) {
+	o.MemoryOutbox.Add(msgs...)
+}
+
+// fileOutbox keeps the entries not yet sent in memory as well as in the file.
+type fileOutbox struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	seq     int64
+	logged  int64 // The last history record whose events were added
+	pending []events.Entry
+}
+
+// outboxLine is one line of the outbox file.
+type outboxLine struct {
+	events.Entry
+	Logged int64 `json:"logged,omitempty"` // History record the event announces a change of
+}
+
+// openOutbox switches to the outbox file at EVENT_OUTBOX_PATH, if set, and
+// picks up the entries the relay had not sent, and the events of history
+// records that never reached the outbox. It must run after openHistory and
+// before anything emits.
+func openOutbox() {
+	path := config.Get("EVENT_OUTBOX_PATH")
+	if path == "" {
+		return
+	}
+	o := &fileOutbox{path: path}
+	_, err := os.Stat(path)
+	fresh := os.IsNotExist(err)
+	if b, err := os.ReadFile(path + ".sent"); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) > 0 {
+			o.seq, _ = strconv.ParseInt(fields[0], 10, 64)
+		}
+		if len(fields) > 1 {
+			o.logged, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	sent := o.seq
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var e outboxLine
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				log.Fatalf("corrupt event outbox %s: %v", path, err)
+			}
+			o.seq = max(o.seq, e.Seq)
+			o.logged = max(o.logged, e.Logged)
+			if e.Seq > sent {
+				o.pending = append(o.pending, e.Entry)
+			}
+		}
+		f.Close()
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Fatalf("cannot open event outbox %s: %v", path, err)
+	}
+	o.file = f
+
+	historyMu.Lock()
+	recovered := 0
+	for _, ev := range history {
+		switch {
+		case fresh:
+			// A new outbox: events of earlier records were the old one's to send
+			o.logged = max(o.logged, ev.Seq)
+		case ev.Seq > o.logged && len(ev.Announced) > 0:
+			o.Add(ev.Seq, ev.Announced...)
+			recovered += len(ev.Announced)
+		}
+	}
+	historyMu.Unlock()
+
+	outbox = o
+	slog.Info("event outbox", "path", path, "unsent", len(o.pending), "recovered", recovered)
+}
+
+func (o *fileOutbox) Add(logged int64, msgs ...events.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, m := range msgs {
+		o.seq++
+		e := events.Entry{Seq: o.seq, Message: m}
+		o.pending = append(o.pending, e)
+		line, _ := json.Marshal(outboxLine{Entry: e, Logged: logged})
+		if _, err := o.file.Write(append(line, '\n')); err != nil {
+			slog.Error("failed to persist event", "event_id", m.Event.ID, "err", err)
+		}
+	}
+	o.logged = max(o.logged, logged)
+}
+
+func (o *fileOutbox) Pending(limit int) ([]events.Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := min(limit, len(o.pending))
+	return append([]events.Entry(nil), o.pending[:n]...), nil
+}
+
+// Sent records the relay's progress, and the last history record whose
+// events the outbox has, then drops the sent entries, emptying the file if
+// nothing is left.
+func (o *fileOutbox) Sent(seq int64) error {
+	o.mu.Lock()
+	logged := o.logged
+	o.mu.Unlock()
+	tmp := o.path + ".sent.tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(seq, 10)+" "+strconv.FormatInt(logged, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, o.path+".sent"); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := 0
+	for i < len(o.pending) && o.pending[i].Seq <= seq {
+		i++
+	}
+	o.pending = o.pending[i:]
+	if len(o.pending) == 0 {
+		return o.file.Truncate(0)
+	}
+	return nil
+}
+
+// closeOutbox syncs the outbox file and closes it at shutdown, after the
+// relay's last pass.
+func closeOutbox(ctx context.Context) error {
+	o, ok := outbox.(*fileOutbox)
+	if !ok {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.file.Sync(); err != nil {
+		o.file.Close()
+		return err
+	}
+	return o.file.Close()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- Overbooking ---
+
+// A course sells floor(Capacity * OverbookFactor) seats, where Capacity is
+// the physical room size, plus any seats added by permission numbers.
+// OpenSlots counts down from that figure.
+
+const maxOverbookFactor = 1.5
+
+type OverbookRequest struct {
+	CourseID string  `json:"course_id"`
+	Factor   float64 `json:"factor"`
+}
+
+type MeltStats struct {
+	CourseID          string  `json:"course_id"`
+	Capacity          int     `json:"capacity"`
+	OverbookFactor    float64 `json:"overbook_factor"`
+	Occupied          int     `json:"occupied"`
+	Enrollments       int     `json:"enrollments"`
+	Drops             int     `json:"drops"`
+	MeltRate          float64 `json:"melt_rate"`
+	RecommendedFactor float64 `json:"recommended_factor"`
+	OverRoomCapacity  bool    `json:"over_room_capacity"`
+}
+
+// sellableSeats is how many seats a course may sell in total. Callers must hold mu.
+func sellableSeats(c *Course) int {
+	factor := c.OverbookFactor
+	if factor < 1 {
+		factor = 1
+	}
+	return int(math.Floor(float64(c.Capacity)*factor)) + c.PermitSeats
+}
+
+// occupiedSeats counts enrollments and live holds. Callers must hold mu.
+func occupiedSeats(c *Course) int {
+	return sellableSeats(c) - c.OpenSlots
+}
+
+// checkRoomCapacity raises an alert for every seat taken past a course's
+// physical room size. Callers must hold mu.
+func checkRoomCapacity(c *Course) {
+	if occupiedSeats(c) > c.Capacity {
+		slog.Warn("room over capacity", "course_id", c.ID, "students", occupiedSeats(c), "capacity", c.Capacity)
+		emit(DomainEvent{Type: "CapacityExceeded", CourseID: c.ID, Data: map[string]string{
+			"occupied": strconv.Itoa(occupiedSeats(c)),
+			"capacity": strconv.Itoa(c.Capacity),
+		}})
+	}
+}
+
+// setOverbooking lets the registrar change a course's overbooking factor.
+func setOverbooking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	var req OverbookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Factor < 1 || req.Factor > maxOverbookFactor {
+		http.Error(w, "factor must be between 1.0 and 1.5", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	if c.CrossListOf != "" {
+		http.Error(w, "Seats of a cross-listed course are managed on "+c.CrossListOf, http.StatusConflict)
+		return
+	}
+	occupied := occupiedSeats(c)
+	old := c.OverbookFactor
+	c.OverbookFactor = req.Factor
+	if sellableSeats(c) < occupied {
+		c.OverbookFactor = old
+		http.Error(w, "Factor would leave fewer seats than students already placed", http.StatusConflict)
+		return
+	}
+	// Bumped before the seat store answers, so an edit racing this one
+	// fails its If-Match
+	touch(c)
+	if err := seats.resize(c, sellableSeats(c)-occupied-c.OpenSlots); err != nil {
+		c.OverbookFactor = old
+		if err == errSeatStore {
+			http.Error(w, "Seat store unavailable", http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, "Seats were taken meanwhile; reload and retry", http.StatusConflict)
+		}
+		return
+	}
+	seatsChanged(c)
+	promoteWaitlists(time.Now())
+	slog.InfoContext(r.Context(), "overbooking set", "course_id", c.ID, "factor", req.Factor, "by", user.Username)
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "overbooking updated"}`))
+}
+
+// meltAnalytics derives historical melt (the share of enrollments that end in
+// a drop) from the audit trail and recommends a factor that would fill the
+// room after expected drops.
+func meltAnalytics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+	courseID := r.URL.Query().Get("course_id")
+
+	counts := enrollmentFlows()
+
+	mu.Lock()
+	stats := []MeltStats{}
+	for _, c := range courses {
+		if courseID != "" && c.ID != courseID {
+			continue
+		}
+		n := counts[c.ID]
+		s := MeltStats{
+			CourseID:          c.ID,
+			Capacity:          c.Capacity,
+			OverbookFactor:    math.Max(c.OverbookFactor, 1),
+			Occupied:          occupiedSeats(c),
+			Enrollments:       n.Enrollments,
+			Drops:             n.Drops,
+			RecommendedFactor: 1,
+		}
+		s.OverRoomCapacity = s.Occupied > c.Capacity
+		if n.Enrollments > 0 {
+			s.MeltRate = float64(n.Drops) / float64(n.Enrollments)
+			if s.MeltRate < 1 {
+				s.RecommendedFactor = math.Min(math.Round(100/(1-s.MeltRate))/100, maxOverbookFactor)
+			} else {
+				s.RecommendedFactor = maxOverbookFactor
+			}
+		}
+		stats = append(stats, s)
+	}
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"shared/models"
+	"shared/studentid"
+)
+
+// --- Permission Numbers ---
+
+// Instructors let specific students into full or restricted sections by
+// issuing a one-time permission number. /enroll accepts it in place of the
+// capacity and reserved-seat checks; the other checks (holds, credit limit,
+// co-requisites) still apply. Every number keeps who issued it and who used
+// it, and both steps are written to the enrollment history.
+
+type PermissionNumber struct {
+	Code      string     `json:"code"`
+	CourseID  string     `json:"course_id"`
+	StudentID string     `json:"student_id,omitempty"` // Empty means any student may use it
+	Reason    string     `json:"reason,omitempty"`
+	IssuedBy  string     `json:"issued_by"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedBy    string     `json:"used_by,omitempty"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+type PermissionRequest struct {
+	CourseID       string `json:"course_id"`
+	StudentID      string `json:"student_id"`
+	Reason         string `json:"reason"`
+	ExpiresInHours int    `json:"expires_in_hours"` // Default 72
+}
+
+// permissionNumbers is keyed by code, guarded by mu.
+var permissionNumbers = make(map[string]*PermissionNumber)
+
+func newPermissionCode() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return base32.StdEncoding.EncodeToString(b) // 8 characters, easy to read out
+}
+
+// permissionsHandler issues permission numbers (POST) and lists them with
+// their audit trail (GET ?course_id=). Instructors manage their own courses;
+// the registrar and admins manage all of them.
+func permissionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "faculty", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		courseID := r.URL.Query().Get("course_id")
+
+		mu.Lock()
+		list := []PermissionNumber{}
+		for _, p := range permissionNumbers {
+			if courseID != "" && p.CourseID != courseID {
+				continue
+			}
+			if c := findCourse(p.CourseID); c != nil && canManageCourse(user, c) {
+				list = append(list, *p)
+			}
+		}
+		mu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].IssuedAt.Before(list[j].IssuedAt) })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var req PermissionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.StudentID != "" {
+			if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+				http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.ExpiresInHours < 0 {
+			http.Error(w, "expires_in_hours cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if req.ExpiresInHours == 0 {
+			req.ExpiresInHours = 72
+		}
+
+		mu.Lock()
+		c := findCourse(req.CourseID)
+		if c == nil {
+			mu.Unlock()
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		if !canManageCourse(user, c) {
+			mu.Unlock()
+			http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+			return
+		}
+		now := time.Now().UTC()
+		p := &PermissionNumber{
+			Code:      newPermissionCode(),
+			CourseID:  c.ID,
+			StudentID: req.StudentID,
+			Reason:    req.Reason,
+			IssuedBy:  user.Username,
+			IssuedAt:  now,
+			ExpiresAt: now.Add(time.Duration(req.ExpiresInHours) * time.Hour),
+		}
+		permissionNumbers[p.Code] = p
+		mu.Unlock()
+
+		recordEvent(EnrollmentEvent{Type: "permission", StudentID: p.StudentID, CourseID: p.CourseID, Actor: user.Username, Detail: "issued permission number " + p.Code})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// redeemablePermission finds an unused, unexpired number for this course and
+// student. Callers must hold mu.
+func redeemablePermission(code, courseID, studentID string, now time.Time) (*PermissionNumber, string) {
+	p, ok := permissionNumbers[strings.ToUpper(strings.TrimSpace(code))]
+	switch {
+	case !ok || p.CourseID != courseID:
+		return nil, "Invalid permission number for this course"
+	case p.UsedAt != nil:
+		return nil, "Permission number has already been used"
+	case now.After(p.ExpiresAt):
+		return nil, "Permission number has expired"
+	case p.StudentID != "" && p.StudentID != studentID:
+		return nil, "Permission number was issued to another student"
+	}
+	return p, ""
+}
+
+// takePermittedSeat claims a seat for a permission-number holder. It tries an
+// ordinary seat first, then any reserved seat, and only then adds a seat past
+// the course's limit. Callers must hold mu exclusively.
+func takePermittedSeat(c *Course, profile *StudentProfile, studentID string) (string, error) {
+	pool, err := takeSeat(c, profile, studentID)
+	if err != errCourseFull {
+		return pool, err
+	}
+
+	owner := seatOwner(c)
+	all := make([]string, 0, len(owner.SeatPools))
+	for _, p := range owner.SeatPools {
+		all = append(all, p.Name)
+	}
+	if pool, err = seats.take(c, all, studentID); err != errCourseFull {
+		if err == nil {
+			seatsChanged(c)
+		}
+		return pool, err
+	}
+
+	owner.PermitSeats++
+	if err := seats.resize(owner, 1); err != nil {
+		owner.PermitSeats--
+		return "", err
+	}
+	pool, err = seats.take(c, all, studentID)
+	if err != nil {
+		// Another replica took the added seat; take it back out
+		owner.PermitSeats--
+		if seats.resize(owner, -1) != nil {
+			owner.PermitSeats++
+		}
+	}
+	seatsChanged(c)
+	return pool, err
+}
+
+// enrollWithPermission is the /enroll path for requests carrying a
+// permission number, in courses that need no saga. It is rare enough to take
+// mu exclusively.
+func enrollWithPermission(w http.ResponseWriter, r *http.Request, req EnrollRequest, profile *StudentProfile) {
+	unlock, lockErr := lockSeats(r.Context(), req.StudentID, req.CourseID)
+	if lockErr != nil {
+		failEnrollment(w, lockErr.Status, lockErr.failure(req.CourseID))
+		return
+	}
+	defer unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if h := activeRegistrationHold(req.StudentID); h != nil {
+		failEnrollment(w, http.StatusForbidden, EnrollmentFailure{Code: models.CodeHoldPresent, Message: "Registration blocked by " + h.Type + " hold"})
+		return
+	}
+	c := findCourse(req.CourseID)
+	if c == nil {
+		failEnrollment(w, http.StatusNotFound, EnrollmentFailure{Code: models.CodeNotFound, Message: "Course not found", CourseID: req.CourseID})
+		return
+	}
+	now := time.Now().UTC()
+	permit, msg := redeemablePermission(req.PermissionNumber, c.ID, req.StudentID, now)
+	if permit == nil {
+		failEnrollment(w, http.StatusForbidden, EnrollmentFailure{Code: models.CodePermissionInvalid, Message: msg, CourseID: c.ID})
+		return
+	}
+	if missing := missingCoRequisites(c, req.StudentID, nil); len(missing) > 0 {
+		failEnrollment(w, http.StatusConflict, EnrollmentFailure{
+			Code:     models.CodePrereqMissing,
+			Message:  "Co-requisite required: enroll together with " + strings.Join(missing, ", ") + " via /enroll-batch",
+			CourseID: c.ID,
+		})
+		return
+	}
+	if err := admissionError(c, req.StudentID); err != nil {
+		failEnrollment(w, err.Status, err.failure(c.ID))
+		return
+	}
+	pool, err := takePermittedSeat(c, profile, req.StudentID)
+	if err != nil {
+		e := seatError(err)
+		failEnrollment(w, e.Status, e.failure(c.ID))
+		return
+	}
+	placeStudent(c, req.StudentID, pool)
+
+	permit.UsedBy = req.StudentID
+	permit.UsedAt = &now
+	recordEvent(EnrollmentEvent{Type: "enroll", StudentID: req.StudentID, CourseID: c.ID, Detail: "permission number " + permit.Code + " issued by " + permit.IssuedBy},
+		announce(DomainEvent{Type: "EnrollmentCreated", StudentID: req.StudentID, CourseID: c.ID, Data: map[string]string{"permission_number": permit.Code}}))
+
+	enrollmentAttempts.WithLabelValues("enrolled").Inc()
+	stampVersion(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "enrolled"}`))
+}
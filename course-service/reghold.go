@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"shared/studentid"
+)
+
+// --- Registration Holds ---
+
+// RegistrationHold blocks a student from registering until it is released.
+// Not to be confused with SeatHold, which reserves a slot *for* a student.
+type RegistrationHold struct {
+	ID        string    `json:"hold_id"`
+	StudentID string    `json:"student_id"`
+	Type      string    `json:"type"` // "registrar", "finance" or "advising"
+	Reason    string    `json:"reason"`
+	PlacedBy  string    `json:"placed_by"`
+	PlacedAt  time.Time `json:"placed_at"`
+}
+
+// holdTypeRoles lists which roles may place or release each hold type.
+var holdTypeRoles = map[string][]string{
+	"registrar": {"registrar", "admin"},
+	"finance":   {"registrar", "admin"},
+	"advising":  {"registrar", "admin", "faculty"},
+}
+
+var registrationHolds = make(map[string][]*RegistrationHold) // Key: StudentID, guarded by mu
+
+// activeRegistrationHold returns the first hold blocking a student, if any.
+// Callers must hold mu.
+func activeRegistrationHold(studentID string) *RegistrationHold {
+	if list := registrationHolds[studentID]; len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+func canManageHold(role, holdType string) bool {
+	for _, allowed := range holdTypeRoles[holdType] {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// registrationHoldsHandler lists a student's holds, or every hold for the
+// registrar and admins (GET), or places a new one (POST). Students may list
+// their own holds.
+func registrationHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		user, ok := requireRole(w, r, "registrar", "admin", "faculty", "student")
+		if !ok {
+			return
+		}
+		studentID := r.URL.Query().Get("student_id")
+		if user.Role == "student" && studentID != user.Username {
+			http.Error(w, "Forbidden: Students can only view their own holds", http.StatusForbidden)
+			return
+		}
+		if studentID == "" && user.Role == "faculty" {
+			http.Error(w, "Forbidden: Faculty must name a student", http.StatusForbidden)
+			return
+		}
+		if studentID != "" {
+			if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+				http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		mu.Lock()
+		list := append([]*RegistrationHold{}, registrationHolds[studentID]...)
+		if studentID == "" {
+			for _, holds := range registrationHolds {
+				list = append(list, holds...)
+			}
+		}
+		mu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].PlacedAt.Before(list[j].PlacedAt) })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		user, ok := requireRole(w, r, "registrar", "admin", "faculty")
+		if !ok {
+			return
+		}
+		var hold RegistrationHold
+		if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := studentid.Validate(studentid.Tenant(r), hold.StudentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, known := holdTypeRoles[hold.Type]; !known {
+			http.Error(w, "Unknown hold type", http.StatusBadRequest)
+			return
+		}
+		if !canManageHold(user.Role, hold.Type) {
+			http.Error(w, "Forbidden: Cannot place "+hold.Type+" holds", http.StatusForbidden)
+			return
+		}
+
+		hold.ID = newHoldID()
+		hold.PlacedBy = user.Username
+		hold.PlacedAt = time.Now()
+
+		mu.Lock()
+		registrationHolds[hold.StudentID] = append(registrationHolds[hold.StudentID], &hold)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hold)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func releaseRegistrationHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "registrar", "admin", "faculty")
+	if !ok {
+		return
+	}
+
+	var req HoldActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for studentID, list := range registrationHolds {
+		for i, h := range list {
+			if h.ID != req.HoldID {
+				continue
+			}
+			if !canManageHold(user.Role, h.Type) {
+				http.Error(w, "Forbidden: Cannot release "+h.Type+" holds", http.StatusForbidden)
+				return
+			}
+			registrationHolds[studentID] = append(list[:i], list[i+1:]...)
+			if len(registrationHolds[studentID]) == 0 {
+				delete(registrationHolds, studentID)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "released"}`))
+			return
+		}
+	}
+	http.Error(w, "Hold not found", http.StatusNotFound)
+}
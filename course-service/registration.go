@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"shared/config"
+	"shared/models"
+)
+
+// --- Registration Window & Error Codes ---
+
+// /enroll and /enroll-batch refuse with a models.EnrollmentFailure: a
+// stable code (models.Code*) next to the human-readable message, so clients
+// can branch on the reason instead of parsing text.
+
+type EnrollmentFailure = models.EnrollmentFailure
+
+// failEnrollment writes a refused enrollment and counts it.
+func failEnrollment(w http.ResponseWriter, status int, f EnrollmentFailure) {
+	enrollmentAttempts.WithLabelValues(f.Code).Inc()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(f)
+}
+
+// The registration window comes from REGISTRATION_OPENS_AT and
+// REGISTRATION_CLOSES_AT. Either may be left unset for an open end.
+var registrationOpens, registrationCloses time.Time
+
+// loadCalendar applies the registration window, the refund deadline and
+// the days of classes. It must run before the server starts.
+func loadCalendar() {
+	registrationOpens = config.Time("REGISTRATION_OPENS_AT")
+	registrationCloses = config.Time("REGISTRATION_CLOSES_AT")
+	dropRefundUntil = config.Time("DROP_REFUND_UNTIL")
+	classesStart = config.Time("CLASSES_START")
+	classesEnd = config.Time("CLASSES_END")
+}
+
+// registrationClosed reports why enrollment is not possible right now, if it isn't.
+func registrationClosed(now time.Time) *EnrollmentFailure {
+	if !registrationOpens.IsZero() && now.Before(registrationOpens) {
+		return &EnrollmentFailure{Code: models.CodeRegistrationEarly, Message: "Registration opens " + registrationOpens.Format(time.RFC3339)}
+	}
+	if !registrationCloses.IsZero() && now.After(registrationCloses) {
+		return &EnrollmentFailure{Code: models.CodeDeadlinePassed, Message: "Registration closed " + registrationCloses.Format(time.RFC3339)}
+	}
+	return nil
+}
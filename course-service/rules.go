@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// --- Enrollment Rules ---
+
+// EnrollmentRules describes who may take a course. An empty field means
+// "no restriction" for that dimension.
+type EnrollmentRules struct {
+	Prerequisites   []string `json:"prerequisites,omitempty"`    // Course IDs that must be completed
+	AllowedPrograms []string `json:"allowed_programs,omitempty"` // e.g. "BSCS"
+	MinYearLevel    int      `json:"min_year_level,omitempty"`
+}
+
+type StudentProfile struct {
+	StudentID string   `json:"student_id"`
+	Program   string   `json:"program"`
+	YearLevel int      `json:"year_level"`
+	Completed []string `json:"completed"`
+}
+
+type RulesPreviewRequest struct {
+	CourseID  string           `json:"course_id"`
+	Rules     *EnrollmentRules `json:"rules"`
+	SeatPools []*SeatPool      `json:"seat_pools"`
+	Capacity  int              `json:"capacity"`
+	Students  []StudentProfile `json:"students"`
+}
+
+type EligibilityResult struct {
+	StudentID string   `json:"student_id"`
+	Eligible  bool     `json:"eligible"`
+	SeatPool  string   `json:"seat_pool,omitempty"`
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+type RulesPreviewResponse struct {
+	CourseID      string              `json:"course_id"`
+	Capacity      int                 `json:"capacity"`
+	OpenSeats     int                 `json:"open_seats"`
+	EligibleCount int                 `json:"eligible_count"`
+	Results       []EligibilityResult `json:"results"`
+	Warnings      []string            `json:"warnings,omitempty"`
+}
+
+// sampleStudents is used when a preview request does not supply its own population.
+var sampleStudents = []StudentProfile{
+	{StudentID: "sample-cs-1", Program: "BSCS", YearLevel: 1},
+	{StudentID: "sample-cs-2", Program: "BSCS", YearLevel: 2, Completed: []string{"CCPROG2"}},
+	{StudentID: "sample-cs-3", Program: "BSCS", YearLevel: 3, Completed: []string{"CCPROG2", "CSMATH1"}},
+	{StudentID: "sample-it-2", Program: "BSIT", YearLevel: 2, Completed: []string{"CCPROG2"}},
+	{StudentID: "sample-ma-4", Program: "BSMATH", YearLevel: 4, Completed: []string{"CSMATH1"}},
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEligibility returns the reasons a student fails the rules, or nil if eligible.
+func checkEligibility(rules *EnrollmentRules, s StudentProfile) []string {
+	if rules == nil {
+		return nil
+	}
+	var reasons []string
+	for _, prereq := range rules.Prerequisites {
+		if !contains(s.Completed, prereq) {
+			reasons = append(reasons, "missing prerequisite "+prereq)
+		}
+	}
+	if len(rules.AllowedPrograms) > 0 && !contains(rules.AllowedPrograms, s.Program) {
+		reasons = append(reasons, "program "+s.Program+" not allowed")
+	}
+	if rules.MinYearLevel > 0 && s.YearLevel < rules.MinYearLevel {
+		reasons = append(reasons, "year level below minimum")
+	}
+	return reasons
+}
+
+// previewRules simulates a course configuration against a student population
+// without touching live enrollment state.
+func previewRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "admin"); !ok {
+		return
+	}
+
+	var req RulesPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Fall back to the live course's configuration for anything not supplied
+	if req.CourseID != "" {
+		mu.Lock()
+		c := findCourse(req.CourseID)
+		if c != nil {
+			if req.Rules == nil {
+				req.Rules = c.Rules
+			}
+			if req.SeatPools == nil {
+				req.SeatPools = c.SeatPools
+			}
+			if req.Capacity == 0 {
+				req.Capacity = c.OpenSlots
+			}
+		}
+		mu.Unlock()
+		if c == nil {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+	}
+	if len(req.Students) == 0 {
+		req.Students = sampleStudents
+	}
+
+	resp := RulesPreviewResponse{CourseID: req.CourseID, Capacity: req.Capacity}
+
+	// 1. Sanity-check the configuration itself
+	reserved := 0
+	for _, p := range req.SeatPools {
+		reserved += p.Seats
+		if req.Rules == nil || len(req.Rules.AllowedPrograms) == 0 {
+			continue
+		}
+		usable := len(p.Programs) == 0
+		for _, program := range p.Programs {
+			if contains(req.Rules.AllowedPrograms, program) {
+				usable = true
+			}
+		}
+		if !usable {
+			resp.Warnings = append(resp.Warnings, "seat pool "+p.Name+" only admits programs that are not allowed")
+		}
+	}
+	if req.Rules != nil {
+		for _, prereq := range req.Rules.Prerequisites {
+			mu.Lock()
+			known := findCourse(prereq) != nil
+			mu.Unlock()
+			if !known {
+				resp.Warnings = append(resp.Warnings, "prerequisite "+prereq+" is not in the catalog")
+			}
+		}
+	}
+	if reserved > req.Capacity {
+		resp.Warnings = append(resp.Warnings, "reserved seats exceed capacity")
+	}
+	resp.OpenSeats = req.Capacity - reserved
+	if resp.OpenSeats < 0 {
+		resp.OpenSeats = 0
+	}
+
+	// 2. Evaluate every student against the rules
+	for _, s := range req.Students {
+		result := EligibilityResult{StudentID: s.StudentID}
+		result.Reasons = checkEligibility(req.Rules, s)
+		if len(result.Reasons) == 0 {
+			result.Eligible = true
+			resp.EligibleCount++
+			result.SeatPool = "open"
+			for _, p := range req.SeatPools {
+				if p.admits(&s) {
+					result.SeatPool = p.Name
+					break
+				}
+			}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	if resp.EligibleCount == 0 {
+		resp.Warnings = append(resp.Warnings, "no student in the sample population is eligible")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/config"
+	"shared/discovery"
+	"shared/httpjson"
+	"shared/idempotency"
+	"shared/models"
+)
+
+// --- Enrollment Sagas ---
+
+// An enrollment that needs other services runs as a saga, one step after
+// another: a seat is held here, the student's prerequisites are checked
+// against their released grades in the Grade Service, the course is
+// charged through the billing service at BILLING_SERVICE_URL, if there is
+// one, and the hold is confirmed. When a step fails, the steps already done
+// are undone in reverse: the charge is voided, then the seat released. An
+// undo that fails (billing is down) is retried by resolveSagas until it
+// goes through, so no enrollment is left half done.
+//
+// POST /enroll runs a saga for courses with prerequisites, and for every
+// course while billing is on, permission numbers included; otherwise nothing
+// leaves the service and the enrollment is a single step. Confirming a seat
+// hold in such a course (POST /holds/confirm) runs the same saga on the
+// hold, so a hold never skips the prerequisites or the charge. GET /enrollment-sagas/{id} shows a saga's
+// progress, and GET /enrollment-sagas lists them for the registrar.
+//
+// Sagas are kept in memory for sagaRetention. If the service stops mid-saga
+// its seat hold goes with it; the billing service is told the saga ID as
+// the charge ID, so it can match up a charge that was never confirmed.
+
+// Saga statuses.
+const (
+	sagaRunning      = "running"
+	sagaEnrolled     = "enrolled"
+	sagaCompensating = "compensating" // Failed; an undo is waiting to be retried
+	sagaRolledBack   = "rolled_back"  // Failed and fully undone
+)
+
+// Step statuses.
+const (
+	stepPending     = "pending"
+	stepDone        = "done"
+	stepSkipped     = "skipped"
+	stepFailed      = "failed"
+	stepUndoPending = "undo_pending"
+	stepCompensated = "compensated"
+)
+
+type Saga struct {
+	ID        string             `json:"saga_id"`
+	StudentID string             `json:"student_id"`
+	CourseID  string             `json:"course_id"`
+	Status    string             `json:"status"`
+	Steps     []*SagaStep        `json:"steps"`
+	Failure   *EnrollmentFailure `json:"failure,omitempty"`
+	StartedAt time.Time          `json:"started_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+
+	token   string // The enrolling user's, for the calls to other services
+	profile *StudentProfile
+	adopt   string // The hold being confirmed, for a saga started by /holds/confirm
+	permit  string // The permission number, for an /enroll that carries one
+
+	// Set by the seat step
+	hold          *SeatHold
+	adopted       bool              // The hold was the student's before the saga
+	permitUsed    *PermissionNumber // Redeemed for the seat, until the saga fails
+	term          string
+	credits       int
+	prerequisites []string
+}
+
+type SagaStep struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	UndoTries int    `json:"undo_attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// sagaStep is one step of the enrollment saga. skip, if set, gives the
+// reason a saga has no need of the step, or "". undo is nil for a step with
+// nothing to take back. undoFailed also undoes the step when it failed for
+// want of an answer, which may hide a success, as with a charge that timed
+// out.
+type sagaStep struct {
+	name       string
+	skip       func(s *Saga) string
+	run        func(s *Saga) (detail string, err *enrollError)
+	undo       func(s *Saga) error
+	undoFailed bool
+}
+
+var enrollmentSaga = []sagaStep{
+	{name: "seat", run: holdSagaSeat, undo: releaseSagaSeat},
+	{name: "prerequisites", skip: noPrerequisites, run: checkPrerequisites},
+	{name: "charge", skip: noBilling, run: chargeEnrollment, undo: voidCharge, undoFailed: true},
+	{name: "confirm", run: confirmSagaSeat},
+}
+
+const (
+	sagaRetryInterval = 5 * time.Second
+	sagaRetention     = 24 * time.Hour
+)
+
+var (
+	sagasMu sync.Mutex // Guards sagas and every Saga's exported fields
+	sagas   = make(map[string]*Saga)
+)
+
+// billingClient calls the billing service, which is outside the system: it
+// keeps the default transport and the system's CAs rather than the internal
+// one of httpjson.Client.
+var billingClient = &http.Client{Timeout: 2 * time.Second}
+
+func billingServiceURL() string {
+	return discovery.Peer("BILLING_SERVICE_URL").URL()
+}
+
+func gradeServiceURL() string {
+	return discovery.Peer("GRADE_SERVICE_URL").URL()
+}
+
+// needsSaga reports whether enrolling in a course involves other services.
+func needsSaga(courseID string) bool {
+	if config.Get("BILLING_SERVICE_URL") != "" {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c := findCourse(courseID)
+	return c != nil && c.Rules != nil && len(c.Rules.Prerequisites) > 0
+}
+
+// enrollBySaga answers /enroll by running a saga, with the same responses
+// as a direct enrollment and the saga's address in Location.
+func enrollBySaga(w http.ResponseWriter, r *http.Request, req EnrollRequest, profile *StudentProfile) {
+	s := newSaga(r, req.StudentID, req.CourseID, profile)
+	s.permit = req.PermissionNumber
+	answerSaga(w, s, runSaga(s))
+}
+
+// newSaga starts keeping a saga enrolling a student in a course for the
+// caller of r. The caller sets what else the saga needs before running it.
+func newSaga(r *http.Request, studentID, courseID string, profile *StudentProfile) *Saga {
+	s := &Saga{
+		ID:        newHoldID(),
+		StudentID: studentID,
+		CourseID:  courseID,
+		Status:    sagaRunning,
+		StartedAt: time.Now(),
+		token:     strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		profile:   profile,
+	}
+	for _, step := range enrollmentSaga {
+		s.Steps = append(s.Steps, &SagaStep{Name: step.name, Status: stepPending})
+	}
+	s.UpdatedAt = s.StartedAt
+	sagasMu.Lock()
+	sagas[s.ID] = s
+	sagasMu.Unlock()
+	return s
+}
+
+// answerSaga writes the outcome of a saga that ran with status.
+func answerSaga(w http.ResponseWriter, s *Saga, status int) {
+	w.Header().Set("Location", "/enrollment-sagas/"+s.ID)
+	if status != http.StatusOK {
+		sagasMu.Lock()
+		f := *s.Failure
+		sagasMu.Unlock()
+		failEnrollment(w, status, f)
+		return
+	}
+	enrollmentAttempts.WithLabelValues("enrolled").Inc()
+	stampVersion(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "enrolled", "saga_id": s.ID})
+}
+
+// runSaga runs the steps in order and, if one fails, undoes the ones before
+// it. It returns the HTTP status of the outcome.
+func runSaga(s *Saga) int {
+	for i, step := range enrollmentSaga {
+		if step.skip != nil {
+			if reason := step.skip(s); reason != "" {
+				sagasMu.Lock()
+				s.Steps[i].Status, s.Steps[i].Detail = stepSkipped, reason
+				sagasMu.Unlock()
+				continue
+			}
+		}
+		detail, err := step.run(s)
+		sagasMu.Lock()
+		s.UpdatedAt = time.Now()
+		if err == nil {
+			s.Steps[i].Status, s.Steps[i].Detail = stepDone, detail
+			sagasMu.Unlock()
+			continue
+		}
+		s.Steps[i].Status, s.Steps[i].LastError = stepFailed, err.Message
+		f := err.failure(s.CourseID)
+		s.Failure = &f
+		sagasMu.Unlock()
+
+		slog.Info("enrollment saga failed, compensating", "saga_id", s.ID, "step", step.name, "student_id", s.StudentID, "course_id", s.CourseID, "reason", err.Message)
+		compensate(s)
+		return err.Status
+	}
+	sagasMu.Lock()
+	s.Status, s.UpdatedAt = sagaEnrolled, time.Now()
+	sagasMu.Unlock()
+	return http.StatusOK
+}
+
+// compensate undoes, last first, the steps that need it and have not been
+// undone yet, and settles the saga as rolled back or still compensating.
+// Only one compensate runs per saga at a time: runSaga's, then
+// resolveSagas'.
+func compensate(s *Saga) {
+	pending := false
+	for i := len(enrollmentSaga) - 1; i >= 0; i-- {
+		step := enrollmentSaga[i]
+		sagasMu.Lock()
+		state := s.Steps[i].Status
+		sagasMu.Unlock()
+		due := state == stepDone || state == stepUndoPending || (state == stepFailed && step.undoFailed && unanswered(s))
+		if step.undo == nil || !due {
+			continue
+		}
+
+		err := step.undo(s)
+		sagasMu.Lock()
+		s.Steps[i].UndoTries++
+		s.UpdatedAt = time.Now()
+		if err != nil {
+			s.Steps[i].Status, s.Steps[i].LastError = stepUndoPending, err.Error()
+			pending = true
+		} else {
+			s.Steps[i].Status = stepCompensated
+		}
+		tries := s.Steps[i].UndoTries
+		sagasMu.Unlock()
+		if err != nil && tries == 1 {
+			slog.Warn("enrollment saga: cannot undo step, will retry", "saga_id", s.ID, "step", step.name, "err", err)
+		}
+	}
+
+	sagasMu.Lock()
+	defer sagasMu.Unlock()
+	was := s.Status
+	s.Status = sagaRolledBack
+	if pending {
+		s.Status = sagaCompensating
+	} else if was == sagaCompensating {
+		slog.Info("enrollment saga rolled back", "saga_id", s.ID)
+	}
+}
+
+// unanswered reports whether the saga failed because a service did not
+// answer, rather than refused.
+func unanswered(s *Saga) bool {
+	sagasMu.Lock()
+	defer sagasMu.Unlock()
+	return s.Failure != nil && s.Failure.Code == models.CodeUnavailable
+}
+
+// resolveSagas runs forever, retrying the undos of failed sagas and
+// forgetting finished ones after sagaRetention.
+func resolveSagas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var retry []*Saga
+		sagasMu.Lock()
+		for id, s := range sagas {
+			switch {
+			case s.Status == sagaCompensating:
+				retry = append(retry, s)
+			case s.Status != sagaRunning && now.Sub(s.UpdatedAt) > sagaRetention:
+				delete(sagas, id)
+			}
+		}
+		sagasMu.Unlock()
+		for _, s := range retry {
+			compensate(s)
+		}
+	}
+}
+
+// --- Steps ---
+
+// holdSagaSeat runs the checks of a direct enrollment and holds a seat, or
+// takes over the hold being confirmed. The hold's ID stays inside the saga:
+// a hold the student could confirm themselves would skip the later steps.
+func holdSagaSeat(s *Saga) (string, *enrollError) {
+	if s.adopt != "" {
+		return adoptSagaHold(s)
+	}
+	unlock, lockErr := lockSeats(context.Background(), s.StudentID, s.CourseID)
+	if lockErr != nil {
+		return "", lockErr
+	}
+	defer unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if h := activeRegistrationHold(s.StudentID); h != nil {
+		return "", &enrollError{http.StatusForbidden, models.CodeHoldPresent, "Registration blocked by " + h.Type + " hold"}
+	}
+	c := findCourse(s.CourseID)
+	if c == nil {
+		return "", &enrollError{http.StatusNotFound, models.CodeNotFound, "Course not found"}
+	}
+	var permit *PermissionNumber
+	if s.permit != "" {
+		var msg string
+		if permit, msg = redeemablePermission(s.permit, c.ID, s.StudentID, time.Now().UTC()); permit == nil {
+			return "", &enrollError{http.StatusForbidden, models.CodePermissionInvalid, msg}
+		}
+	}
+	if missing := missingCoRequisites(c, s.StudentID, nil); len(missing) > 0 {
+		return "", &enrollError{http.StatusConflict, models.CodePrereqMissing,
+			"Co-requisite required: enroll together with " + strings.Join(missing, ", ") + " via /enroll-batch"}
+	}
+	if err := admissionError(c, s.StudentID); err != nil {
+		return "", err
+	}
+	var pool string
+	var err error
+	if permit != nil {
+		pool, err = takePermittedSeat(c, s.profile, "")
+	} else {
+		pool, err = takeSeat(c, s.profile, "")
+	}
+	if err != nil {
+		return "", seatError(err)
+	}
+	h := &SeatHold{ID: newHoldID(), CourseID: c.ID, StudentID: s.StudentID, ExpiresAt: time.Now().Add(defaultHoldTTL()), Pool: pool, saga: s.ID}
+	holds[h.ID] = h
+
+	detail := "seat held"
+	if permit != nil {
+		// Spent now, so it cannot be used twice while the saga runs
+		now := time.Now().UTC()
+		permit.UsedBy, permit.UsedAt = s.StudentID, &now
+		s.permitUsed = permit
+		detail = "seat held with permission number " + permit.Code
+	}
+	s.hold = h
+	sagaCourse(s, c)
+	return detail, nil
+}
+
+// adoptSagaHold takes over the student's own hold for the saga, so it can
+// neither be confirmed nor released by hand while the saga runs.
+func adoptSagaHold(s *Saga) (string, *enrollError) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	h, ok := holds[s.adopt]
+	if !ok || h.saga != "" || h.StudentID != s.StudentID {
+		return "", &enrollError{http.StatusNotFound, models.CodeNotFound, "Hold not found"}
+	}
+	if time.Now().After(h.ExpiresAt) {
+		releaseHold(h)
+		return "", &enrollError{http.StatusGone, models.CodeHoldExpired, "Hold expired"}
+	}
+	c := findCourse(h.CourseID)
+	if c == nil {
+		return "", &enrollError{http.StatusNotFound, models.CodeNotFound, "Course not found"}
+	}
+	h.saga = s.ID
+	s.hold, s.adopted = h, true
+	sagaCourse(s, c)
+	return "seat held since before the saga", nil
+}
+
+// sagaCourse keeps what the later steps need to know of the course.
+func sagaCourse(s *Saga, c *Course) {
+	s.term, s.credits = c.Term, c.Credits
+	if c.Rules != nil {
+		s.prerequisites = slices.Clone(c.Rules.Prerequisites)
+	}
+}
+
+// releaseSagaSeat gives the held seat back, unless the hold has lapsed and
+// expireHolds already did. A hold the student placed themselves goes back
+// to them instead, and a permission number is theirs to use again.
+func releaseSagaSeat(s *Saga) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if p := s.permitUsed; p != nil {
+		p.UsedBy, p.UsedAt = "", nil
+		s.permitUsed = nil
+	}
+	h, ok := holds[s.hold.ID]
+	switch {
+	case !ok || h.saga != s.ID:
+	case s.adopted:
+		h.saga = ""
+	default:
+		releaseHold(h)
+		promoteWaitlists(time.Now())
+	}
+	return nil
+}
+
+// CompletedCourses is the Grade Service's answer on /completed-courses.
+type CompletedCourses struct {
+	StudentID string   `json:"student_id"`
+	Courses   []string `json:"courses"` // Catalog codes
+}
+
+func noPrerequisites(s *Saga) string {
+	if len(s.prerequisites) == 0 {
+		return "no prerequisites"
+	}
+	return ""
+}
+
+// checkPrerequisites asks the Grade Service which courses the student has
+// passed. A prerequisite names a catalog code or an offering of one.
+func checkPrerequisites(s *Saga) (string, *enrollError) {
+	var done CompletedCourses
+	err := httpjson.Get(gradeServiceURL()+"/completed-courses?student_id="+url.QueryEscape(s.StudentID), s.token, &done)
+	if err != nil {
+		return "", &enrollError{http.StatusServiceUnavailable, models.CodeUnavailable, "Cannot check prerequisites with the Grade Service: " + err.Error()}
+	}
+	var missing []string
+	for _, p := range s.prerequisites {
+		code, _, _ := strings.Cut(p, "@")
+		if !slices.Contains(done.Courses, code) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &enrollError{http.StatusConflict, models.CodePrereqMissing, "Prerequisite required: " + strings.Join(missing, ", ")}
+	}
+	return "completed " + strings.Join(s.prerequisites, ", "), nil
+}
+
+// Charge is what the billing service is asked to bill. ChargeID is the
+// saga's ID, so billing can drop a repeat of the same charge.
+type Charge struct {
+	ChargeID  string `json:"charge_id"`
+	StudentID string `json:"student_id"`
+	CourseID  string `json:"course_id"`
+	Term      string `json:"term"`
+	Credits   int    `json:"credits"`
+}
+
+func noBilling(s *Saga) string {
+	if config.Get("BILLING_SERVICE_URL") == "" {
+		return "no billing service"
+	}
+	return ""
+}
+
+// chargeEnrollment bills the course (POST /charges). A refusal from billing
+// declines the enrollment; no answer at all might still have been a charge,
+// which is why the step is undone even when it fails.
+func chargeEnrollment(s *Saga) (string, *enrollError) {
+	charge := Charge{ChargeID: s.ID, StudentID: s.StudentID, CourseID: s.CourseID, Term: s.term, Credits: s.credits}
+	req, err := httpjson.NewRequest(context.Background(), http.MethodPost, billingServiceURL()+"/charges", s.token, charge)
+	if err == nil {
+		req.Header.Set(idempotency.Header, "charge-"+s.ID)
+		err = httpjson.Do(billingClient, req, nil)
+	}
+	var refused *httpjson.StatusError
+	if errors.As(err, &refused) && refused.StatusCode < 500 {
+		return "", &enrollError{http.StatusPaymentRequired, models.CodePaymentDeclined, "Charge declined: " + refused.Message}
+	}
+	if err != nil {
+		return "", &enrollError{http.StatusServiceUnavailable, models.CodeUnavailable, "Cannot charge the enrollment: " + err.Error()}
+	}
+	return "charge " + s.ID, nil
+}
+
+// voidCharge cancels the charge (POST /charges/{id}/void). Billing not
+// knowing the charge means it was never made, which is as good as voided.
+func voidCharge(s *Saga) error {
+	req, err := httpjson.NewRequest(context.Background(), http.MethodPost, billingServiceURL()+"/charges/"+url.PathEscape(s.ID)+"/void", s.token, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(idempotency.Header, "void-"+s.ID)
+	err = httpjson.Do(billingClient, req, nil)
+	var refused *httpjson.StatusError
+	if errors.As(err, &refused) && refused.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func confirmSagaSeat(s *Saga) (string, *enrollError) {
+	mu.Lock()
+	defer mu.Unlock()
+	h, ok := holds[s.hold.ID]
+	if !ok || h.saga != s.ID {
+		return "", &enrollError{http.StatusGone, models.CodeHoldExpired, "Hold expired"}
+	}
+	detail := "enrollment saga " + s.ID
+	if p := s.permitUsed; p != nil {
+		detail += ", permission number " + p.Code + " issued by " + p.IssuedBy
+	}
+	if err := confirmSeat(h, detail); err != nil {
+		return "", err
+	}
+	return "enrolled", nil
+}
+
+// --- Status API ---
+
+// sagaHandler (GET /enrollment-sagas/{id}) shows one saga. Students see
+// their own; the registrar and admins see any.
+func sagaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	sagasMu.Lock()
+	defer sagasMu.Unlock()
+	s, ok := sagas[r.PathValue("id")]
+	if !ok || (user.Role == "student" && s.StudentID != user.Username) {
+		http.Error(w, "Saga not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// listSagas (GET /enrollment-sagas?status=&student_id=) lists the sagas
+// kept, newest first, for the registrar and admins.
+func listSagas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+	status, studentID := r.URL.Query().Get("status"), r.URL.Query().Get("student_id")
+
+	sagasMu.Lock()
+	defer sagasMu.Unlock()
+	list := []*Saga{}
+	for _, s := range sagas {
+		if (status == "" || s.Status == status) && (studentID == "" || s.StudentID == studentID) {
+			list = append(list, s)
+		}
+	}
+	slices.SortFunc(list, func(a, b *Saga) int { return b.StartedAt.Compare(a.StartedAt) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"shared/models"
+	"shared/studentid"
+)
+
+// --- Meeting Times ---
+
+// A section meets at the same times every week of the term. Times are
+// campus-local "15:04" clock times. GET /schedule lists a student's sections
+// with their meetings, plus the first and last day of classes
+// (CLASSES_START and CLASSES_END) so calendars know when the weekly
+// meetings begin and stop; either may be unset.
+
+var classesStart, classesEnd time.Time // Set by loadCalendar
+
+var meetingDays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+type Meeting = models.Meeting
+
+// validateMeeting checks the day and that the meeting ends after it starts.
+func validateMeeting(m Meeting) error {
+	known := false
+	for _, d := range meetingDays {
+		known = known || m.Day == d
+	}
+	if !known {
+		return fmt.Errorf("day must be one of %s", strings.Join(meetingDays, ", "))
+	}
+	start, err := time.Parse("15:04", m.Start)
+	if err != nil {
+		return fmt.Errorf("start %q must be a time such as 09:15", m.Start)
+	}
+	end, err := time.Parse("15:04", m.End)
+	if err != nil {
+		return fmt.Errorf("end %q must be a time such as 10:45", m.End)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("%s meeting must end after it starts", m.Day)
+	}
+	return nil
+}
+
+// parseMeeting reads the fixture form "Mon 09:15-10:45 G304"; the room is
+// optional.
+func parseMeeting(s string) (Meeting, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return Meeting{}, fmt.Errorf("meeting %q must look like \"Mon 09:15-10:45 G304\"", s)
+	}
+	start, end, _ := strings.Cut(fields[1], "-")
+	m := Meeting{Day: fields[0], Start: start, End: end, Room: strings.Join(fields[2:], " ")}
+	if err := validateMeeting(m); err != nil {
+		return Meeting{}, fmt.Errorf("meeting %q: %w", s, err)
+	}
+	return m, nil
+}
+
+type MeetingsRequest struct {
+	CourseID string    `json:"course_id"`
+	Meetings []Meeting `json:"meetings"`
+}
+
+// setMeetings replaces a section's meeting times (PUT /courses/meetings).
+// Registrar or admin, with the course version as If-Match.
+func setMeetings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "registrar", "admin")
+	if !ok {
+		return
+	}
+	var req MeetingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, m := range req.Meetings {
+		if err := validateMeeting(m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	c.Meetings = req.Meetings
+	touch(c)
+	recordEvent(EnrollmentEvent{Type: "update", CourseID: c.ID, Actor: user.Username, Detail: "meetings"})
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "meetings updated"}`))
+}
+
+type ScheduledSection struct {
+	CourseID   string    `json:"course_id"`
+	Title      string    `json:"title"`
+	Instructor string    `json:"instructor,omitempty"`
+	Meetings   []Meeting `json:"meetings"`
+}
+
+type Schedule struct {
+	StudentID    string             `json:"student_id"`
+	Term         string             `json:"term"`
+	ClassesStart *time.Time         `json:"classes_start,omitempty"`
+	ClassesEnd   *time.Time         `json:"classes_end,omitempty"`
+	Sections     []ScheduledSection `json:"sections"`
+}
+
+// studentSchedule lists the sections a student is enrolled in for a term
+// (GET /schedule?student_id=&term=, current term by default).
+func studentSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := enrollingProfile(w, r, studentID); !ok {
+		return
+	}
+
+	s := Schedule{StudentID: studentID, Term: requestedTerm(r), Sections: []ScheduledSection{}}
+	if !classesStart.IsZero() {
+		s.ClassesStart = &classesStart
+	}
+	if !classesEnd.IsZero() {
+		s.ClassesEnd = &classesEnd
+	}
+	mu.RLock()
+	enrollMu.Lock()
+	for _, c := range courses {
+		if c.Term == s.Term && enrollments[c.ID+":"+studentID] {
+			s.Sections = append(s.Sections, ScheduledSection{CourseID: c.ID, Title: c.Title, Instructor: c.Instructor, Meetings: c.Meetings})
+		}
+	}
+	enrollMu.Unlock()
+	mu.RUnlock()
+	sort.Slice(s.Sections, func(i, j int) bool { return s.Sections[i].CourseID < s.Sections[j].CourseID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"shared/logging"
+	"shared/models"
+)
+
+// --- Reserved Seat Pools ---
+
+// SeatPool holds back part of a course's capacity for a cohort. A student
+// qualifies when they match any listed program and any listed year level;
+// an empty list matches everyone on that dimension. Seats not covered by a
+// pool are open to all.
+type SeatPool struct {
+	Name       string   `json:"name"`
+	Programs   []string `json:"programs,omitempty"`
+	YearLevels []int    `json:"year_levels,omitempty"`
+	Seats      int      `json:"seats"`
+	Taken      int      `json:"taken"`
+}
+
+// enrollmentPools remembers which pool each seat came from so it can be
+// returned there. Key: "CourseID:StudentID", guarded by mu.
+var enrollmentPools = make(map[string]string)
+
+func (p *SeatPool) admits(s *StudentProfile) bool {
+	if s == nil {
+		return false
+	}
+	if len(p.Programs) > 0 && !contains(p.Programs, s.Program) {
+		return false
+	}
+	if len(p.YearLevels) == 0 {
+		return true
+	}
+	for _, y := range p.YearLevels {
+		if y == s.YearLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedRemaining counts seats still held back for cohorts. Callers must hold mu.
+func reservedRemaining(c *Course) int {
+	n := 0
+	for _, p := range c.SeatPools {
+		if left := p.Seats - p.Taken; left > 0 {
+			n += left
+		}
+	}
+	return n
+}
+
+// seatsFor counts the open seats a student could take: the unreserved ones
+// plus those left in the pools they qualify for. Callers must hold mu.
+func seatsFor(c *Course, s *StudentProfile) int {
+	c = seatOwner(c)
+	n := c.OpenSlots - reservedRemaining(c)
+	for _, p := range c.SeatPools {
+		if left := p.Seats - p.Taken; left > 0 && p.admits(s) {
+			n += left
+		}
+	}
+	return max(0, min(n, c.OpenSlots))
+}
+
+// takeSeat claims a seat, preferring a reserved pool the student qualifies
+// for and falling back to the open seats. It returns the pool name ("" for an
+// open seat). A non-empty studentID is recorded as enrolled in the seat store
+// in the same step; holds pass "". Callers must hold mu.
+func takeSeat(c *Course, s *StudentProfile, studentID string) (string, error) {
+	var eligible []string
+	for _, p := range seatOwner(c).SeatPools {
+		if p.admits(s) {
+			eligible = append(eligible, p.Name)
+		}
+	}
+	pool, err := seats.take(c, eligible, studentID)
+	if err == nil {
+		seatsChanged(c)
+	}
+	return pool, err
+}
+
+// seatError is what the client is told when a seat could not be taken.
+func seatError(err error) *enrollError {
+	switch err {
+	case errAlreadyEnrolled:
+		return &enrollError{http.StatusConflict, models.CodeAlreadyEnrolled, "Student already enrolled"}
+	case errSeatStore:
+		return &enrollError{http.StatusServiceUnavailable, models.CodeUnavailable, "Seat store unavailable; try again"}
+	}
+	return &enrollError{http.StatusConflict, models.CodeFull, "Course full"}
+}
+
+// releaseSeat returns a seat to the pool it was taken from. A seat added by a
+// permission number is retired instead, so the course does not reopen past
+// its normal limit. Callers must hold mu.
+func releaseSeat(c *Course, pool, studentID string) {
+	seats.release(c, pool, studentID)
+	if owner := seatOwner(c); owner.PermitSeats > 0 {
+		owner.PermitSeats--
+		if seats.resize(owner, -1) != nil {
+			owner.PermitSeats++
+		}
+	}
+	seatsChanged(c)
+}
+
+// reclaimSeat undoes releaseSeat, putting a seat back into the exact pool it
+// was released from. It fails if another replica sold the seat in between.
+// Callers must hold mu.
+func reclaimSeat(c *Course, pool, studentID string) bool {
+	if !seats.reclaim(c, pool, studentID) {
+		return false
+	}
+	seatsChanged(c)
+	return true
+}
+
+// seatsChanged mirrors the counts of the course that owns the seats onto
+// any cross-listings. Seat counts are not an admin edit, so the version
+// stays. Callers must hold mu.
+func seatsChanged(c *Course) {
+	mirrorSeats(seatOwner(c))
+}
+
+// enrollingProfile works out whose cohort claims apply to an enrollment. The
+// token is optional; when a student presents one it must match studentID,
+// and staff acting on a student's behalf only get open seats.
+func enrollingProfile(w http.ResponseWriter, r *http.Request, studentID string) (*StudentProfile, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, true
+	}
+	user, valid := validateTokenAndGetUser(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+	if !valid {
+		http.Error(w, "Unauthorized: Invalid Token", http.StatusUnauthorized)
+		return nil, false
+	}
+	logging.SetUser(r.Context(), user.Username)
+	if user.Role != "student" {
+		return nil, true
+	}
+	if user.Username != studentID {
+		http.Error(w, "Forbidden: You cannot enroll another student", http.StatusForbidden)
+		return nil, false
+	}
+	return &StudentProfile{StudentID: user.Username, Program: user.Program, YearLevel: user.YearLevel}, true
+}
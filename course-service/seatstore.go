@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"shared/config"
+)
+
+// --- Shared Seat Store ---
+
+// Seat counts and enrollment membership decide whether a seat can be sold, so
+// they are the state replicas must share. With SEAT_STORE_URL=redis://... they
+// live in Redis and every claim runs as a single Lua script, letting several
+// course-service instances sit behind a load balancer without overselling a
+// section or enrolling a student twice. Each replica keeps its in-memory
+// fields as a cache, refreshed by runSeatSync, for reads and for the state
+// that is still per replica (holds, waitlists, history, catalog edits other
+// than capacity).
+//
+// Without SEAT_STORE_URL the in-memory fields are authoritative, which is
+// right for a single instance.
+//
+// Callers hold mu, as for the in-memory fields. An exclusive holder lets
+// readers in while Redis answers (see shareMu). A store that cannot be
+// reached is errSeatStore, which the client gets as 503: it says nothing of
+// the seats.
+
+var (
+	errCourseFull      = errors.New("course full")
+	errAlreadyEnrolled = errors.New("student already enrolled")
+	errSeatStore       = errors.New("seat store unavailable")
+)
+
+// Every method takes the course the student is enrolling under; seat counts
+// are kept on its seatOwner, so cross-listed codes share them.
+type seatStore interface {
+	// take claims a seat, trying the eligible pools in order before the open
+	// seats. A non-empty studentID is recorded as enrolled in the same step;
+	// holds pass "" and record the enrollment later with confirm.
+	take(c *Course, eligible []string, studentID string) (string, error)
+	// release returns a seat and, for a non-empty studentID, the enrollment.
+	release(c *Course, pool, studentID string)
+	// reclaim undoes release, if the seat has not been sold in the meantime.
+	reclaim(c *Course, pool, studentID string) bool
+	// confirm records a held seat as the student's enrollment, or fails with
+	// errAlreadyEnrolled.
+	confirm(c *Course, pool, studentID string) error
+	// resize applies a change in sellable seats, failing with errCourseFull
+	// rather than going below zero open.
+	resize(c *Course, delta int) error
+	// add registers a new course with its current seat counts.
+	add(c *Course) error
+	// version is the store version after every write made so far.
+	version() int64
+	// catchUp brings the local cache up to at least version v.
+	catchUp(v int64) bool
+}
+
+var seats seatStore = memorySeats{}
+
+// memorySeats keeps seats in the Course fields themselves. Callers must hold
+// mu, or the course's lock on the shared hot path. Every write bumps
+// syncedVersion directly, since this cache is the store.
+type memorySeats struct{}
+
+func (memorySeats) take(c *Course, eligible []string, studentID string) (string, error) {
+	c = seatOwner(c)
+	if c.OpenSlots <= 0 {
+		return "", errCourseFull
+	}
+	for _, p := range c.SeatPools {
+		if p.Taken < p.Seats && contains(eligible, p.Name) {
+			p.Taken++
+			c.OpenSlots--
+			syncedVersion.Add(1)
+			return p.Name, nil
+		}
+	}
+	if c.OpenSlots-reservedRemaining(c) > 0 {
+		c.OpenSlots--
+		syncedVersion.Add(1)
+		return "", nil
+	}
+	return "", errCourseFull
+}
+
+func (memorySeats) release(c *Course, pool, studentID string) {
+	c = seatOwner(c)
+	c.OpenSlots++
+	if p := findPool(c, pool); p != nil && p.Taken > 0 {
+		p.Taken--
+	}
+	syncedVersion.Add(1)
+}
+
+func (memorySeats) reclaim(c *Course, pool, studentID string) bool {
+	c = seatOwner(c)
+	c.OpenSlots--
+	if p := findPool(c, pool); p != nil {
+		p.Taken++
+	}
+	syncedVersion.Add(1)
+	return true
+}
+
+func (memorySeats) confirm(c *Course, pool, studentID string) error {
+	syncedVersion.Add(1)
+	return nil
+}
+
+func (memorySeats) resize(c *Course, delta int) error {
+	c = seatOwner(c)
+	if c.OpenSlots+delta < 0 {
+		return errCourseFull
+	}
+	c.OpenSlots += delta
+	syncedVersion.Add(1)
+	return nil
+}
+
+func (memorySeats) add(c *Course) error {
+	return nil
+}
+
+func (memorySeats) version() int64 {
+	return syncedVersion.Load()
+}
+
+func (memorySeats) catchUp(v int64) bool {
+	return true
+}
+
+func findPool(c *Course, name string) *SeatPool {
+	if name == "" {
+		return nil
+	}
+	for _, p := range c.SeatPools {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Redis layout per course: hash seats:<id> with "open", "capacity", "factor",
+// "permits", "cap:<pool>" and "taken:<pool>", and hash enrolled:<id> mapping each
+// student ID to the pool their seat came from. A cross-listed code has its
+// own enrolled hash but counts seats in its primary's seats hash; the claim
+// checks every listing's enrolled hash for duplicates. The counter
+// seats:version is bumped by every write, for read-your-writes.
+
+var takeScript = redis.NewScript(`
+local student = ARGV[1]
+if student ~= '' then
+  for i = 2, #KEYS do
+    if redis.call('HEXISTS', KEYS[i], student) == 1 then return {'enrolled', ''} end
+  end
+end
+local open = tonumber(redis.call('HGET', KEYS[1], 'open') or '0')
+if open <= 0 then return {'full', ''} end
+local function claim(pool)
+  redis.call('INCR', 'seats:version')
+  redis.call('HINCRBY', KEYS[1], 'open', -1)
+  if pool ~= '' then redis.call('HINCRBY', KEYS[1], 'taken:' .. pool, 1) end
+  if student ~= '' then redis.call('HSET', KEYS[2], student, pool) end
+  return {'ok', pool}
+end
+local n = tonumber(ARGV[2])
+for i = 3, 2 + n do
+  local cap = tonumber(redis.call('HGET', KEYS[1], 'cap:' .. ARGV[i]) or '0')
+  local taken = tonumber(redis.call('HGET', KEYS[1], 'taken:' .. ARGV[i]) or '0')
+  if taken < cap then return claim(ARGV[i]) end
+end
+local reserved = 0
+for i = 3 + n, #ARGV do
+  local left = tonumber(redis.call('HGET', KEYS[1], 'cap:' .. ARGV[i]) or '0') - tonumber(redis.call('HGET', KEYS[1], 'taken:' .. ARGV[i]) or '0')
+  if left > 0 then reserved = reserved + left end
+end
+if open - reserved > 0 then return claim('') end
+return {'full', ''}
+`)
+
+// A seat is only returned if the enrollment was still there, so two replicas
+// dropping the same student cannot free the seat twice.
+var releaseScript = redis.NewScript(`
+if ARGV[2] ~= '' and redis.call('HDEL', KEYS[2], ARGV[2]) == 0 then return 0 end
+redis.call('INCR', 'seats:version')
+redis.call('HINCRBY', KEYS[1], 'open', 1)
+if ARGV[1] ~= '' and tonumber(redis.call('HGET', KEYS[1], 'taken:' .. ARGV[1]) or '0') > 0 then
+  redis.call('HINCRBY', KEYS[1], 'taken:' .. ARGV[1], -1)
+end
+return 1
+`)
+
+var reclaimScript = redis.NewScript(`
+if tonumber(redis.call('HGET', KEYS[1], 'open') or '0') <= 0 then return 0 end
+redis.call('INCR', 'seats:version')
+redis.call('HINCRBY', KEYS[1], 'open', -1)
+if ARGV[1] ~= '' then redis.call('HINCRBY', KEYS[1], 'taken:' .. ARGV[1], 1) end
+if ARGV[2] ~= '' then redis.call('HSET', KEYS[2], ARGV[2], ARGV[1]) end
+return 1
+`)
+
+var resizeScript = redis.NewScript(`
+local open = tonumber(redis.call('HGET', KEYS[1], 'open') or '0') + tonumber(ARGV[1])
+if open < 0 then return 0 end
+redis.call('INCR', 'seats:version')
+redis.call('HSET', KEYS[1], 'open', open, 'capacity', ARGV[2], 'factor', ARGV[3], 'permits', ARGV[4])
+return 1
+`)
+
+// redisSeats shares seats between replicas.
+type redisSeats struct {
+	client *redis.Client
+}
+
+func seatKeys(c *Course) []string {
+	return []string{"seats:" + seatOwner(c).ID, "enrolled:" + c.ID}
+}
+
+func (s redisSeats) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 2*time.Second)
+}
+
+func (s redisSeats) ping() error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	return s.client.Ping(ctx).Err()
+}
+
+func (s redisSeats) take(c *Course, eligible []string, studentID string) (string, error) {
+	owner := seatOwner(c)
+	args := []interface{}{studentID, len(eligible)}
+	for _, name := range eligible {
+		args = append(args, name)
+	}
+	for _, p := range owner.SeatPools {
+		args = append(args, p.Name)
+	}
+	keys := seatKeys(c)
+	for _, listing := range crossListGroup(c) {
+		if listing != c {
+			keys = append(keys, "enrolled:"+listing.ID)
+		}
+	}
+	var res []string
+	var err error
+	shareMu(func() {
+		ctx, cancel := s.ctx()
+		defer cancel()
+		res, err = takeScript.Run(ctx, s.client, keys, args...).StringSlice()
+	})
+	if err != nil {
+		slog.Error("seat store: take", "course_id", c.ID, "err", err)
+		return "", errSeatStore // Fail closed: never sell a seat we could not record
+	}
+	switch res[0] {
+	case "enrolled":
+		return "", errAlreadyEnrolled
+	case "full":
+		return "", errCourseFull
+	}
+	// Keep the local cache close until the next sync
+	owner.OpenSlots--
+	if p := findPool(owner, res[1]); p != nil {
+		p.Taken++
+	}
+	return res[1], nil
+}
+
+func (s redisSeats) release(c *Course, pool, studentID string) {
+	keys := seatKeys(c)
+	var released int
+	var err error
+	shareMu(func() {
+		ctx, cancel := s.ctx()
+		defer cancel()
+		released, err = releaseScript.Run(ctx, s.client, keys, pool, studentID).Int()
+	})
+	if err != nil {
+		slog.Error("seat store: release", "course_id", c.ID, "err", err)
+		return
+	}
+	if released == 1 {
+		c = seatOwner(c)
+		c.OpenSlots++
+		if p := findPool(c, pool); p != nil && p.Taken > 0 {
+			p.Taken--
+		}
+	}
+}
+
+func (s redisSeats) reclaim(c *Course, pool, studentID string) bool {
+	keys := seatKeys(c)
+	var ok int
+	var err error
+	shareMu(func() {
+		ctx, cancel := s.ctx()
+		defer cancel()
+		ok, err = reclaimScript.Run(ctx, s.client, keys, pool, studentID).Int()
+	})
+	if err != nil || ok == 0 {
+		return false
+	}
+	c = seatOwner(c)
+	c.OpenSlots--
+	if p := findPool(c, pool); p != nil {
+		p.Taken++
+	}
+	return true
+}
+
+func (s redisSeats) confirm(c *Course, pool, studentID string) error {
+	key := "enrolled:" + c.ID
+	var set *redis.BoolCmd
+	var err error
+	shareMu(func() {
+		ctx, cancel := s.ctx()
+		defer cancel()
+		_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			set = pipe.HSetNX(ctx, key, studentID, pool)
+			pipe.Incr(ctx, "seats:version")
+			return nil
+		})
+	})
+	if err != nil {
+		slog.Error("seat store: confirm", "course_id", c.ID, "err", err)
+		return errSeatStore
+	}
+	if !set.Val() {
+		return errAlreadyEnrolled
+	}
+	return nil
+}
+
+func (s redisSeats) resize(c *Course, delta int) error {
+	c = seatOwner(c)
+	keys := seatKeys(c)
+	args := []interface{}{delta, c.Capacity, strconv.FormatFloat(c.OverbookFactor, 'f', -1, 64), c.PermitSeats}
+	var ok int
+	var err error
+	shareMu(func() {
+		ctx, cancel := s.ctx()
+		defer cancel()
+		ok, err = resizeScript.Run(ctx, s.client, keys, args...).Int()
+	})
+	if err != nil {
+		slog.Error("seat store: resize", "course_id", c.ID, "err", err)
+		return errSeatStore
+	}
+	if ok == 0 {
+		return errCourseFull
+	}
+	c.OpenSlots += delta
+	return nil
+}
+
+// add seeds a course unless the store already knows it: HSETNX lets the first
+// replica to see a course set its counts and the rest adopt them.
+func (s redisSeats) add(c *Course) error {
+	var err error
+	shareMu(func() { err = s.seed(c) })
+	return err
+}
+
+// seed runs add's writes. c must not change meanwhile: it is a new course,
+// not yet in the catalog, or the catalog is still being loaded.
+func (s redisSeats) seed(c *Course) error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	pipe := s.client.Pipeline()
+	pipe.HSetNX(ctx, "seats:"+c.ID, "open", c.OpenSlots)
+	pipe.HSetNX(ctx, "seats:"+c.ID, "capacity", c.Capacity)
+	pipe.HSetNX(ctx, "seats:"+c.ID, "factor", strconv.FormatFloat(c.OverbookFactor, 'f', -1, 64))
+	pipe.HSetNX(ctx, "seats:"+c.ID, "permits", c.PermitSeats)
+	for _, p := range c.SeatPools {
+		pipe.HSetNX(ctx, "seats:"+c.ID, "cap:"+p.Name, p.Seats)
+		pipe.HSetNX(ctx, "seats:"+c.ID, "taken:"+p.Name, 0)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s redisSeats) version() int64 {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	v, err := s.client.Get(ctx, "seats:version").Int64()
+	if err != nil && err != redis.Nil {
+		slog.Error("seat store: version", "err", err)
+	}
+	return v
+}
+
+// catchUp syncs now if the periodic sync has not reached v yet.
+func (s redisSeats) catchUp(v int64) bool {
+	if syncedVersion.Load() >= v {
+		return true
+	}
+	catchUpMu.Lock()
+	defer catchUpMu.Unlock()
+	if syncedVersion.Load() < v {
+		if err := s.sync(); err != nil {
+			slog.Error("seat store: catch up", "err", err)
+		}
+	}
+	return syncedVersion.Load() >= v
+}
+
+// openSeatStore connects to the shared store if one is configured and seeds
+// any course it has not seen yet. It must run before the server starts.
+func openSeatStore() {
+	url := config.Get("SEAT_STORE_URL")
+	if url == "" {
+		return
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		log.Fatalf("invalid SEAT_STORE_URL: %v", err)
+	}
+	store := redisSeats{client: redis.NewClient(opts)}
+
+	mu.Lock()
+	for _, c := range courses {
+		if err := store.seed(c); err != nil {
+			log.Fatalf("seat store unreachable: %v", err)
+		}
+	}
+	mu.Unlock()
+
+	seats = store
+	seatLocks = redisSeatLocks{client: store.client}
+	if err := store.sync(); err != nil {
+		log.Fatalf("seat store sync failed: %v", err)
+	}
+	slog.Info("seat store: shared", "addr", opts.Addr)
+}
+
+// closeSeatStore disconnects from the shared store at shutdown.
+func closeSeatStore(ctx context.Context) error {
+	if store, ok := seats.(redisSeats); ok {
+		return store.client.Close()
+	}
+	return nil
+}
+
+// syncMu keeps syncs in order, so an older snapshot never overwrites a newer one.
+var syncMu sync.Mutex
+
+// sync refreshes the local cache of every course from the shared store.
+func (s redisSeats) sync() error {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	mu.RLock()
+	ids := make([]string, 0, len(courses))
+	for _, c := range courses {
+		ids = append(ids, c.ID)
+	}
+	mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// One transaction, so the version read matches the hashes read with it
+	pipe := s.client.TxPipeline()
+	versionCmd := pipe.Get(ctx, "seats:version")
+	seatCmds := make(map[string]*redis.MapStringStringCmd)
+	memberCmds := make(map[string]*redis.MapStringStringCmd)
+	for _, id := range ids {
+		seatCmds[id] = pipe.HGetAll(ctx, "seats:"+id)
+		memberCmds[id] = pipe.HGetAll(ctx, "enrolled:"+id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		c := findCourse(id)
+		if c == nil {
+			continue
+		}
+		fields := seatCmds[seatOwner(c).ID].Val()
+		members := memberCmds[id].Val()
+		edited := false // Capacity and overbooking are admin edits; seat counts are not
+
+		if open, err := strconv.Atoi(fields["open"]); err == nil && open != c.OpenSlots {
+			c.OpenSlots = open
+		}
+		if capacity, err := strconv.Atoi(fields["capacity"]); err == nil && capacity != c.Capacity {
+			c.Capacity, edited = capacity, true
+		}
+		if factor, err := strconv.ParseFloat(fields["factor"], 64); err == nil && factor != c.OverbookFactor {
+			c.OverbookFactor, edited = factor, true
+		}
+		if permits, err := strconv.Atoi(fields["permits"]); err == nil && permits != c.PermitSeats {
+			c.PermitSeats = permits
+		}
+		for _, p := range c.SeatPools {
+			if taken, err := strconv.Atoi(fields["taken:"+p.Name]); err == nil && taken != p.Taken {
+				p.Taken = taken
+			}
+		}
+
+		// Enrollments made or dropped on other replicas
+		prefix := id + ":"
+		for key := range enrollments {
+			if studentID, ok := strings.CutPrefix(key, prefix); ok {
+				if _, still := members[studentID]; !still {
+					delete(enrollments, key)
+					delete(enrollmentPools, key)
+				}
+			}
+		}
+		for studentID, pool := range members {
+			if !enrollments[prefix+studentID] {
+				enrollments[prefix+studentID] = true
+				if pool != "" {
+					enrollmentPools[prefix+studentID] = pool
+				}
+			}
+		}
+		if edited {
+			touch(c)
+		}
+	}
+	v, _ := versionCmd.Int64()
+	noteVersion(v)
+	return nil
+}
+
+// runSeatSync keeps this replica's cache in step with the shared store.
+func runSeatSync(interval time.Duration) {
+	store, ok := seats.(redisSeats)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := store.sync(); err != nil {
+			slog.Error("seat store: sync", "err", err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/server"
+)
+
+// --- Live Seat Stream ---
+
+// Clients subscribe to GET /courses/seats/stream (Server-Sent Events) instead
+// of polling /courses. A broadcaster samples seat counts on a short interval
+// and pushes only the courses that changed, so a burst of enrollments turns
+// into one update per course per tick.
+
+type SeatUpdate struct {
+	CourseID   string    `json:"course_id"`
+	OpenSlots  int       `json:"open_slots"`
+	Waitlisted int       `json:"waitlisted"`
+	At         time.Time `json:"at"`
+}
+
+type seatSubscriber struct {
+	courses map[string]bool // Empty means every course
+	ch      chan SeatUpdate
+}
+
+const seatStreamBuffer = 64
+
+var (
+	seatSubsMu sync.Mutex
+	seatSubs   = make(map[*seatSubscriber]bool)
+	lastSeats  = make(map[string]SeatUpdate) // Last broadcast per course, guarded by seatSubsMu
+)
+
+func (s *seatSubscriber) wants(courseID string) bool {
+	return len(s.courses) == 0 || s.courses[courseID]
+}
+
+// snapshotSeats reads the current counts of every active course.
+func snapshotSeats(now time.Time) []SeatUpdate {
+	mu.RLock()
+	defer mu.RUnlock()
+	var list []SeatUpdate
+	for _, c := range courses {
+		if isArchived(c) {
+			continue
+		}
+		view := courseView(c, "")
+		list = append(list, SeatUpdate{CourseID: c.ID, OpenSlots: view.OpenSlots, Waitlisted: view.Waitlisted, At: now})
+	}
+	return list
+}
+
+// runSeatBroadcaster pushes changed seat counts to subscribers. A subscriber
+// that falls a full buffer behind is disconnected; EventSource reconnects on
+// its own and starts again from a fresh snapshot.
+func runSeatBroadcaster(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		snapshot := snapshotSeats(now)
+
+		seatSubsMu.Lock()
+		for _, u := range snapshot {
+			prev, seen := lastSeats[u.CourseID]
+			if seen && prev.OpenSlots == u.OpenSlots && prev.Waitlisted == u.Waitlisted {
+				continue
+			}
+			lastSeats[u.CourseID] = u
+			for sub := range seatSubs {
+				if !sub.wants(u.CourseID) {
+					continue
+				}
+				select {
+				case sub.ch <- u:
+				default:
+					delete(seatSubs, sub)
+					close(sub.ch)
+				}
+			}
+		}
+		seatSubsMu.Unlock()
+	}
+}
+
+func writeSeatEvent(w http.ResponseWriter, u SeatUpdate) error {
+	data, _ := json.Marshal(u)
+	_, err := fmt.Fprintf(w, "event: seats\ndata: %s\n\n", data)
+	return err
+}
+
+// seatStream serves the SSE feed. ?course_id=A,B limits it to some courses.
+func seatStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := &seatSubscriber{courses: make(map[string]bool), ch: make(chan SeatUpdate, seatStreamBuffer)}
+	if ids := r.URL.Query().Get("course_id"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			sub.courses[strings.TrimSpace(id)] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop proxies from holding events back
+
+	// 1. Register before taking the snapshot so no change slips between them
+	seatSubsMu.Lock()
+	seatSubs[sub] = true
+	seatSubsMu.Unlock()
+	defer func() {
+		seatSubsMu.Lock()
+		if seatSubs[sub] {
+			delete(seatSubs, sub)
+			close(sub.ch)
+		}
+		seatSubsMu.Unlock()
+	}()
+
+	// 2. Current state, so the client can render immediately
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, u := range snapshotSeats(time.Now()) {
+		if sub.wants(u.CourseID) {
+			writeSeatEvent(w, u)
+		}
+	}
+	flusher.Flush()
+
+	// 3. Changes as they happen, with a heartbeat to keep idle connections open
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case u, open := <-sub.ch:
+			if !open {
+				return
+			}
+			if writeSeatEvent(w, u) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-server.Stopping():
+			return // EventSource reconnects, to another node if there is one
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"shared/config"
+	"shared/models"
+)
+
+// --- Terms ---
+
+// Each course offering belongs to one term. The seeded catalog is the first
+// term (CURRENT_TERM, default 2026-T1) and keeps its plain course codes as
+// IDs. Publishing another term copies a catalog into fresh offerings with IDs
+// of the form CODE@TERM, so enrollments, waitlists and seat counts are scoped
+// to their term without any other change. Catalog reads take ?term= and
+// default to the current term.
+
+type Term struct {
+	ID      string `json:"id"`
+	Current bool   `json:"current"`
+	Courses int    `json:"courses"`
+}
+
+type TermRequest struct {
+	ID       string `json:"id"`
+	CopyFrom string `json:"copy_from"` // Defaults to the current term
+}
+
+// seedTerm is the term the service starts in, which the built-in catalog
+// is offered in.
+var seedTerm string
+
+// termMu guards the term list and the current term, so they can be read
+// without mu (lock order: mu, then termMu).
+var (
+	termMu     sync.RWMutex
+	termIDs    []string
+	activeTerm string
+)
+
+// loadTerms starts the term list at CURRENT_TERM. It must run before the
+// catalog is seeded.
+func loadTerms() {
+	seedTerm = config.Get("CURRENT_TERM")
+	termIDs = []string{seedTerm}
+	activeTerm = seedTerm
+}
+
+// currentTerm names the term that catalog reads default to.
+func currentTerm() string {
+	termMu.RLock()
+	defer termMu.RUnlock()
+	return activeTerm
+}
+
+func termExists(id string) bool {
+	termMu.RLock()
+	defer termMu.RUnlock()
+	return contains(termIDs, id)
+}
+
+// requestedTerm is the ?term= of a request, or the current term.
+func requestedTerm(r *http.Request) string {
+	if term := r.URL.Query().Get("term"); term != "" {
+		return term
+	}
+	return currentTerm()
+}
+
+func offeringID(code, term string) string {
+	if term == seedTerm {
+		return code
+	}
+	return code + "@" + term
+}
+
+// publishTerm copies every active offering of one term into another, with
+// fresh seats and no enrollments. Callers must hold mu exclusively.
+func publishTerm(from, to string) []*Course {
+	var added []*Course
+	for _, c := range courses {
+		if c.Term != from || isArchived(c) {
+			continue
+		}
+		next := &Course{Course: models.Course{
+			ID:             offeringID(c.Code, to),
+			Code:           c.Code,
+			Term:           to,
+			Title:          c.Title,
+			Credits:        c.Credits,
+			Instructor:     c.Instructor,
+			DepartmentID:   c.DepartmentID,
+			Capacity:       c.Capacity,
+			OverbookFactor: c.OverbookFactor,
+			Meetings:       c.Meetings, // Replaced whole, never edited in place
+		}, Rules: c.Rules}
+		next.OpenSlots = sellableSeats(next)
+		for _, p := range c.SeatPools {
+			pool := *p
+			pool.Taken = 0
+			next.SeatPools = append(next.SeatPools, &pool)
+		}
+		// Links between offerings point at the same term's copies
+		for _, id := range c.CoRequisites {
+			if linked := findCourse(id); linked != nil {
+				next.CoRequisites = append(next.CoRequisites, offeringID(linked.Code, to))
+			}
+		}
+		if owner := findCourse(c.CrossListOf); owner != nil {
+			next.CrossListOf = offeringID(owner.Code, to)
+			next.SeatPools = nil
+		}
+		added = append(added, next)
+	}
+	return added
+}
+
+// termsHandler lists terms (GET) or publishes a new one (POST, registrar or
+// admin) by copying an existing term's catalog.
+func termsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mu.RLock()
+		counts := make(map[string]int)
+		for _, c := range courses {
+			counts[c.Term]++
+		}
+		mu.RUnlock()
+
+		termMu.RLock()
+		list := []Term{}
+		for _, id := range termIDs {
+			list = append(list, Term{ID: id, Current: id == activeTerm, Courses: counts[id]})
+		}
+		termMu.RUnlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+			return
+		}
+		var req TermRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ID == "" || strings.ContainsAny(req.ID, "@:,") {
+			http.Error(w, "id is required and may not contain @, : or ,", http.StatusBadRequest)
+			return
+		}
+		if req.CopyFrom == "" {
+			req.CopyFrom = currentTerm()
+		}
+
+		mu.Lock()
+		if termExists(req.ID) {
+			mu.Unlock()
+			http.Error(w, "Term already exists", http.StatusConflict)
+			return
+		}
+		if !termExists(req.CopyFrom) {
+			mu.Unlock()
+			http.Error(w, "Term to copy from not found", http.StatusNotFound)
+			return
+		}
+		added := publishTerm(req.CopyFrom, req.ID)
+		for _, c := range added {
+			if err := seats.add(c); err != nil {
+				mu.Unlock()
+				http.Error(w, "Seat store unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		courses = append(courses, added...)
+		termMu.Lock()
+		termIDs = append(termIDs, req.ID)
+		termMu.Unlock()
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Term{ID: req.ID, Courses: len(added)})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// setCurrentTerm (PUT /terms/current) moves the default term forward once a
+// new term starts. Registrar or admin only.
+func setCurrentTerm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+	var req TermRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !termExists(req.ID) {
+		http.Error(w, "Term not found", http.StatusNotFound)
+		return
+	}
+
+	termMu.Lock()
+	activeTerm = req.ID
+	termMu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "current term updated"}`))
+}
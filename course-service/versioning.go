@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Optimistic Concurrency ---
+
+// Every admin edit to a course bumps Course.Version; seats taken and released
+// by enrollments do not, so an edit form is not made stale by every sale.
+// Admin updates must send the version they based their edit on as
+// If-Match: "<version>". If the course moved on in the meantime the update
+// is refused with 409 and the client re-reads before retrying. If-Match: *
+// would skip the check, so it is refused like a missing header.
+
+type CapacityRequest struct {
+	CourseID string `json:"course_id"`
+	Capacity int    `json:"capacity"`
+}
+
+// touch records that an admin edited a course. Callers must hold mu.
+func touch(c *Course) {
+	c.Version++
+}
+
+// checkVersion enforces If-Match on an update and writes the error response
+// when it fails. Callers must hold mu.
+func checkVersion(w http.ResponseWriter, r *http.Request, c *Course) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		http.Error(w, "If-Match header with the course version is required", http.StatusPreconditionRequired)
+		return false
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil {
+		http.Error(w, "If-Match must be a course version", http.StatusBadRequest)
+		return false
+	}
+	if version != c.Version {
+		w.Header().Set("ETag", versionTag(c))
+		http.Error(w, "Course was modified (now version "+strconv.Itoa(c.Version)+"); reload and retry", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func versionTag(c *Course) string {
+	return `"` + strconv.Itoa(c.Version) + `"`
+}
+
+// updateCapacity changes a course's physical room size. Seats already taken
+// are kept; the open count is recomputed from the new capacity.
+func updateCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+
+	var req CapacityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Capacity < 0 {
+		http.Error(w, "capacity cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c := findCourse(req.CourseID)
+	if c == nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !checkVersion(w, r, c) {
+		return
+	}
+	if c.CrossListOf != "" {
+		http.Error(w, "Seats of a cross-listed course are managed on "+c.CrossListOf, http.StatusConflict)
+		return
+	}
+	occupied := occupiedSeats(c)
+	old := c.Capacity
+	c.Capacity = req.Capacity
+	if sellableSeats(c) < occupied {
+		c.Capacity = old
+		http.Error(w, "Capacity would leave fewer seats than students already placed", http.StatusConflict)
+		return
+	}
+	// Bumped before the seat store answers, so an edit racing this one
+	// fails its If-Match
+	touch(c)
+	if err := seats.resize(c, sellableSeats(c)-occupied-c.OpenSlots); err != nil {
+		c.Capacity = old
+		if err == errSeatStore {
+			http.Error(w, "Seat store unavailable", http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, "Seats were taken meanwhile; reload and retry", http.StatusConflict)
+		}
+		return
+	}
+	seatsChanged(c)
+	promoteWaitlists(time.Now())
+
+	w.Header().Set("ETag", versionTag(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "capacity updated"}`))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"shared/config"
+	"shared/studentid"
+)
+
+// --- Waitlists ---
+
+type WaitlistEntry struct {
+	StudentID string          `json:"student_id"`
+	JoinedAt  time.Time       `json:"joined_at"`
+	profile   *StudentProfile // Cohort claims captured at join time, for seat pools
+}
+
+type WaitlistStatus struct {
+	CourseID string `json:"course_id"`
+	Position int    `json:"position"` // 1-based; 0 when not on the list
+	Length   int    `json:"length"`
+}
+
+var waitlists = make(map[string][]*WaitlistEntry) // Key: CourseID, guarded by mu
+
+// waitlistOfferTTL is how long a promoted student has to accept the seat.
+func waitlistOfferTTL() time.Duration {
+	return time.Duration(config.Int("WAITLIST_OFFER_TTL_SECONDS")) * time.Second
+}
+
+// waitlistPosition returns a student's 1-based position, or 0. Callers must
+// hold mu exclusively, or enrollMu.
+func waitlistPosition(courseID, studentID string) int {
+	for i, e := range waitlists[courseID] {
+		if e.StudentID == studentID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// removeFromWaitlist drops a student from a course's list. Callers must hold
+// mu exclusively, or enrollMu.
+func removeFromWaitlist(courseID, studentID string) bool {
+	list := waitlists[courseID]
+	for i, e := range list {
+		if e.StudentID == studentID {
+			waitlists[courseID] = append(list[:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// waitlistHandler reports a student's position (GET) or joins the list (POST).
+func waitlistHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		courseID := r.URL.Query().Get("course_id")
+		studentID := r.URL.Query().Get("student_id")
+
+		mu.Lock()
+		if findCourse(courseID) == nil {
+			mu.Unlock()
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		status := WaitlistStatus{CourseID: courseID, Position: waitlistPosition(courseID, studentID), Length: len(waitlists[courseID])}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+
+	case http.MethodPost:
+		var req EnrollRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		profile, ok := enrollingProfile(w, r, req.StudentID)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		c := findCourse(req.CourseID)
+		if c == nil {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		if isArchived(c) || isArchived(seatOwner(c)) {
+			http.Error(w, "Course is archived", http.StatusGone)
+			return
+		}
+		if enrolledInClass(c, req.StudentID) || holdsClass(c, req.StudentID) {
+			http.Error(w, "Student already enrolled", http.StatusConflict)
+			return
+		}
+		if waitlistPosition(c.ID, req.StudentID) > 0 {
+			http.Error(w, "Student already waitlisted", http.StatusConflict)
+			return
+		}
+		if seatsFor(c, profile) > 0 {
+			http.Error(w, "Course has open seats; enroll instead", http.StatusConflict)
+			return
+		}
+
+		waitlists[c.ID] = append(waitlists[c.ID], &WaitlistEntry{StudentID: req.StudentID, JoinedAt: time.Now(), profile: profile})
+		status := WaitlistStatus{CourseID: c.ID, Position: len(waitlists[c.ID]), Length: len(waitlists[c.ID])}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(status)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// leaveWaitlist takes a student off a course's waitlist: the student
+// themselves, or the registrar or an admin on their behalf.
+func leaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student", "registrar", "admin")
+	if !ok {
+		return
+	}
+
+	var req EnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := studentid.Validate(studentid.Tenant(r), req.StudentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if user.Role == "student" && req.StudentID != user.Username {
+		http.Error(w, "Forbidden: Students can only leave their own waitlists", http.StatusForbidden)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !removeFromWaitlist(req.CourseID, req.StudentID) {
+		http.Error(w, "Student not waitlisted", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "left waitlist"}`))
+}
+
+// promoteWaitlists offers every free seat to the first student on its
+// course's waitlist who can take it, as a seat hold the student must confirm
+// before the deadline. Students the free seats are reserved away from keep
+// their place; entries that can no longer be admitted are skipped. Callers
+// must hold mu.
+func promoteWaitlists(now time.Time) {
+	for _, c := range courses {
+		list := waitlists[c.ID]
+		if len(list) == 0 || c.OpenSlots <= 0 {
+			continue
+		}
+		var kept []*WaitlistEntry
+		for i, e := range list {
+			if seatsFor(c, e.profile) == 0 {
+				kept = append(kept, e)
+				continue
+			}
+			if hold := activeRegistrationHold(e.StudentID); hold != nil || exceedsCreditLimit(e.StudentID, c.Term, c.Credits) ||
+				len(missingCoRequisites(c, e.StudentID, nil)) > 0 {
+				emit(DomainEvent{Type: "WaitlistSkipped", StudentID: e.StudentID, CourseID: c.ID})
+				continue
+			}
+			pool, err := takeSeat(c, e.profile, "")
+			if err != nil {
+				// Taken by another replica, or the store is down; try again later
+				kept = append(kept, list[i:]...)
+				break
+			}
+
+			h := &SeatHold{ID: newHoldID(), CourseID: c.ID, StudentID: e.StudentID, ExpiresAt: now.Add(waitlistOfferTTL()), Pool: pool}
+			holds[h.ID] = h
+			emit(DomainEvent{
+				Type:      "WaitlistPromoted",
+				StudentID: e.StudentID,
+				CourseID:  c.ID,
+				Data:      map[string]string{"hold_id": h.ID, "accept_by": h.ExpiresAt.Format(time.RFC3339)},
+			})
+			notify(e.StudentID, Notification{Type: "waitlist_offer", CourseID: c.ID, HoldID: h.ID,
+				Message: "A seat in " + c.ID + " opened up for you. Accept it by " + h.ExpiresAt.Format("Jan 2, 15:04 MST") + " or it goes to the next student."})
+		}
+		waitlists[c.ID] = kept
+	}
+}
+
+// runWaitlistWorker promotes waitlisted students whenever seats free up.
+func runWaitlistWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		mu.Lock()
+		promoteWaitlists(now)
+		mu.Unlock()
+	}
+}
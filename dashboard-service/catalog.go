@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/httpjson"
+	"shared/models"
+)
+
+// --- Course Catalog Cache ---
+
+// Titles, credits and class times come from the Course Service's public
+// catalog of every term, cached for catalogTTL and expired at once by the
+// catalog events it publishes. A stale cache is still used if a refresh
+// fails, and without any catalog courses are served by ID alone.
+
+const catalogTTL = 5 * time.Minute
+
+var (
+	catalogMu      sync.Mutex
+	catalogByID    map[string]models.Course // Offering ID -> course
+	catalogFetched time.Time
+)
+
+func fetchTerms() ([]string, error) {
+	var terms []struct {
+		ID string `json:"id"`
+	}
+	if err := httpjson.Get(courseServiceURL()+"/terms", "", &terms); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(terms))
+	for i, t := range terms {
+		ids[i] = t.ID
+	}
+	return ids, nil
+}
+
+// courseCatalog returns every known course, keyed by offering ID.
+func courseCatalog() map[string]models.Course {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if catalogByID != nil && time.Since(catalogFetched) < catalogTTL {
+		return catalogByID
+	}
+	fresh, err := fetchCourseCatalog()
+	if err != nil {
+		slog.Warn("course catalog: refresh failed", "err", err, "cached", catalogByID != nil)
+		return catalogByID
+	}
+	catalogByID, catalogFetched = fresh, time.Now()
+	return catalogByID
+}
+
+// expireCatalog makes the next read refetch the catalog.
+func expireCatalog() {
+	catalogMu.Lock()
+	catalogFetched = time.Time{}
+	catalogMu.Unlock()
+}
+
+func fetchCourseCatalog() (map[string]models.Course, error) {
+	terms, err := fetchTerms()
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]models.Course)
+	for _, term := range terms {
+		var list []models.Course
+		if err := httpjson.Get(courseServiceURL()+"/courses?include_archived=true&term="+url.QueryEscape(term), "", &list); err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			catalog[c.ID] = c
+		}
+	}
+	return catalog, nil
+}
+
+// sortCourses orders a dashboard's courses by term, then ID.
+func sortCourses(list []DashboardCourse) {
+	slices.SortFunc(list, func(a, b DashboardCourse) int {
+		if n := strings.Compare(a.Term, b.Term); n != 0 {
+			return n
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"slices"
+
+	"shared/auth"
+	"shared/config"
+	"shared/discovery"
+	"shared/events"
+	"shared/logging"
+	"shared/mtls"
+	"shared/server"
+)
+
+// --- Configuration ---
+
+// settings are everything the Dashboard Service can be configured with. Run
+// it with dump-config to see the values it would use.
+var settings = slices.Concat(logging.Settings, server.Settings, mtls.Settings, events.Settings, auth.Settings, []config.Setting{
+	{Name: "PORT", Default: "8084", Usage: "Port to listen on", Check: config.IntBetween(1, 65535)},
+	{Name: "COURSE_SERVICE_URL", Default: "http://node_course:8082", Usage: "Where the Course Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "GRADE_SERVICE_URL", Default: "http://node_grade:8083", Usage: "Where the Grade Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "DASHBOARD_MAX_AGE", Default: "5m", Usage: "How long a dashboard kept up to date by events is trusted before it is rebuilt", Check: config.ValidDuration},
+})
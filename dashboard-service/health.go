@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+
+	"shared/auth"
+	"shared/events"
+	"shared/health"
+)
+
+// --- Readiness ---
+
+// readyz reports "down" when the Auth Service or the Course Service is
+// unreachable, since no dashboard can be read or built without them. The
+// Grade Service is soft, as dashboards are still served with the grades they
+// have, and so is the event broker, since without it every read rebuilds.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	deps := map[string]health.DependencyStatus{
+		"auth":   health.Probe(auth.ServiceURL()+"/readyz", true),
+		"course": health.Probe(courseServiceURL()+"/readyz", true),
+		"grade":  health.Probe(gradeServiceURL()+"/readyz", false),
+	}
+	if broker := events.Broker(); broker != "" {
+		deps[broker] = health.Check(false, events.Ping)
+	}
+	health.Write(w, health.NewReport(deps))
+}
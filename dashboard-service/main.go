@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+
+	"shared/auth"
+	"shared/config"
+	"shared/events"
+	"shared/health"
+	"shared/logging"
+	"shared/metrics"
+	"shared/mtls"
+	"shared/server"
+	"shared/tracing"
+)
+
+// dashboardHandler (GET /dashboard?student_id=) serves a student's whole
+// dashboard in one call. Students read their own, and student_id defaults
+// to them; the registrar and admins read anyone's.
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := auth.RequireRole(w, r, "student", "registrar", "admin")
+	if !ok {
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	if studentID == "" && user.Role == "student" {
+		studentID = user.Username
+	}
+	if studentID == "" {
+		http.Error(w, "student_id is required", http.StatusBadRequest)
+		return
+	}
+	if user.Role == "student" && studentID != user.Username {
+		http.Error(w, "Forbidden: You cannot view another student's dashboard", http.StatusForbidden)
+		return
+	}
+
+	token, _ := auth.BearerToken(r)
+	d, err := dashboard(r.Context(), studentID, token, user.Username == studentID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "dashboard: cannot build", "student_id", studentID, "err", err)
+		http.Error(w, "Course Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+func main() {
+	config.Load("dashboard", settings...)
+	logging.Setup("dashboard")
+	mtls.Setup()
+	tracing.Setup("dashboard")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dashboard", dashboardHandler)
+	mux.HandleFunc("/healthz", health.Liveness)
+	mux.HandleFunc("/readyz", readyz)
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Nothing is published; the outbox only satisfies the relay
+	events.Start("dashboard-service", &events.MemoryOutbox{})
+	subscribe()
+
+	server.OnShutdown("events", events.Flush)
+	server.OnShutdown("tracing", tracing.Shutdown)
+
+	port := config.Get("PORT")
+	slog.Info("Node 5 (Dashboard Service) running", "port", port)
+	srv := &http.Server{Addr: "0.0.0.0:" + port, Handler: tracing.Middleware(logging.Middleware(metrics.Middleware(mtls.Middleware(mux))))}
+	if err := mtls.Run(srv); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"shared/config"
+	"shared/discovery"
+	"shared/events"
+	"shared/httpjson"
+	"shared/models"
+)
+
+// --- Read Model ---
+
+// Each student's dashboard is kept here as one document, so the portal gets
+// in one call what it would otherwise ask the Course and Grade Services for
+// on every page load. A document is built the first time it is read, from
+// the Course Service's course lists and the Grade Service's grades, fetched
+// with the reader's token. After that the enrollment events keep its courses
+// current without asking anyone. Grade events do not carry the grade, so a
+// GradePosted only marks the grades stale, and they are fetched again on the
+// student's next read.
+//
+// Events can be missed while the broker is down, so a document is rebuilt
+// once it is older than DASHBOARD_MAX_AGE. Without a broker nothing keeps
+// documents current and every read rebuilds.
+
+type studentDoc struct {
+	courses     map[string]time.Time // Offering ID -> when the student enrolled, zero if before the document was built
+	grades      []models.GradeRecord
+	gradesStale bool
+	version     int64 // Events applied since the document was built
+	builtAt     time.Time
+	updatedAt   time.Time
+
+	building bool
+	pending  []events.Event // Arrived while building, applied once built
+}
+
+var (
+	docsMu sync.Mutex
+	docs   = make(map[string]*studentDoc) // Key: student
+
+	seenMu  sync.Mutex
+	seen    = make(map[string]bool) // Recent event IDs, to drop repeats
+	seenLog []string                // The same IDs, oldest first
+)
+
+const maxSeenEvents = 10000
+
+var dashboardReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "dashboard_reads_total",
+	Help: "Dashboards served from the read model (hit) or rebuilt first (build).",
+}, []string{"result"})
+
+func courseServiceURL() string {
+	return discovery.Peer("COURSE_SERVICE_URL").URL()
+}
+
+func gradeServiceURL() string {
+	return discovery.Peer("GRADE_SERVICE_URL").URL()
+}
+
+// subscribe keeps the documents current from the event bus.
+func subscribe() {
+	events.Subscribe("enrollment.*", applyEvent)
+	events.Subscribe("grades.*", applyEvent)
+	events.Subscribe("catalog.*", func(ev events.Event) {
+		expireCatalog()
+		slog.Info("course catalog: changed, refetching", "type", ev.Type, "course_id", ev.CourseID)
+	})
+}
+
+// firstSighting records an event ID, reporting whether it is new. Delivery
+// is at least once, so the same event can arrive twice.
+func firstSighting(id string) bool {
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	if seen[id] {
+		return false
+	}
+	seen[id] = true
+	seenLog = append(seenLog, id)
+	if len(seenLog) > maxSeenEvents {
+		delete(seen, seenLog[0])
+		seenLog = seenLog[1:]
+	}
+	return true
+}
+
+// applyEvent folds an enrollment or grade event into its student's
+// document. Students without a document are skipped: theirs will be built
+// from the services when first read.
+func applyEvent(ev events.Event) {
+	if ev.StudentID == "" || !firstSighting(ev.ID) {
+		return
+	}
+	docsMu.Lock()
+	defer docsMu.Unlock()
+	d, ok := docs[ev.StudentID]
+	if !ok {
+		return
+	}
+	if d.building {
+		d.pending = append(d.pending, ev)
+		return
+	}
+	d.apply(ev)
+}
+
+// apply changes the document as ev says. Callers must hold docsMu.
+func (d *studentDoc) apply(ev events.Event) {
+	switch ev.Type {
+	case "EnrollmentCreated":
+		d.courses[ev.CourseID] = ev.At
+	case "EnrollmentDropped":
+		delete(d.courses, ev.CourseID)
+	case "GradePosted":
+		d.gradesStale = true
+	default:
+		return
+	}
+	d.version++
+	d.updatedAt = time.Now()
+}
+
+// Dashboard is one student's dashboard, as served at /dashboard.
+type Dashboard struct {
+	StudentID     string               `json:"student_id"`
+	Courses       []DashboardCourse    `json:"courses"`
+	CreditsByTerm map[string]int       `json:"credits_by_term"`
+	Grades        []models.GradeRecord `json:"grades"`
+	GradesStale   bool                 `json:"grades_stale,omitempty"` // Grades may be missing; the student's next read fetches them
+	Version       int64                `json:"version"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// DashboardCourse is an enrolled course with its catalog details.
+type DashboardCourse struct {
+	ID         string           `json:"id"`
+	Code       string           `json:"code,omitempty"`
+	Term       string           `json:"term,omitempty"`
+	Title      string           `json:"title,omitempty"`
+	Credits    int              `json:"credits"`
+	Instructor string           `json:"instructor,omitempty"`
+	Meetings   []models.Meeting `json:"meetings,omitempty"`
+	EnrolledAt *time.Time       `json:"enrolled_at,omitempty"`
+}
+
+// dashboard returns a student's document, building it first if there is
+// none or it is too old. token is the reader's; own says whether the reader
+// is the student, whose token alone refreshes the grades, so that the
+// document only ever holds what the student may see.
+func dashboard(ctx context.Context, studentID, token string, own bool) (*Dashboard, error) {
+	maxAge := config.Duration("DASHBOARD_MAX_AGE")
+	if events.Broker() == "" {
+		maxAge = 0
+	}
+
+	docsMu.Lock()
+	d, ok := docs[studentID]
+	fresh := ok && !d.building && time.Since(d.builtAt) < maxAge
+	docsMu.Unlock()
+	if fresh {
+		dashboardReads.WithLabelValues("hit").Inc()
+	} else {
+		dashboardReads.WithLabelValues("build").Inc()
+		var err error
+		if d, err = build(ctx, studentID, token, own); err != nil {
+			return nil, err
+		}
+	}
+
+	docsMu.Lock()
+	stale := d.gradesStale
+	docsMu.Unlock()
+	if stale && own {
+		refreshGrades(ctx, d, studentID, token)
+	}
+	return render(d, studentID), nil
+}
+
+// build fetches a student's courses and grades from the services and
+// replaces their document. Events that arrive meanwhile are applied on top.
+func build(ctx context.Context, studentID, token string, own bool) (*studentDoc, error) {
+	d := &studentDoc{courses: make(map[string]time.Time), building: true}
+	docsMu.Lock()
+	old := docs[studentID]
+	docs[studentID] = d
+	docsMu.Unlock()
+
+	enrolled, err := fetchEnrolled(ctx, studentID, token)
+	var grades []models.GradeRecord
+	var gradeErr error
+	if own {
+		gradeErr = getJSON(ctx, gradeServiceURL()+"/grades?student_id="+url.QueryEscape(studentID), token, &grades)
+	}
+
+	docsMu.Lock()
+	defer docsMu.Unlock()
+	if err != nil {
+		// Put back what there was; the next read tries again
+		if old != nil {
+			docs[studentID] = old
+		} else {
+			delete(docs, studentID)
+		}
+		return nil, err
+	}
+	for _, id := range enrolled {
+		d.courses[id] = time.Time{}
+	}
+	switch {
+	case own && gradeErr == nil:
+		d.grades = grades
+	case old != nil:
+		d.grades, d.gradesStale = old.grades, old.gradesStale || gradeErr != nil
+	default:
+		// Not fetched yet, or the Grade Service did not answer
+		d.gradesStale = true
+	}
+	if gradeErr != nil {
+		slog.WarnContext(ctx, "dashboard: cannot fetch grades", "student_id", studentID, "err", gradeErr)
+	}
+	d.builtAt, d.updatedAt, d.building = time.Now(), time.Now(), false
+	for _, ev := range d.pending {
+		d.apply(ev)
+	}
+	d.pending = nil
+	return d, nil
+}
+
+// fetchEnrolled lists the offerings a student is enrolled in, term by term.
+func fetchEnrolled(ctx context.Context, studentID, token string) ([]string, error) {
+	terms, err := fetchTerms()
+	if err != nil {
+		return nil, err
+	}
+	var enrolled []string
+	for _, term := range terms {
+		var list []models.Course
+		query := url.Values{"student_id": {studentID}, "term": {term}, "include_archived": {"true"}}
+		if err := getJSON(ctx, courseServiceURL()+"/courses?"+query.Encode(), token, &list); err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			if c.IsEnrolled {
+				enrolled = append(enrolled, c.ID)
+			}
+		}
+	}
+	return enrolled, nil
+}
+
+// getJSON GETs url as the token's owner, as part of the request ctx serves.
+func getJSON(ctx context.Context, url, token string, target interface{}) error {
+	req, err := httpjson.NewRequest(ctx, http.MethodGet, url, token, nil)
+	if err != nil {
+		return err
+	}
+	return httpjson.Do(httpjson.Client, req, target)
+}
+
+// refreshGrades fetches the student's grades after a GradePosted. On
+// failure the old grades are kept and still marked stale.
+func refreshGrades(ctx context.Context, d *studentDoc, studentID, token string) {
+	var grades []models.GradeRecord
+	if err := getJSON(ctx, gradeServiceURL()+"/grades?student_id="+url.QueryEscape(studentID), token, &grades); err != nil {
+		slog.WarnContext(ctx, "dashboard: cannot refresh grades", "student_id", studentID, "err", err)
+		return
+	}
+	docsMu.Lock()
+	d.grades, d.gradesStale, d.updatedAt = grades, false, time.Now()
+	docsMu.Unlock()
+}
+
+// render joins a document with the catalog.
+func render(d *studentDoc, studentID string) *Dashboard {
+	catalog := courseCatalog()
+
+	docsMu.Lock()
+	defer docsMu.Unlock()
+	out := &Dashboard{
+		StudentID:     studentID,
+		Courses:       []DashboardCourse{},
+		CreditsByTerm: make(map[string]int),
+		Grades:        d.grades,
+		GradesStale:   d.gradesStale,
+		Version:       d.version,
+		UpdatedAt:     d.updatedAt,
+	}
+	if out.Grades == nil {
+		out.Grades = []models.GradeRecord{}
+	}
+	for id, at := range d.courses {
+		dc := DashboardCourse{ID: id}
+		if c, ok := catalog[id]; ok {
+			dc.Code, dc.Term, dc.Title, dc.Credits, dc.Instructor, dc.Meetings = c.Code, c.Term, c.Title, c.Credits, c.Instructor, c.Meetings
+			out.CreditsByTerm[c.Term] += c.Credits
+		}
+		if !at.IsZero() {
+			dc.EnrolledAt = &at
+		}
+		out.Courses = append(out.Courses, dc)
+	}
+	sortCourses(out.Courses)
+	return out
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"shared/auth"
+)
+
+// Tokens are checked against the Auth Service by the shared auth package.
+
+type AuthResponse = auth.User
+
+func validateTokenAndGetUser(ctx context.Context, tokenString string) (*AuthResponse, bool) {
+	return auth.Validate(ctx, tokenString)
+}
+
+// requireRole authenticates the caller via the Auth Service and checks that
+// their role is one of the allowed roles. It writes the error response itself.
+func requireRole(w http.ResponseWriter, r *http.Request, allowed ...string) (*AuthResponse, bool) {
+	return auth.RequireRole(w, r, allowed...)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/studentid"
+)
+
+// --- Bulk Grade Upload ---
+
+// Faculty (or the registrar, as an override) upload a whole class at once as
+// CSV, either as a multipart "file" field or as a text/csv body. The header
+// row names the columns student_id, course_id and grade, plus optional term,
+// type and reason. Every row is checked the way a single upload is; valid
+// rows are saved in one transaction and invalid ones are reported. With dry_run=true
+// nothing is saved. With format=csv the response is the error report as a
+// CSV download, and the counts are in the X-Grades-Recorded and
+// X-Grades-Rejected headers.
+
+const maxUploadBytes = 5 << 20
+
+type UploadRowReport struct {
+	Row       int    `json:"row"` // Line number in the file, the header being line 1
+	StudentID string `json:"student_id"`
+	CourseID  string `json:"course_id"`
+	Term      string `json:"term"`
+	Type      string `json:"type"`
+	Grade     string `json:"grade"`
+	Status    string `json:"status"` // "recorded", "corrected", "unchanged", "valid" (dry run) or "rejected"
+	Error     string `json:"error,omitempty"`
+}
+
+type UploadReport struct {
+	DryRun   bool              `json:"dry_run"`
+	Recorded int               `json:"recorded"`
+	Rejected int               `json:"rejected"`
+	Rows     []UploadRowReport `json:"rows"`
+}
+
+// readUploadCSV returns the uploaded file's rows, header first.
+func readUploadCSV(r *http.Request) ([][]string, error) {
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+	}
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1 // Short rows are reported per row, not as a parse failure
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	return rows, nil
+}
+
+// uploadGradesCSV handles POST /upload-grades.
+func uploadGradesCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar")
+	if !ok {
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	rows, err := readUploadCSV(r)
+	if err != nil {
+		http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"student_id", "course_id", "grade"} {
+		if _, ok := columns[required]; !ok {
+			http.Error(w, "CSV header is missing the "+required+" column", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One lookup for the whole file rather than one per row
+	gradable, err := gradableCourses(user, token)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	tenant := studentid.Tenant(r)
+	now := time.Now()
+	report := UploadReport{DryRun: r.URL.Query().Get("dry_run") == "true", Rows: []UploadRowReport{}}
+	seen := make(map[string]int) // student/course/term/type -> line, to catch duplicates in one file
+	rosters := make(map[string]Roster)
+	var accepted []GradeUpload
+
+	for i, row := range rows[1:] {
+		field := func(name string) string {
+			if col, ok := columns[name]; ok && col < len(row) {
+				return strings.TrimSpace(row[col])
+			}
+			return ""
+		}
+		line := UploadRowReport{Row: i + 2, StudentID: field("student_id"), CourseID: field("course_id"), Term: field("term"), Grade: field("grade")}
+		if line.Term == "" {
+			line.Term = currentTerm()
+		}
+		reason := field("reason")
+		gradeType, typeErr := normalizeGradeType(strings.ToLower(field("type")))
+		line.Type = gradeType
+		gradeErr := checkGrade(line.Grade, line.Type)
+		closed := ""
+		if user.Role == "faculty" {
+			closed = submissionClosed(line.Term, now)
+		}
+		key := line.StudentID + "/" + line.CourseID + "/" + line.Term + "/" + line.Type
+
+		previous, exists, err := currentGrade(line.StudentID, line.CourseID, line.Term, line.Type)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: read", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		idErr := studentid.Validate(tenant, line.StudentID)
+		switch {
+		case idErr != nil:
+			line.Error = idErr.Error()
+		case line.CourseID == "" || line.Grade == "":
+			line.Error = "course_id and grade are required"
+		case typeErr != nil:
+			line.Type = field("type")
+			line.Error = typeErr.Error()
+		case gradeErr != nil:
+			line.Error = gradeErr.Error()
+		case !gradable[line.CourseID] && user.Role == "registrar":
+			line.Error = "course " + line.CourseID + " not found"
+		case !gradable[line.CourseID]:
+			line.Error = "you do not teach " + line.CourseID
+		case user.Role == "registrar" && reason == "":
+			line.Error = "a reason is required for a registrar override"
+		case closed != "":
+			line.Error = closed
+		case seen[key] != 0:
+			line.Error = "duplicate of row " + strconv.Itoa(seen[key])
+		case exists && previous != line.Grade && reason == "":
+			line.Error = "a reason is required to change the existing grade " + previous
+		}
+		if line.Error == "" {
+			roster, ok := rosters[line.CourseID]
+			if !ok {
+				if roster, err = courseRoster(token, line.CourseID); err != nil {
+					http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+					return
+				}
+				rosters[line.CourseID] = roster
+			}
+			line.Error = enrollmentProblem(roster, line.StudentID, line.Term)
+		}
+		seen[key] = line.Row
+
+		switch {
+		case line.Error != "":
+			line.Status = "rejected"
+			report.Rejected++
+		case exists && previous == line.Grade:
+			line.Status = "unchanged"
+		case report.DryRun:
+			line.Status = "valid"
+			report.Recorded++
+		default:
+			line.Status = "recorded"
+			if exists {
+				line.Status = "corrected"
+			}
+			report.Recorded++
+			accepted = append(accepted, GradeUpload{
+				GradeRecord: GradeRecord{StudentID: line.StudentID, CourseID: line.CourseID, Grade: line.Grade, Term: line.Term, Type: line.Type},
+				Reason:      reason,
+			})
+		}
+		report.Rows = append(report.Rows, line)
+	}
+
+	changes, err := grades.Save(user.Username, announceSaved(), accepted...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: bulk upload", "err", err)
+		http.Error(w, "Grade store unavailable; nothing was recorded", http.StatusServiceUnavailable)
+		return
+	}
+	notifySaved(changes)
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeUploadErrors(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeUploadErrors sends the rejected rows as a CSV download.
+func writeUploadErrors(w http.ResponseWriter, report UploadReport) {
+	var buf bytes.Buffer
+	out := csv.NewWriter(&buf)
+	out.Write([]string{"row", "student_id", "course_id", "term", "type", "grade", "error"})
+	for _, line := range report.Rows {
+		if line.Status == "rejected" {
+			out.Write([]string{strconv.Itoa(line.Row), line.StudentID, line.CourseID, line.Term, line.Type, line.Grade, line.Error})
+		}
+	}
+	out.Flush()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="grade-upload-errors.csv"`)
+	w.Header().Set("X-Grades-Recorded", strconv.Itoa(report.Recorded))
+	w.Header().Set("X-Grades-Rejected", strconv.Itoa(report.Rejected))
+	w.Write(buf.Bytes())
+}
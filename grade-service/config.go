@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+
+	"shared/auth"
+	"shared/config"
+	"shared/discovery"
+	"shared/events"
+	"shared/idempotency"
+	"shared/logging"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+)
+
+// --- Configuration ---
+
+// settings are everything the Grade Service can be configured with. Run it
+// with dump-config to see the values it would use.
+var settings = slices.Concat(logging.Settings, server.Settings, mtls.Settings, studentid.Settings, events.Settings, auth.Settings, idempotency.Settings, []config.Setting{
+	{Name: "PORT", Default: "8083", Usage: "Port to listen on", Check: config.IntBetween(1, 65535)},
+	{Name: "FIXTURE_PATH", Flag: "fixture", Usage: "YAML file or directory of CSV files to load at startup"},
+	{Name: "COURSE_SERVICE_URL", Default: "http://node_course:8082", Usage: "Where the Course Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "CURRENT_TERM", Default: "2026-T1", Usage: "Term that grades are recorded in when none is given"},
+	{Name: "GRADE_SCALE", Default: "4.0", Usage: "Grade scale: 4.0 or letter", Check: config.OneOf("4.0", "letter")},
+	{Name: "REPEAT_POLICY", Default: repeatHighest, Usage: "Which attempts at a repeated course count: highest, latest or all", Check: config.OneOf(repeatHighest, repeatLatest, repeatAll)},
+	{Name: "INC_DEADLINE_DAYS", Default: "365", Usage: "Days before an INC grade lapses", Check: config.Positive},
+	{Name: "DEFAULT_COURSE_CREDITS", Default: "3", Usage: "Credits a course missing from the catalog counts for in the GPA", Check: config.Positive},
+	{Name: "STATS_MIN_COHORT_SIZE", Default: "5", Usage: "Smallest cohort whose grade statistics are shown; at least 2", Check: config.AtLeast(2)},
+	{Name: "GRADE_DB_PATH", Usage: "SQLite database the grades are kept in; unset keeps them in memory"},
+})
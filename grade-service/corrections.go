@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"shared/studentid"
+)
+
+// --- Grade Corrections ---
+
+// Uploading a grade for a student, course and term that already has one is a
+// correction: it replaces the value and must say why. Every change is kept
+// and can be read back with GET /grades/history.
+
+// GradeUpload is one grade to save, with the reason for a correction.
+type GradeUpload struct {
+	GradeRecord
+	Reason string `json:"reason"` // Required when changing an existing grade
+}
+
+// currentGrade returns the grade of a type recorded for a student, course
+// and term.
+func currentGrade(studentID, courseID, term, gradeType string) (string, bool, error) {
+	recorded, err := grades.ForStudent(studentID)
+	if err != nil {
+		return "", false, err
+	}
+	for _, rec := range recorded {
+		if rec.CourseID == courseID && rec.Term == term && rec.Type == gradeType {
+			return rec.Grade, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// gradeHistory (GET /grades/history?student_id=&course_id=[&term=&type=])
+// returns a grade's audit trail, oldest change first. Faculty, registrar or
+// admin only.
+func gradeHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "faculty", "registrar", "admin"); !ok {
+		return
+	}
+	q := r.URL.Query()
+	studentID, courseID, term := q.Get("student_id"), q.Get("course_id"), q.Get("term")
+	if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if courseID == "" {
+		http.Error(w, "Missing course_id parameter", http.StatusBadRequest)
+		return
+	}
+	if term == "" {
+		term = currentTerm()
+	}
+	gradeType, err := normalizeGradeType(q.Get("type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := grades.History(studentID, courseID, term, gradeType)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: history", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if changes == nil {
+		changes = []GradeChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
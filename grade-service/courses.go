@@ -0,0 +1,167 @@
+package main
+
+import (
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"shared/discovery"
+	"shared/events"
+	"shared/httpjson"
+	"shared/models"
+)
+
+// --- Course Service Client ---
+
+type Course struct {
+	models.Course
+	Chair string `json:"-"` // Chair of the department, from /departments
+}
+
+func courseServiceURL() string {
+	return discovery.Peer("COURSE_SERVICE_URL").URL()
+}
+
+// gradableCourses returns the IDs of the courses a user may grade. Faculty
+// may grade the courses they are assigned to teach; the registrar may grade
+// any course in the catalog, as an override.
+func gradableCourses(user *AuthResponse, tokenString string) (map[string]bool, error) {
+	if user.Role != "registrar" {
+		return taughtCourses(tokenString)
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]bool)
+	for id := range catalog {
+		all[id] = true
+	}
+	return all, nil
+}
+
+// taughtCourses returns the IDs of the courses the token's owner teaches.
+func taughtCourses(tokenString string) (map[string]bool, error) {
+	var list []Course
+	if err := httpjson.Get(courseServiceURL()+"/my-courses", tokenString, &list); err != nil {
+		return nil, err
+	}
+	taught := make(map[string]bool)
+	for _, c := range list {
+		taught[c.ID] = true
+	}
+	return taught, nil
+}
+
+// Roster is a course's current enrollment, as the Course Service reports it.
+type Roster struct {
+	CourseID string   `json:"course_id"`
+	Term     string   `json:"term"`
+	Students []string `json:"students"`
+}
+
+// courseRoster fetches a course's roster with the caller's token, which the
+// Course Service only honours for the instructor, the registrar and admins.
+func courseRoster(tokenString, courseID string) (Roster, error) {
+	var roster Roster
+	err := httpjson.Get(courseServiceURL()+"/roster?course_id="+url.QueryEscape(courseID), tokenString, &roster)
+	return roster, err
+}
+
+// enrollmentProblem explains why a student cannot be graded in a course and
+// term, or returns "" if they are enrolled in it.
+func enrollmentProblem(roster Roster, studentID, term string) string {
+	if roster.Term != "" && roster.Term != term {
+		return roster.CourseID + " is offered in " + roster.Term + ", not " + term
+	}
+	for _, s := range roster.Students {
+		if s == studentID {
+			return ""
+		}
+	}
+	return studentID + " is not enrolled in " + roster.CourseID
+}
+
+// --- Course Catalog Cache ---
+
+// Titles, credits, instructors and department chairs come from the Course
+// Service catalog of every term and are cached for catalogTTL, since they
+// rarely change and GPA or transcript reads should not cost one catalog fetch
+// each. Catalog edits the Course Service announces on the event bus expire
+// the cache at once. A stale cache is still used if a refresh fails.
+
+const catalogTTL = 5 * time.Minute
+
+var (
+	catalogMu      sync.Mutex
+	catalogByID    map[string]Course // Offering ID and catalog code -> course
+	catalogFetched time.Time
+)
+
+// courseCatalog returns every known course, keyed by both offering ID
+// (CODE@TERM) and catalog code.
+func courseCatalog() (map[string]Course, error) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if catalogByID != nil && time.Since(catalogFetched) < catalogTTL {
+		return catalogByID, nil
+	}
+	fresh, err := fetchCourseCatalog()
+	if err != nil {
+		if catalogByID != nil {
+			slog.Warn("course catalog: refresh failed, using cache", "err", err)
+			return catalogByID, nil
+		}
+		return nil, err
+	}
+	catalogByID, catalogFetched = fresh, time.Now()
+	return catalogByID, nil
+}
+
+// watchCatalog expires the catalog cache whenever the Course Service
+// announces a catalog edit.
+func watchCatalog() {
+	events.Subscribe("catalog.*", func(ev events.Event) {
+		catalogMu.Lock()
+		catalogFetched = time.Time{}
+		catalogMu.Unlock()
+		slog.Info("course catalog: changed, refetching", "type", ev.Type, "course_id", ev.CourseID)
+	})
+}
+
+func fetchCourseCatalog() (map[string]Course, error) {
+	var terms []struct {
+		ID string `json:"id"`
+	}
+	if err := httpjson.Get(courseServiceURL()+"/terms", "", &terms); err != nil {
+		return nil, err
+	}
+	var departments []struct {
+		ID    string `json:"id"`
+		Chair string `json:"chair"`
+	}
+	if err := httpjson.Get(courseServiceURL()+"/departments", "", &departments); err != nil {
+		return nil, err
+	}
+	chairs := make(map[string]string)
+	for _, d := range departments {
+		chairs[d.ID] = d.Chair
+	}
+
+	catalog := make(map[string]Course)
+	for _, t := range terms {
+		var list []Course
+		if err := httpjson.Get(courseServiceURL()+"/courses?include_archived=true&term="+url.QueryEscape(t.ID), "", &list); err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			c.Chair = chairs[c.DepartmentID]
+			catalog[c.ID] = c
+			if c.Code != "" {
+				catalog[c.Code] = c
+			}
+		}
+	}
+	return catalog, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// --- Degree Audit ---
+
+// Each program lists the requirements a student must complete to graduate
+// and the total credits it takes. A requirement names one or more catalog
+// codes; passing any of them completes it, so an elective slot lists its
+// choices. GET /degree-audit checks a student's final grades against their
+// program: students see only released grades, as on /gpa. The registrar
+// keeps the definitions at /programs/{id}. GET /completed-courses lists the
+// codes a student has passed, by released grades only, for the Course
+// Service's prerequisite checks.
+
+type Requirement struct {
+	Name    string   `json:"name"`
+	Courses []string `json:"courses"` // Catalog codes; any one completes it
+}
+
+type Program struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	TotalCredits int           `json:"total_credits"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+type RequirementStatus struct {
+	Requirement
+	Done        bool   `json:"done"`
+	CompletedBy string `json:"completed_by,omitempty"` // The course that completed it
+	Grade       string `json:"grade,omitempty"`
+	Term        string `json:"term,omitempty"`
+}
+
+type CompletedCourses struct {
+	StudentID string   `json:"student_id"`
+	Courses   []string `json:"courses"` // Catalog codes, sorted
+}
+
+type DegreeAudit struct {
+	StudentID        string              `json:"student_id"`
+	Program          string              `json:"program"`
+	ProgramName      string              `json:"program_name"`
+	TotalCredits     int                 `json:"total_credits"`
+	EarnedCredits    int                 `json:"earned_credits"`
+	CreditsRemaining int                 `json:"credits_remaining"`
+	Requirements     []RequirementStatus `json:"requirements"`
+}
+
+var (
+	programsMu sync.Mutex
+	programs   = map[string]Program{
+		"BSCS": {ID: "BSCS", Name: "BS Computer Science", TotalCredits: 140, Requirements: []Requirement{
+			{Name: "Introduction to Programming", Courses: []string{"CCPROG1"}},
+			{Name: "Structured Programming", Courses: []string{"CCPROG2"}},
+			{Name: "Calculus", Courses: []string{"CSMATH1", "MTH101A"}},
+			{Name: "Algorithms and Complexity", Courses: []string{"CSALGCM"}},
+			{Name: "Computer Architecture", Courses: []string{"CSARCH1"}},
+			{Name: "Distributed Computing", Courses: []string{"STDISCM"}},
+			{Name: "Distributed Computing Laboratory", Courses: []string{"STDISCL"}},
+		}},
+		"BSIT": {ID: "BSIT", Name: "BS Information Technology", TotalCredits: 130, Requirements: []Requirement{
+			{Name: "Introduction to Programming", Courses: []string{"CCPROG1"}},
+			{Name: "Structured Programming", Courses: []string{"CCPROG2"}},
+			{Name: "Mathematics", Courses: []string{"MTH101A", "CSMATH1"}},
+			{Name: "Computer Architecture", Courses: []string{"CSARCH1"}},
+			{Name: "Humanities Elective", Courses: []string{"LOGPHIL"}},
+		}},
+	}
+)
+
+// passed reports whether a final grade completes a course.
+func passed(grade string) bool {
+	if code, ok := gradeCode(grade); ok {
+		return code.EarnsCredit
+	}
+	points, ok := gradePoints(grade)
+	return ok && points >= passingPoints
+}
+
+// auditDegree checks a student's grades against a program. A requirement
+// is completed by the first passed course it lists.
+func auditDegree(studentID string, program Program, records []GradeRecord, catalog map[string]Course) DegreeAudit {
+	audit := DegreeAudit{StudentID: studentID, Program: program.ID, ProgramName: program.Name, TotalCredits: program.TotalCredits,
+		Requirements: []RequirementStatus{}}
+	audit.EarnedCredits = computeGPA(studentID, records, catalog).Cumulative.EarnedCredits
+	audit.CreditsRemaining = max(program.TotalCredits-audit.EarnedCredits, 0)
+
+	completed := make(map[string]GradeRecord) // Course code -> passing grade
+	for _, rec := range latestPerCourse(finalsOnly(records)) {
+		if passed(rec.Grade) {
+			completed[courseCode(rec.CourseID)] = rec
+		}
+	}
+	for _, req := range program.Requirements {
+		status := RequirementStatus{Requirement: req}
+		for _, code := range req.Courses {
+			if rec, ok := completed[code]; ok {
+				status.Done, status.CompletedBy, status.Grade, status.Term = true, code, rec.Grade, rec.Term
+				break
+			}
+		}
+		audit.Requirements = append(audit.Requirements, status)
+	}
+	return audit
+}
+
+// degreeAuditHandler (GET /degree-audit?student_id=&program=) audits a
+// student against a program's requirements.
+func degreeAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	records, ok := visibleGrades(w, r, studentID)
+	if !ok {
+		return
+	}
+	programsMu.Lock()
+	program, ok := programs[strings.ToUpper(r.URL.Query().Get("program"))]
+	programsMu.Unlock()
+	if !ok {
+		http.Error(w, "No requirements defined for this program", http.StatusNotFound)
+		return
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditDegree(studentID, program, records, catalog))
+}
+
+// completedCourses returns the catalog codes of the courses a student has
+// passed, by the latest final grade in each course and term.
+func completedCourses(records []GradeRecord) []string {
+	codes := []string{}
+	for _, rec := range latestPerCourse(finalsOnly(records)) {
+		if code := courseCode(rec.CourseID); passed(rec.Grade) && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// completedCoursesHandler (GET /completed-courses?student_id=) lists the
+// courses a student has completed. Unreleased grades never count, whoever
+// asks, so staff enrolling a student get the answer the student would.
+func completedCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	records, ok := visibleGrades(w, r, studentID)
+	if !ok {
+		return
+	}
+	released, err := releasedOnly(records)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: completed courses", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CompletedCourses{StudentID: studentID, Courses: completedCourses(released)})
+}
+
+// programHandler shows (GET) or replaces (PUT, registrar) a program's
+// requirements.
+func programHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.ToUpper(r.PathValue("id"))
+	switch r.Method {
+	case http.MethodGet:
+		programsMu.Lock()
+		program, ok := programs[id]
+		programsMu.Unlock()
+		if !ok {
+			http.Error(w, "Program not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(program)
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar"); !ok {
+			return
+		}
+		var program Program
+		if err := json.NewDecoder(r.Body).Decode(&program); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if program.TotalCredits <= 0 {
+			http.Error(w, "total_credits must be positive", http.StatusBadRequest)
+			return
+		}
+		for _, req := range program.Requirements {
+			if req.Name == "" || len(req.Courses) == 0 {
+				http.Error(w, "every requirement needs a name and at least one course", http.StatusBadRequest)
+				return
+			}
+		}
+		program.ID = id
+		programsMu.Lock()
+		programs[id] = program
+		programsMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "program updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"shared/config"
+)
+
+// --- Grade Release Embargo ---
+
+// TermRelease hides a term's grades from students until ReleaseAt. Faculty,
+// the registrar and admins always see them.
+type TermRelease struct {
+	Term      string    `json:"term"`
+	ReleaseAt time.Time `json:"release_at"`
+	Released  bool      `json:"released"`
+}
+
+var (
+	releaseMu    sync.Mutex
+	termReleases = make(map[string]*TermRelease) // Key: term
+)
+
+func currentTerm() string {
+	return config.Get("CURRENT_TERM")
+}
+
+// isEmbargoed reports whether a term's grades are still hidden from students.
+// Terms without a configured release date are visible immediately.
+func isEmbargoed(term string, now time.Time) bool {
+	releaseMu.Lock()
+	defer releaseMu.Unlock()
+	rel, ok := termReleases[term]
+	return ok && !rel.Released && now.Before(rel.ReleaseAt)
+}
+
+// runReleaseScheduler flips terms to released once their date passes, so the
+// release is recorded once rather than inferred on every read.
+func runReleaseScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var lifted []string
+		releaseMu.Lock()
+		for _, rel := range termReleases {
+			if !rel.Released && !now.Before(rel.ReleaseAt) {
+				rel.Released = true
+				lifted = append(lifted, rel.Term)
+				slog.Info("grades released", "term", rel.Term)
+			}
+		}
+		releaseMu.Unlock()
+		for _, term := range lifted {
+			notifyTermVisible(term)
+		}
+	}
+}
+
+// termReleasesHandler lists release dates (GET, public so the portal can show
+// a countdown) or lets the registrar set one (PUT).
+func termReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		releaseMu.Lock()
+		list := []TermRelease{}
+		for _, rel := range termReleases {
+			list = append(list, *rel)
+		}
+		releaseMu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].Term < list[j].Term })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+			return
+		}
+		var req TermRelease
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Term == "" || req.ReleaseAt.IsZero() {
+			http.Error(w, "term and release_at are required", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		wasHidden := isEmbargoed(req.Term, now)
+		releaseMu.Lock()
+		termReleases[req.Term] = &TermRelease{Term: req.Term, ReleaseAt: req.ReleaseAt, Released: !now.Before(req.ReleaseAt)}
+		releaseMu.Unlock()
+		// Moving the date into the past lifts the embargo now
+		if wasHidden && !isEmbargoed(req.Term, now) {
+			notifyTermVisible(req.Term)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "release date set"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "shared/events"
+
+// --- Grade Events ---
+
+// GradeEvent is published through the event bus on subject "grades.<Type>",
+// in the same envelope as the Course Service's enrollment events. Events
+// never carry the grade itself, only that there is one to see.
+type GradeEvent = events.Event
+
+// gradeMessage stamps an event for the outbox.
+func gradeMessage(ev GradeEvent) events.Message {
+	return events.NewMessage("grades."+ev.Type, ev)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"shared/studentid"
+)
+
+// --- Fixture Loader ---
+
+// The Grade Service reads the same fixture as the Course Service (-fixture or
+// FIXTURE_PATH) and takes its grades section, so one file seeds both:
+//
+//	grades: [{student_id: student1, course_id: CCPROG1, grade: "4.0", term: 2025-T3}]
+//
+// A row may set type: midterm; grades are final otherwise.
+// A CSV fixture directory provides grades.csv with the same header names.
+// Grades must already be on the grade scale or be a grade code; legacy values go through
+// /import-grades instead.
+
+type GradeFixture struct {
+	Grades []GradeFixtureRow `yaml:"grades"`
+}
+
+type GradeFixtureRow struct {
+	StudentID string `yaml:"student_id"`
+	CourseID  string `yaml:"course_id"`
+	Grade     string `yaml:"grade"`
+	Term      string `yaml:"term"`
+	Type      string `yaml:"type"`
+}
+
+// readGradeFixture parses a YAML file, or grades.csv in a fixture directory.
+func readGradeFixture(path string) ([]GradeFixtureRow, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f GradeFixture
+		if err := yaml.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return f.Grades, nil
+	}
+
+	file, err := os.Open(filepath.Join(path, "grades.csv"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("grades.csv: %w", err)
+	}
+
+	var grades []GradeFixtureRow
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		var g GradeFixtureRow
+		for col, header := range rows[0] {
+			if col >= len(row) {
+				break
+			}
+			value := strings.TrimSpace(row[col])
+			switch strings.TrimSpace(header) {
+			case "student_id":
+				g.StudentID = value
+			case "course_id":
+				g.CourseID = value
+			case "grade":
+				g.Grade = value
+			case "term":
+				g.Term = value
+			case "type":
+				g.Type = value
+			}
+		}
+		grades = append(grades, g)
+	}
+	return grades, nil
+}
+
+// loadFixture adds the fixture's grades to the grade book, if a fixture was
+// given. Any invalid row stops the service, like a bad configuration.
+func loadFixture(path string) {
+	if path == "" {
+		return
+	}
+	rows, err := readGradeFixture(path)
+	if err != nil {
+		log.Fatalf("cannot read fixture: %v", err)
+	}
+
+	var accepted []GradeUpload
+	for i, row := range rows {
+		if err := studentid.Validate("default", row.StudentID); err != nil {
+			log.Fatalf("invalid fixture %s: grade %d: %v", path, i+1, err)
+		}
+		if row.CourseID == "" {
+			log.Fatalf("invalid fixture %s: grade %d: needs a course_id", path, i+1)
+		}
+		if row.Term == "" {
+			row.Term = currentTerm()
+		}
+		if row.Type, err = normalizeGradeType(row.Type); err != nil {
+			log.Fatalf("invalid fixture %s: grade %d: %v", path, i+1, err)
+		}
+		if err := checkGrade(row.Grade, row.Type); err != nil {
+			log.Fatalf("invalid fixture %s: grade %d: %v", path, i+1, err)
+		}
+		// A persistent grade store keeps grades, and any corrections to them,
+		// from earlier starts; the fixture never overwrites them
+		_, exists, err := currentGrade(row.StudentID, row.CourseID, row.Term, row.Type)
+		if err != nil {
+			log.Fatalf("cannot read grade store: %v", err)
+		}
+		if exists {
+			continue
+		}
+		accepted = append(accepted, GradeUpload{
+			GradeRecord: GradeRecord{StudentID: row.StudentID, CourseID: row.CourseID, Grade: row.Grade, Term: row.Term, Type: row.Type},
+			Reason:      "fixture " + path,
+		})
+	}
+
+	if _, err := grades.Save("fixture", nil, accepted...); err != nil {
+		log.Fatalf("cannot store fixture grades: %v", err)
+	}
+	if err := releaseLoaded("fixture", accepted); err != nil {
+		log.Fatalf("cannot release fixture grades: %v", err)
+	}
+	slog.Info("fixture loaded", "grades", len(accepted))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"shared/config"
+	"shared/studentid"
+)
+
+// --- GPA ---
+
+// GPA is the credit-weighted mean of grade points on the configured scale.
+// Within a term a later upload for the same course supersedes the earlier
+// one; a course retaken in another term counts as REPEAT_POLICY says
+// (repeats.go). Grade codes
+// (gradecodes.go) are left out of the GPA, though a P still earns its
+// credits. Courses the Course Service does not know (e.g. retired before the catalog was kept) are weighted at
+// DEFAULT_COURSE_CREDITS, 3 unless set, and listed in assumed_credits.
+
+type GPASummary struct {
+	Credits       int     `json:"credits"`        // Credits counted in the GPA
+	EarnedCredits int     `json:"earned_credits"` // Credits passed, including pass/fail courses
+	QualityPoints float64 `json:"quality_points"`
+	GPA           float64 `json:"gpa"`
+}
+
+type TermGPA struct {
+	Term string `json:"term"`
+	GPASummary
+}
+
+type GPAReport struct {
+	StudentID      string     `json:"student_id"`
+	Terms          []TermGPA  `json:"terms"`
+	Cumulative     GPASummary `json:"cumulative"`
+	AssumedCredits []string   `json:"assumed_credits,omitempty"`
+	RepeatPolicy   string     `json:"repeat_policy"`
+}
+
+func defaultCourseCredits() int {
+	return config.Int("DEFAULT_COURSE_CREDITS")
+}
+
+// latestPerCourse keeps the last recorded grade for each course in each term,
+// in recording order.
+func latestPerCourse(records []GradeRecord) []GradeRecord {
+	type key struct{ CourseID, Term string }
+	index := make(map[key]int)
+	var latest []GradeRecord
+	for _, rec := range records {
+		k := key{rec.CourseID, rec.Term}
+		if i, ok := index[k]; ok {
+			latest[i] = rec
+			continue
+		}
+		index[k] = len(latest)
+		latest = append(latest, rec)
+	}
+	return latest
+}
+
+func (s *GPASummary) add(credits int, grade float64) {
+	s.Credits += credits
+	s.QualityPoints += float64(credits) * grade
+	if grade >= passingPoints {
+		s.EarnedCredits += credits
+	}
+}
+
+func (s *GPASummary) finish() {
+	s.QualityPoints = *round2(s.QualityPoints)
+	if s.Credits > 0 {
+		s.GPA = *round2(s.QualityPoints / float64(s.Credits))
+	}
+}
+
+// computeGPA builds term and cumulative GPA from a student's grades.
+func computeGPA(studentID string, records []GradeRecord, catalog map[string]Course) GPAReport {
+	report := GPAReport{StudentID: studentID, Terms: []TermGPA{}, RepeatPolicy: repeatPolicy}
+	byTerm := make(map[string]*TermGPA)
+	assumed := make(map[string]bool)
+	fallback := defaultCourseCredits()
+
+	latest := latestPerCourse(finalsOnly(records))
+	superseded := supersededAttempts(latest)
+	for _, rec := range latest {
+		grade, scaled := gradePoints(rec.Grade)
+		code, coded := gradeCode(rec.Grade)
+		if !scaled && !coded {
+			continue
+		}
+		course, ok := catalog[rec.CourseID]
+		weight := course.Credits
+		if !ok {
+			weight = fallback
+			if !assumed[rec.CourseID] {
+				assumed[rec.CourseID] = true
+				report.AssumedCredits = append(report.AssumedCredits, rec.CourseID)
+			}
+		}
+		t, ok := byTerm[rec.Term]
+		if !ok {
+			t = &TermGPA{Term: rec.Term}
+			byTerm[rec.Term] = t
+		}
+		// The term stays listed even if all its attempts are superseded
+		if superseded[attemptKey(rec)] {
+			continue
+		}
+		if coded {
+			if code.EarnsCredit {
+				t.EarnedCredits += weight
+				report.Cumulative.EarnedCredits += weight
+			}
+			continue
+		}
+		t.add(weight, grade)
+		report.Cumulative.add(weight, grade)
+	}
+
+	for _, t := range byTerm {
+		t.finish()
+		report.Terms = append(report.Terms, *t)
+	}
+	sort.Slice(report.Terms, func(i, j int) bool { return report.Terms[i].Term < report.Terms[j].Term })
+	report.Cumulative.finish()
+	return report
+}
+
+// visibleGrades loads a student's grades for the caller: students may only
+// read their own, and never see drafts or terms still under embargo. It
+// writes the error response itself.
+func visibleGrades(w http.ResponseWriter, r *http.Request, studentID string) ([]GradeRecord, bool) {
+	user, ok := requireRole(w, r, "student", "faculty", "registrar", "admin")
+	if !ok {
+		return nil, false
+	}
+	if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if user.Role == "student" && user.Username != studentID {
+		http.Error(w, "Forbidden: You cannot view another student's grades", http.StatusForbidden)
+		return nil, false
+	}
+
+	recorded, err := grades.ForStudent(studentID)
+	if err == nil && user.Role == "student" {
+		recorded, err = studentVisible(recorded, time.Now())
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: read", "student_id", studentID, "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return recorded, true
+}
+
+// gpaHandler (GET /gpa?student_id=) returns term and cumulative GPA.
+func gpaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	records, ok := visibleGrades(w, r, studentID)
+	if !ok {
+		return
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(computeGPA(studentID, records, catalog))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"shared/config"
+)
+
+// --- Special Grade Codes ---
+
+// Besides the grades on the scale, a final grade can be one of a few
+// administrative codes. None of them counts toward GPA or the grade
+// statistics; P earns the course's credits, the others do not. An INC that is
+// not completed within INC_DEADLINE_DAYS (365 unless set) of being recorded
+// lapses to the lowest grade on the scale, which then counts like any other.
+
+const (
+	incompleteGrade = "INC"
+	withdrawnGrade  = "W"
+	passGrade       = "P"
+	noPassGrade     = "NP"
+)
+
+type GradeCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	EarnsCredit bool   `json:"earns_credit"`
+}
+
+var gradeCodes = []GradeCode{
+	{incompleteGrade, "Incomplete; lapses to the lowest grade if not completed by the deadline", false},
+	{withdrawnGrade, "Withdrawn", false},
+	{passGrade, "Pass (pass/fail course)", true},
+	{noPassGrade, "No pass (pass/fail course)", false},
+}
+
+func gradeCode(grade string) (GradeCode, bool) {
+	for _, c := range gradeCodes {
+		if c.Code == grade {
+			return c, true
+		}
+	}
+	return GradeCode{}, false
+}
+
+// checkGrade accepts a grade on the scale, or a code for a final grade.
+func checkGrade(grade, gradeType string) error {
+	if _, ok := gradeCode(grade); ok {
+		if gradeType != finalGrade {
+			return fmt.Errorf("grade code %s only applies to final grades", grade)
+		}
+		return nil
+	}
+	if !inGradeScale(grade) {
+		return fmt.Errorf("grade %s is not on the %s grade scale or a grade code", grade, gradeScale.Name)
+	}
+	return nil
+}
+
+func incompleteDeadline() time.Duration {
+	return time.Duration(config.Int("INC_DEADLINE_DAYS")) * 24 * time.Hour
+}
+
+// lapseIncompletes converts the INC grades past their deadline, all in one
+// batch, and returns how many it converted. An INC with no history (seeded
+// before history was kept) has no known start and is left alone.
+func lapseIncompletes(now time.Time) (int, error) {
+	recorded, err := grades.List("", "")
+	if err != nil {
+		return 0, err
+	}
+	lowest := gradeScale.Grades[len(gradeScale.Grades)-1].Grade
+	var lapsed []GradeUpload
+	for _, rec := range recorded {
+		if rec.Grade != incompleteGrade || rec.Type != finalGrade {
+			continue
+		}
+		changes, err := grades.History(rec.StudentID, rec.CourseID, rec.Term, rec.Type)
+		if err != nil {
+			return 0, err
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		deadline := changes[len(changes)-1].ChangedAt.Add(incompleteDeadline())
+		if now.Before(deadline) {
+			continue
+		}
+		rec.Grade = lowest
+		lapsed = append(lapsed, GradeUpload{GradeRecord: rec, Reason: "Incomplete not completed by " + deadline.Format("2006-01-02")})
+	}
+	changes, err := grades.Save("system", announceSaved(), lapsed...)
+	notifySaved(changes)
+	return len(changes), err
+}
+
+// runIncompleteLapse checks for lapsed incompletes at start and then on
+// every tick.
+func runIncompleteLapse(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := time.Now(); ; now = <-ticker.C {
+		n, err := lapseIncompletes(now)
+		if err != nil {
+			slog.Error("grade store: lapse incompletes", "err", err)
+		} else if n > 0 {
+			slog.Info("incomplete grades lapsed", "count", n)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"shared/studentid"
+)
+
+// --- Legacy Grade Normalization ---
+
+// GradeMapping converts one legacy value into the grade scale. A mapping
+// either matches a Symbol exactly (case-insensitive) or, when Symbol is empty,
+// matches any numeric value in [Min, Max). Adjacent ranges share a bound, the
+// next range's Min being this one's Max, so no value falls between them; the
+// table's highest range also takes its Max, such as 100%.
+type GradeMapping struct {
+	Symbol string  `json:"symbol,omitempty"`
+	Min    float64 `json:"min,omitempty"`
+	Max    float64 `json:"max,omitempty"`
+	Grade  string  `json:"grade"`
+}
+
+var (
+	mappingsMu sync.Mutex
+
+	// gradeMappings is keyed by legacy system name.
+	gradeMappings = map[string][]GradeMapping{
+		"letter": {
+			{Symbol: "A", Grade: "4.0"}, {Symbol: "A-", Grade: "3.5"},
+			{Symbol: "B+", Grade: "3.5"}, {Symbol: "B", Grade: "3.0"}, {Symbol: "B-", Grade: "2.5"},
+			{Symbol: "C+", Grade: "2.5"}, {Symbol: "C", Grade: "2.0"}, {Symbol: "C-", Grade: "1.5"},
+			{Symbol: "D", Grade: "1.0"}, {Symbol: "F", Grade: "0.0"},
+		},
+		"percent": {
+			{Min: 95, Max: 100, Grade: "4.0"}, {Min: 89, Max: 95, Grade: "3.5"},
+			{Min: 83, Max: 89, Grade: "3.0"}, {Min: 78, Max: 83, Grade: "2.5"},
+			{Min: 72, Max: 78, Grade: "2.0"}, {Min: 66, Max: 72, Grade: "1.5"},
+			{Min: 60, Max: 66, Grade: "1.0"}, {Min: 0, Max: 60, Grade: "0.0"},
+		},
+		// Old 1.0 (best) to 5.0 (fail) scale
+		"legacy5": {
+			{Min: 1.0, Max: 1.26, Grade: "4.0"}, {Min: 1.26, Max: 1.51, Grade: "3.5"},
+			{Min: 1.51, Max: 1.76, Grade: "3.0"}, {Min: 1.76, Max: 2.01, Grade: "2.5"},
+			{Min: 2.01, Max: 2.51, Grade: "2.0"}, {Min: 2.51, Max: 2.76, Grade: "1.5"},
+			{Min: 2.76, Max: 3.01, Grade: "1.0"}, {Min: 3.01, Max: 5.0, Grade: "0.0"},
+		},
+	}
+)
+
+// normalizeGrade maps a legacy value through the named system's table.
+func normalizeGrade(system, value string) (string, error) {
+	mappingsMu.Lock()
+	table, ok := gradeMappings[system]
+	mappingsMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("unknown grading system %q", system)
+	}
+
+	value = strings.TrimSpace(value)
+	num, numErr := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	top := math.Inf(-1)
+	for _, m := range table {
+		if m.Symbol == "" {
+			top = max(top, m.Max)
+		}
+	}
+	for _, m := range table {
+		if m.Symbol != "" {
+			if strings.EqualFold(m.Symbol, value) {
+				return m.Grade, nil
+			}
+			continue
+		}
+		if numErr == nil && num >= m.Min && (num < m.Max || num == m.Max && m.Max == top) {
+			return m.Grade, nil
+		}
+	}
+	return "", fmt.Errorf("no %s mapping for %q", system, value)
+}
+
+// gradeMappingsHandler lets admins view (GET) or replace (PUT) one system's table.
+func gradeMappingsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, "admin"); !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mappingsMu.Lock()
+		defer mappingsMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gradeMappings)
+
+	case http.MethodPut:
+		system := r.URL.Query().Get("system")
+		if system == "" {
+			http.Error(w, "Missing system parameter", http.StatusBadRequest)
+			return
+		}
+		var table []GradeMapping
+		if err := json.NewDecoder(r.Body).Decode(&table); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, m := range table {
+			if checkGrade(m.Grade, finalGrade) != nil {
+				http.Error(w, "Mapping target "+m.Grade+" is not on the grade scale or a grade code", http.StatusBadRequest)
+				return
+			}
+			if m.Symbol == "" && m.Min >= m.Max {
+				http.Error(w, "Mapping range needs min below max", http.StatusBadRequest)
+				return
+			}
+		}
+
+		mappingsMu.Lock()
+		gradeMappings[system] = table
+		mappingsMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "mappings updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type ImportRequest struct {
+	System string        `json:"system"`
+	DryRun bool          `json:"dry_run"`
+	Rows   []GradeRecord `json:"rows"`
+}
+
+type ImportRowReport struct {
+	Row       int    `json:"row"`
+	StudentID string `json:"student_id"`
+	CourseID  string `json:"course_id"`
+	Input     string `json:"input"`
+	Mapped    string `json:"mapped,omitempty"`
+	Status    string `json:"status"` // "imported", "valid" (dry run) or "rejected"
+	Error     string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	System   string            `json:"system"`
+	DryRun   bool              `json:"dry_run"`
+	Imported int               `json:"imported"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowReport `json:"rows"`
+}
+
+// importGrades normalizes a batch of legacy grades and records the ones that map cleanly.
+func importGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "admin")
+	if !ok {
+		return
+	}
+
+	var req ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant := studentid.Tenant(r)
+	report := ImportReport{System: req.System, DryRun: req.DryRun}
+	var accepted []GradeUpload
+
+	for i, row := range req.Rows {
+		line := ImportRowReport{Row: i + 1, StudentID: row.StudentID, CourseID: row.CourseID, Input: row.Grade}
+
+		mapped, err := normalizeGrade(req.System, row.Grade)
+		if err == nil {
+			err = studentid.Validate(tenant, row.StudentID)
+		}
+		if err == nil && row.CourseID == "" {
+			err = fmt.Errorf("missing course_id")
+		}
+
+		if err != nil {
+			line.Status = "rejected"
+			line.Error = err.Error()
+			report.Rejected++
+		} else {
+			line.Mapped = mapped
+			line.Status = "valid"
+			if !req.DryRun {
+				line.Status = "imported"
+				term := row.Term
+				if term == "" {
+					term = currentTerm()
+				}
+				accepted = append(accepted, GradeUpload{
+					GradeRecord: GradeRecord{StudentID: row.StudentID, CourseID: row.CourseID, Grade: mapped, Term: term, Type: finalGrade},
+					Reason:      "imported from " + req.System,
+				})
+			}
+			report.Imported++
+		}
+		report.Rows = append(report.Rows, line)
+	}
+
+	// One transaction: a failed write leaves none of the batch behind
+	if len(accepted) > 0 {
+		if _, err := grades.Save(user.Username, nil, accepted...); err != nil {
+			slog.ErrorContext(r.Context(), "grade store: import", "err", err)
+			http.Error(w, "Grade store unavailable; nothing was imported", http.StatusServiceUnavailable)
+			return
+		}
+		if err := releaseLoaded(user.Username, accepted); err != nil {
+			slog.ErrorContext(r.Context(), "grade store: release import", "err", err)
+			http.Error(w, "Grades imported but not released", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
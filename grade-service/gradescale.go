@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"shared/config"
+)
+
+// --- Grade Scale ---
+
+// Every stored grade must be on the configured scale, chosen at start with
+// GRADE_SCALE: "4.0" (the default: 4.0, 3.5, ... 0.0) or "letter" (A to F).
+// Each grade carries the points it is worth, which is what the GPA and the
+// grade statistics average. GET /grade-scale publishes the scale so clients
+// can offer it as a list instead of a free-text field.
+
+type ScaleGrade struct {
+	Grade  string  `json:"grade"`
+	Points float64 `json:"points"`
+}
+
+type GradeScale struct {
+	Name   string       `json:"name"`
+	Grades []ScaleGrade `json:"grades"` // Best first
+}
+
+var gradeScales = map[string]GradeScale{
+	"4.0": {Name: "4.0", Grades: []ScaleGrade{
+		{"4.0", 4.0}, {"3.5", 3.5}, {"3.0", 3.0}, {"2.5", 2.5},
+		{"2.0", 2.0}, {"1.5", 1.5}, {"1.0", 1.0}, {"0.0", 0.0},
+	}},
+	"letter": {Name: "letter", Grades: []ScaleGrade{
+		{"A", 4.0}, {"A-", 3.7}, {"B+", 3.3}, {"B", 3.0}, {"B-", 2.7}, {"C+", 2.3},
+		{"C", 2.0}, {"C-", 1.7}, {"D+", 1.3}, {"D", 1.0}, {"F", 0.0},
+	}},
+}
+
+// passingPoints is the lowest passing grade on either scale.
+const passingPoints = 1.0
+
+// gradeScale is the active scale; loadGradeScale replaces it at start.
+var gradeScale = gradeScales["4.0"]
+
+// loadGradeScale applies GRADE_SCALE.
+func loadGradeScale() {
+	name := config.Get("GRADE_SCALE")
+	gradeScale = gradeScales[name]
+
+	// The built-in legacy tables target the 4.0 scale; keep only those that
+	// still fit. Admins can set the rest with PUT /admin/grade-mappings.
+	mappingsMu.Lock()
+	defer mappingsMu.Unlock()
+	for system, table := range gradeMappings {
+		for _, m := range table {
+			if !inGradeScale(m.Grade) {
+				slog.Warn("grade scale: dropping an import mapping that targets another scale", "scale", name, "system", system)
+				delete(gradeMappings, system)
+				break
+			}
+		}
+	}
+	slog.Info("grade scale", "scale", name)
+}
+
+func inGradeScale(grade string) bool {
+	_, ok := gradePoints(grade)
+	return ok
+}
+
+// gradePoints returns what a grade is worth, if it is on the scale.
+func gradePoints(grade string) (float64, bool) {
+	for _, g := range gradeScale.Grades {
+		if g.Grade == grade {
+			return g.Points, true
+		}
+	}
+	return 0, false
+}
+
+// gradeScaleHandler serves GET /grade-scale, with the grade codes alongside.
+// The scale is not sensitive, so no token is needed.
+func gradeScaleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		GradeScale
+		Codes []GradeCode `json:"codes"`
+	}{gradeScale, gradeCodes})
+}
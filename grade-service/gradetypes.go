@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// --- Grade Types ---
+
+// A course can carry two grades per term: a midterm grade, reported partway
+// through to flag deficiencies, and the final grade. They are stored, corrected
+// and released independently. Only final grades count toward GPA,
+// transcripts and grade statistics. A grade sent without a type is final.
+
+const (
+	finalGrade   = "final"
+	midtermGrade = "midterm"
+)
+
+// normalizeGradeType defaults an empty type to final and rejects unknown ones.
+func normalizeGradeType(gradeType string) (string, error) {
+	switch gradeType {
+	case "", finalGrade:
+		return finalGrade, nil
+	case midtermGrade:
+		return midtermGrade, nil
+	}
+	return "", fmt.Errorf("unknown grade type %q (want midterm or final)", gradeType)
+}
+
+// finalsOnly drops midterm grades.
+func finalsOnly(records []GradeRecord) []GradeRecord {
+	var finals []GradeRecord
+	for _, rec := range records {
+		if rec.Type == finalGrade {
+			finals = append(finals, rec)
+		}
+	}
+	return finals
+}
+
+// releaseKey identifies the set of grades one release publishes.
+func releaseKey(courseID, term, gradeType string) string {
+	return courseID + "/" + term + "/" + gradeType
+}
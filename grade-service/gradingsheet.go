@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- Grading Sheet ---
+
+// GET /courses/{id}/grades joins a course's roster with the grades recorded
+// for it, one row per student, so a client can render the class as an
+// editable sheet. Students who were graded but have since left the roster
+// are listed after it, flagged as not enrolled, so no recorded grade goes
+// missing from the sheet.
+
+type GradingSheetRow struct {
+	StudentID string `json:"student_id"`
+	Enrolled  bool   `json:"enrolled"`
+	Midterm   string `json:"midterm,omitempty"`
+	Final     string `json:"final,omitempty"`
+}
+
+type GradingSheet struct {
+	CourseID         string            `json:"course_id"`
+	Term             string            `json:"term"`
+	MidtermReleased  bool              `json:"midterm_released"`
+	FinalReleased    bool              `json:"final_released"`
+	SubmissionClosed string            `json:"submission_closed,omitempty"` // Why faculty cannot edit the sheet now
+	Students         []GradingSheetRow `json:"students"`
+}
+
+// gradingSheet serves GET /courses/{id}/grades to the course's instructor
+// and the registrar.
+func gradingSheet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar")
+	if !ok {
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	courseID := r.PathValue("id")
+
+	gradable, err := gradableCourses(user, token)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	if !gradable[courseID] {
+		if user.Role == "registrar" {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+		return
+	}
+	roster, err := courseRoster(token, courseID)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	sheet := GradingSheet{CourseID: courseID, Term: roster.Term, Students: []GradingSheetRow{}}
+	if sheet.Term == "" {
+		sheet.Term = currentTerm()
+	}
+	if user.Role == "faculty" {
+		sheet.SubmissionClosed = submissionClosed(sheet.Term, time.Now())
+	}
+
+	recorded, err := grades.List(sheet.Term, courseID)
+	var released map[string]bool
+	if err == nil {
+		released, err = releasedKeys()
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: grading sheet", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	sheet.MidtermReleased = released[releaseKey(courseID, sheet.Term, midtermGrade)]
+	sheet.FinalReleased = released[releaseKey(courseID, sheet.Term, finalGrade)]
+
+	index := make(map[string]int)
+	for _, studentID := range roster.Students {
+		index[studentID] = len(sheet.Students)
+		sheet.Students = append(sheet.Students, GradingSheetRow{StudentID: studentID, Enrolled: true})
+	}
+	for _, rec := range recorded {
+		i, ok := index[rec.StudentID]
+		if !ok {
+			i = len(sheet.Students)
+			index[rec.StudentID] = i
+			sheet.Students = append(sheet.Students, GradingSheetRow{StudentID: rec.StudentID})
+		}
+		if rec.Type == midtermGrade {
+			sheet.Students[i].Midterm = rec.Grade
+		} else {
+			sheet.Students[i].Final = rec.Grade
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sheet)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+
+	"shared/auth"
+	"shared/events"
+	"shared/health"
+)
+
+// --- Readiness ---
+
+// readyz reports "down" when the Auth Service is unreachable, since every
+// grade endpoint needs token introspection to answer anything, or when the
+// grade store cannot be reached. the event broker is soft, since events fall
+// back to the log.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	deps := map[string]health.DependencyStatus{
+		"auth":  health.Probe(auth.ServiceURL()+"/readyz", true),
+		"store": health.Check(true, grades.Ping),
+	}
+	if broker := events.Broker(); broker != "" {
+		deps[broker] = health.Check(false, events.Ping)
+	}
+	health.Write(w, health.NewReport(deps))
+}
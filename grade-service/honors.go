@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"shared/studentid"
+)
+
+// --- Latin Honors ---
+
+// At graduation the registrar confers Latin honors on the graduation GPA,
+// which is the cumulative GPA over released final grades under the repeat
+// policy. A student needs at least MinCredits GPA credits to qualify, and
+// with DisqualifyOnFailure any failing or NP grade on record, even one
+// since retaken, rules honors out. Final transcripts carry the result.
+
+const (
+	summaCumLaude = "summa_cum_laude"
+	magnaCumLaude = "magna_cum_laude"
+	cumLaude      = "cum_laude"
+)
+
+type HonorsRules struct {
+	SummaCumLaude       float64 `json:"summa_cum_laude"`
+	MagnaCumLaude       float64 `json:"magna_cum_laude"`
+	CumLaude            float64 `json:"cum_laude"`
+	MinCredits          int     `json:"min_credits"`
+	DisqualifyOnFailure bool    `json:"disqualify_on_failure"`
+}
+
+type HonorsResult struct {
+	StudentID     string  `json:"student_id"`
+	GraduationGPA float64 `json:"graduation_gpa"`
+	Credits       int     `json:"credits"`
+	Honors        string  `json:"honors,omitempty"` // Empty when none
+	Reason        string  `json:"reason"`
+}
+
+var (
+	honorsRulesMu sync.Mutex
+	honorsRules   = HonorsRules{SummaCumLaude: 3.8, MagnaCumLaude: 3.6, CumLaude: 3.4, MinCredits: 60, DisqualifyOnFailure: true}
+)
+
+// honorsTitle is how an honor reads on a transcript, e.g. "Magna Cum Laude".
+func honorsTitle(honors string) string {
+	words := strings.Split(honors, "_")
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// determineHonors applies the honors rules to a student's released grades.
+func determineHonors(studentID string, records []GradeRecord, catalog map[string]Course) HonorsResult {
+	honorsRulesMu.Lock()
+	rules := honorsRules
+	honorsRulesMu.Unlock()
+
+	gpa := computeGPA(studentID, records, catalog)
+	result := HonorsResult{StudentID: studentID, GraduationGPA: gpa.Cumulative.GPA, Credits: gpa.Cumulative.Credits}
+	var failed []string
+	for _, rec := range latestPerCourse(finalsOnly(records)) {
+		points, scaled := gradePoints(rec.Grade)
+		if (scaled && points < passingPoints) || rec.Grade == noPassGrade {
+			failed = append(failed, rec.CourseID+" ("+rec.Grade+")")
+		}
+	}
+
+	switch {
+	case result.Credits < rules.MinCredits:
+		result.Reason = fmt.Sprintf("%d of the %d GPA credits required", result.Credits, rules.MinCredits)
+	case rules.DisqualifyOnFailure && len(failed) > 0:
+		result.Reason = "failed " + strings.Join(failed, ", ")
+	case result.GraduationGPA >= rules.SummaCumLaude:
+		result.Honors = summaCumLaude
+	case result.GraduationGPA >= rules.MagnaCumLaude:
+		result.Honors = magnaCumLaude
+	case result.GraduationGPA >= rules.CumLaude:
+		result.Honors = cumLaude
+	default:
+		result.Reason = fmt.Sprintf("graduation GPA %.2f is below %.2f", result.GraduationGPA, rules.CumLaude)
+	}
+	if result.Honors != "" {
+		result.Reason = fmt.Sprintf("graduation GPA %.2f on %d credits", result.GraduationGPA, result.Credits)
+	}
+	return result
+}
+
+// releasedHonors loads a student's released grades and determines honors.
+func releasedHonors(studentID string, catalog map[string]Course) (HonorsResult, error) {
+	records, err := grades.ForStudent(studentID)
+	if err == nil {
+		records, err = releasedOnly(records)
+	}
+	if err != nil {
+		return HonorsResult{}, err
+	}
+	return determineHonors(studentID, records, catalog), nil
+}
+
+// honorsHandler (GET /honors[?student_id=]) determines one student's honors
+// or, without student_id, lists every graded student who qualifies.
+// Registrar only.
+func honorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar"); !ok {
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	if studentID != "" {
+		if err := studentid.Validate(studentid.Tenant(r), studentID); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if studentID != "" {
+		result, err := releasedHonors(studentID, catalog)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: honors", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	graded, err := grades.List("", "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: honors", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	list := []HonorsResult{}
+	done := make(map[string]bool)
+	for _, rec := range graded {
+		if done[rec.StudentID] {
+			continue
+		}
+		done[rec.StudentID] = true
+		result, err := releasedHonors(rec.StudentID, catalog)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: honors", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if result.Honors != "" {
+			list = append(list, result)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StudentID < list[j].StudentID })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// honorsRulesHandler shows (GET) or replaces (PUT, registrar) the honors
+// rules.
+func honorsRulesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		honorsRulesMu.Lock()
+		rules := honorsRules
+		honorsRulesMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rules)
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar"); !ok {
+			return
+		}
+		var rules HonorsRules
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !(rules.SummaCumLaude > rules.MagnaCumLaude && rules.MagnaCumLaude > rules.CumLaude && rules.CumLaude > 0) || rules.MinCredits < 0 {
+			http.Error(w, "thresholds must satisfy summa_cum_laude > magna_cum_laude > cum_laude > 0, and min_credits cannot be negative", http.StatusBadRequest)
+			return
+		}
+		honorsRulesMu.Lock()
+		honorsRules = rules
+		honorsRulesMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "rules updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -2,52 +2,26 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
-)
-
-const AuthValidateURL = "http://node_auth:8081/validate"
-
-type GradeRecord struct {
-	StudentID string `json:"student_id"`
-	CourseID  string `json:"course_id"`
-	Grade     string `json:"grade"`
-}
-
-type AuthResponse struct {
-	Status   string `json:"status"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
-}
-
-var gradeBook = []GradeRecord{
-	{StudentID: "student1", CourseID: "CCPROG1", Grade: "4.0"},
-	{StudentID: "student1", CourseID: "MTH101A", Grade: "3.5"},
-	{StudentID: "student2", CourseID: "CCPROG1", Grade: "2.0"},
-}
 
-func validateTokenAndGetUser(tokenString string) (*AuthResponse, bool) {
-	client := http.Client{Timeout: 2 * time.Second}
-
-	req, _ := http.NewRequest("GET", AuthValidateURL, nil)
-	req.Header.Set("Authorization", "Bearer "+tokenString)
-
-	resp, err := client.Do(req)
-	if err != nil || resp.StatusCode != 200 {
-		return nil, false
-	}
-	defer resp.Body.Close()
-
-	var authData AuthResponse
-	if err := json.NewDecoder(resp.Body).Decode(&authData); err != nil {
-		return nil, false
-	}
+	"shared/config"
+	"shared/events"
+	"shared/health"
+	"shared/idempotency"
+	"shared/logging"
+	"shared/metrics"
+	"shared/models"
+	"shared/mtls"
+	"shared/server"
+	"shared/studentid"
+	"shared/tracing"
+)
 
-	return &authData, true
-}
+type GradeRecord = models.GradeRecord
 
 func getGrades(w http.ResponseWriter, r *http.Request) {
 	// 1. EXTRACT TOKEN
@@ -59,34 +33,66 @@ func getGrades(w http.ResponseWriter, r *http.Request) {
 	tokenValue := strings.TrimPrefix(authHeader, "Bearer ")
 
 	// 2. IDENTIFY USER (Talk to Auth Service)
-	user, valid := validateTokenAndGetUser(tokenValue)
+	user, valid := validateTokenAndGetUser(r.Context(), tokenValue)
 	if !valid {
 		http.Error(w, "Unauthorized: Invalid Token", http.StatusUnauthorized)
 		return
 	}
+	logging.SetUser(r.Context(), user.Username)
 
 	// 3. AUTHORIZATION CHECK (The Logic You Asked For)
-	requestedStudent := r.URL.Query().Get("student_id")
+	q := r.URL.Query()
+	requestedStudent := q.Get("student_id")
+	staff := user.Role == "faculty" || user.Role == "registrar" || user.Role == "admin"
+	if requestedStudent == "" && !staff {
+		requestedStudent = user.Username
+	}
+	if requestedStudent != "" {
+		if err := studentid.Validate(studentid.Tenant(r), requestedStudent); err != nil {
+			http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	filter, err := parseGradeFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePage(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// RULE: You can only see the data if:
-	// A) You are a Faculty member
+	// A) You are Faculty, the registrar or an admin, who may also leave out
+	//    student_id to query across students
 	// OR
 	// B) You are the student requesting your own data
-	if user.Role != "faculty" && user.Username != requestedStudent {
+	if !staff && user.Username != requestedStudent {
 		http.Error(w, "Forbidden: You cannot view another student's grades", http.StatusForbidden)
 		return
 	}
 
 	// 4. Return Data
+	// Students don't see draft grades or terms still under embargo
 	var results []GradeRecord
-	for _, rec := range gradeBook {
-		if rec.StudentID == requestedStudent {
-			results = append(results, rec)
-		}
+	if requestedStudent == "" {
+		results, err = grades.List(filter.Term, filter.CourseID)
+	} else {
+		results, err = grades.ForStudent(requestedStudent)
+	}
+	if err == nil && user.Role == "student" {
+		results, err = studentVisible(results, time.Now())
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: read", "student_id", requestedStudent, "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+	json.NewEncoder(w).Encode(page(w, r, filter.apply(results), limit, offset))
 }
 
 func uploadGrade(w http.ResponseWriter, r *http.Request) {
@@ -97,14 +103,15 @@ func uploadGrade(w http.ResponseWriter, r *http.Request) {
 	}
 	tokenValue := strings.TrimPrefix(authHeader, "Bearer ")
 
-	user, valid := validateTokenAndGetUser(tokenValue)
+	user, valid := validateTokenAndGetUser(r.Context(), tokenValue)
 	if !valid {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	logging.SetUser(r.Context(), user.Username)
 
-	// RULE: Only Faculty can upload
-	if user.Role != "faculty" {
+	// RULE: Only Faculty (or the registrar, as an override) can upload
+	if user.Role != "faculty" && user.Role != "registrar" {
 		http.Error(w, "Forbidden: Only faculty can upload grades", http.StatusForbidden)
 		return
 	}
@@ -114,22 +121,159 @@ func uploadGrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var newGrade GradeRecord
-	if err := json.NewDecoder(r.Body).Decode(&newGrade); err != nil {
+	var upload GradeUpload
+	if err := json.NewDecoder(r.Body).Decode(&upload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newGrade := upload.GradeRecord
+	if err := studentid.Validate(studentid.Tenant(r), newGrade.StudentID); err != nil {
+		http.Error(w, "Invalid student ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if newGrade.Term == "" {
+		newGrade.Term = currentTerm()
+	}
+	gradeType, err := normalizeGradeType(newGrade.Type)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newGrade.Type = gradeType
+	if err := checkGrade(newGrade.Grade, newGrade.Type); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	gradeBook = append(gradeBook, newGrade)
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte(`{"status": "grade recorded"}`))
+	// RULE: Faculty can only grade the courses they teach
+	gradable, err := gradableCourses(user, tokenValue)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	if !gradable[newGrade.CourseID] {
+		if user.Role == "registrar" {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+		return
+	}
+	// An override is always explained in the audit trail
+	if user.Role == "registrar" && upload.Reason == "" {
+		http.Error(w, "A reason is required for a registrar override", http.StatusBadRequest)
+		return
+	}
+	// RULE: Faculty submit grades only while the term's window is open
+	if user.Role == "faculty" {
+		if closed := submissionClosed(newGrade.Term, time.Now()); closed != "" {
+			http.Error(w, "Forbidden: "+closed, http.StatusForbidden)
+			return
+		}
+	}
+
+	// RULE: Only students enrolled in the course can be graded
+	roster, err := courseRoster(tokenValue, newGrade.CourseID)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	if problem := enrollmentProblem(roster, newGrade.StudentID, newGrade.Term); problem != "" {
+		http.Error(w, "Student not enrolled: "+problem, http.StatusConflict)
+		return
+	}
+
+	// A changed grade is a correction and needs a reason for the audit trail
+	previous, exists, err := currentGrade(newGrade.StudentID, newGrade.CourseID, newGrade.Term, newGrade.Type)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: read", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if exists && previous != newGrade.Grade && upload.Reason == "" {
+		http.Error(w, "A reason is required to change an existing grade", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := grades.Save(user.Username, announceSaved(), GradeUpload{GradeRecord: newGrade, Reason: upload.Reason})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: save", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	notifySaved(changes)
+	switch {
+	case len(changes) == 0:
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "grade unchanged"}`))
+	case changes[0].OldGrade != "":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "grade corrected"}`))
+	default:
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status": "grade recorded"}`))
+	}
 }
 
 func main() {
+	config.Load("grade", settings...)
+	logging.Setup("grade")
+	mtls.Setup()
+	tracing.Setup("grade")
+	idempotency.Setup("grade")
+	loadGradeScale()
+	loadRepeatPolicy()
+	loadStatsPolicy()
+	studentid.Setup()
+	openGradeStore()
+	loadFixture(config.Get("FIXTURE_PATH"))
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/grades", getGrades)
 	mux.HandleFunc("/upload-grade", uploadGrade)
+	mux.HandleFunc("/upload-grades", uploadGradesCSV)
+	mux.HandleFunc("/grades/history", gradeHistory)
+	mux.HandleFunc("/grades/release", gradeReleases)
+	mux.HandleFunc("/import-grades", importGrades)
+	mux.HandleFunc("/grade-scale", gradeScaleHandler)
+	mux.HandleFunc("/courses/{id}/grade-stats", sectionGradeStats)
+	mux.HandleFunc("/courses/{id}/grades", gradingSheet)
+	mux.HandleFunc("/gpa", gpaHandler)
+	mux.HandleFunc("/transcript", transcriptHandler)
+	mux.HandleFunc("/honors", honorsHandler)
+	mux.HandleFunc("/honors/rules", honorsRulesHandler)
+	mux.HandleFunc("/standing", standingHandler)
+	mux.HandleFunc("/standing/term", termStandingHandler)
+	mux.HandleFunc("/standing/rules", standingRulesHandler)
+	mux.HandleFunc("/degree-audit", degreeAuditHandler)
+	mux.HandleFunc("/completed-courses", completedCoursesHandler)
+	mux.HandleFunc("/programs/{id}", programHandler)
+	mux.HandleFunc("/admin/grade-mappings", gradeMappingsHandler)
+	mux.HandleFunc("/terms/release-dates", termReleasesHandler)
+	mux.HandleFunc("/notifications", notificationsHandler)
+	mux.HandleFunc("/notifications/read", markNotificationsRead)
+	mux.HandleFunc("/notifications/preferences", notificationPrefsHandler)
+	mux.HandleFunc("/terms/submission-windows", submissionWindowsHandler)
+	mux.HandleFunc("/public/grade-stats", publicGradeStats)
+	mux.HandleFunc("/public/grade-stats/policy", statsPolicyHandler)
+	mux.HandleFunc("/healthz", health.Liveness)
+	mux.HandleFunc("/readyz", readyz)
+	mux.Handle("/metrics", metrics.Handler())
+
+	events.Start("grade-service", grades)
+	watchCatalog()
+	go runReleaseScheduler(time.Minute)
+	go runIncompleteLapse(time.Hour)
 
-	fmt.Println("Node 4 (Grade Service) running on port 8083...")
-	log.Fatal(http.ListenAndServe("0.0.0.0:8083", mux))
+	server.OnShutdown("events", events.Flush)
+	server.OnShutdown("grade store", closeGradeStore)
+	server.OnShutdown("idempotency keys", idempotency.Close)
+	server.OnShutdown("tracing", tracing.Shutdown)
+
+	port := config.Get("PORT")
+	slog.Info("Node 4 (Grade Service) running", "port", port)
+	srv := &http.Server{Addr: "0.0.0.0:" + port, Handler: tracing.Middleware(logging.Middleware(metrics.Middleware(mtls.Middleware(idempotency.Middleware(mux)))))}
+	if err := mtls.Run(srv); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"shared/events"
+)
+
+// --- Grade Notifications ---
+
+// A student is notified whenever a grade becomes visible to them: their
+// course's grades are released, the term's embargo lifts, or a released grade
+// is recorded or corrected. Each notice is a GradePosted event, which the
+// notification service turns into an email, plus a note in the student's
+// portal inbox that stays unread until they mark it. The events are written
+// to the outbox with the save or release that makes the grade visible. Students choose the channels with /notifications/preferences;
+// both are on by default. Legacy imports and fixtures notify no one.
+// Preferences and inboxes are kept in memory.
+
+type NotificationPrefs struct {
+	Email  bool `json:"email"`
+	Portal bool `json:"portal"`
+}
+
+type Notification struct {
+	ID       string    `json:"id"`
+	CourseID string    `json:"course_id"`
+	Term     string    `json:"term"`
+	Type     string    `json:"type"`
+	Message  string    `json:"message"`
+	At       time.Time `json:"at"`
+	Read     bool      `json:"read"`
+}
+
+const maxInbox = 50 // Older notes are dropped
+
+var (
+	notifyMu    sync.Mutex
+	notifyPrefs = make(map[string]NotificationPrefs) // Key: student
+	inboxes     = make(map[string][]Notification)    // Key: student; newest first
+	noteSeq     int64                                // Last notification ID, guarded by notifyMu
+)
+
+func prefsFor(studentID string) NotificationPrefs {
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	if prefs, ok := notifyPrefs[studentID]; ok {
+		return prefs
+	}
+	return NotificationPrefs{Email: true, Portal: true}
+}
+
+// postedMessage is the GradePosted event for a grade its student can now see.
+func postedMessage(rec GradeRecord) events.Message {
+	return gradeMessage(GradeEvent{Type: "GradePosted", StudentID: rec.StudentID, CourseID: rec.CourseID, Data: map[string]string{
+		"term":         rec.Term,
+		"grade_type":   rec.Type,
+		"notify_email": strconv.FormatBool(prefsFor(rec.StudentID).Email),
+	}})
+}
+
+// notePosted puts a note in the student's inbox, if they want one.
+func notePosted(rec GradeRecord, updated bool) {
+	if !prefsFor(rec.StudentID).Portal {
+		return
+	}
+	verb := "posted"
+	if updated {
+		verb = "updated"
+	}
+	note := Notification{CourseID: rec.CourseID, Term: rec.Term, Type: rec.Type, At: time.Now().UTC(),
+		Message: "Your " + rec.Type + " grade for " + rec.CourseID + " (" + rec.Term + ") has been " + verb + "."}
+	notifyMu.Lock()
+	noteSeq++
+	note.ID = strconv.FormatInt(noteSeq, 10)
+	inbox := append([]Notification{note}, inboxes[rec.StudentID]...)
+	if len(inbox) > maxInbox {
+		inbox = inbox[:maxInbox]
+	}
+	inboxes[rec.StudentID] = inbox
+	notifyMu.Unlock()
+}
+
+// visibleChanges picks the saved grades students can already see. Grades
+// still in draft, or in a term under embargo, wait for the release.
+func visibleChanges(changes []GradeChange, released map[string]bool, now time.Time) []GradeChange {
+	var visible []GradeChange
+	for _, c := range changes {
+		if released[releaseKey(c.CourseID, c.Term, c.Type)] && !isEmbargoed(c.Term, now) {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+func (c GradeChange) record() GradeRecord {
+	return GradeRecord{StudentID: c.StudentID, CourseID: c.CourseID, Grade: c.NewGrade, Term: c.Term, Type: c.Type}
+}
+
+// announceSaved is what grades.Save announces for a save students are
+// notified of: a GradePosted event per visible change. Releases are read
+// now, since the announcement runs inside the save.
+func announceSaved() func([]GradeChange) []events.Message {
+	released, err := releasedKeys()
+	if err != nil {
+		slog.Error("grade store: notify", "err", err)
+		return nil
+	}
+	now := time.Now()
+	return func(changes []GradeChange) []events.Message {
+		var msgs []events.Message
+		for _, c := range visibleChanges(changes, released, now) {
+			msgs = append(msgs, postedMessage(c.record()))
+		}
+		return msgs
+	}
+}
+
+// notifySaved puts notes in the inboxes of the students whose saved grades
+// are already visible.
+func notifySaved(changes []GradeChange) {
+	if len(changes) == 0 {
+		return
+	}
+	released, err := releasedKeys()
+	if err != nil {
+		slog.Error("grade store: notify", "err", err)
+		return
+	}
+	for _, c := range visibleChanges(changes, released, time.Now()) {
+		notePosted(c.record(), c.OldGrade != "")
+	}
+}
+
+// announceReleased is what grades.Release announces: a GradePosted event
+// for every grade in the release, unless the term is under embargo; then
+// notifyTermVisible announces them when the embargo lifts.
+func announceReleased(released []GradeRecord) []events.Message {
+	var msgs []events.Message
+	for _, rec := range released {
+		if !isEmbargoed(rec.Term, time.Now()) {
+			msgs = append(msgs, postedMessage(rec))
+		}
+	}
+	return msgs
+}
+
+// notifyReleased puts notes in the inboxes of every student graded in a
+// release, unless the term is under embargo.
+func notifyReleased(courseID, term, gradeType string) {
+	if isEmbargoed(term, time.Now()) {
+		return
+	}
+	recorded, err := grades.List(term, courseID)
+	if err != nil {
+		slog.Error("grade store: notify", "err", err)
+		return
+	}
+	for _, rec := range recorded {
+		if rec.Type == gradeType {
+			notePosted(rec, false)
+		}
+	}
+}
+
+// notifyTermVisible notifies every student with released grades in a term
+// whose embargo has just lifted.
+func notifyTermVisible(term string) {
+	recorded, err := grades.List(term, "")
+	if err == nil {
+		recorded, err = releasedOnly(recorded)
+	}
+	if err != nil {
+		slog.Error("grade store: notify", "err", err)
+		return
+	}
+	var msgs []events.Message
+	for _, rec := range recorded {
+		msgs = append(msgs, postedMessage(rec))
+	}
+	if err := grades.Announce(msgs...); err != nil {
+		slog.Error("grade store: notify", "term", term, "err", err)
+	}
+	for _, rec := range recorded {
+		notePosted(rec, false)
+	}
+}
+
+// notificationsHandler returns (GET) or clears (DELETE) the caller's inbox.
+// Students only.
+func notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "student")
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		notifyMu.Lock()
+		inbox := append([]Notification{}, inboxes[user.Username]...)
+		notifyMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inbox)
+
+	case http.MethodDelete:
+		notifyMu.Lock()
+		delete(inboxes, user.Username)
+		notifyMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "notifications cleared"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type MarkReadRequest struct {
+	IDs []string `json:"ids"` // Empty marks the whole inbox
+}
+
+// markNotificationsRead marks notes in the caller's inbox as read (POST
+// /notifications/read). Students only.
+func markNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "student")
+	if !ok {
+		return
+	}
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids := make(map[string]bool)
+	for _, id := range req.IDs {
+		ids[id] = true
+	}
+	notifyMu.Lock()
+	for i, note := range inboxes[user.Username] {
+		if len(ids) == 0 || ids[note.ID] {
+			inboxes[user.Username][i].Read = true
+		}
+	}
+	notifyMu.Unlock()
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "notifications read"}`))
+}
+
+// notificationPrefsHandler shows (GET) or replaces (PUT) the caller's
+// notification preferences. Students only.
+func notificationPrefsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireRole(w, r, "student")
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prefsFor(user.Username))
+
+	case http.MethodPut:
+		var prefs NotificationPrefs
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		notifyMu.Lock()
+		notifyPrefs[user.Username] = prefs
+		notifyMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "preferences updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- Grade Release ---
+
+// Grades are drafts while faculty are still grading: staff see them, students
+// do not. Releasing a course's grades for a term (POST /grades/release)
+// publishes every grade in it, including any recorded or corrected later;
+// midterm and final grades are released separately. Legacy imports and
+// fixtures are complete when loaded and release themselves. Release dates
+// (embargo.go) still apply on top, per term.
+
+type ReleaseRequest struct {
+	CourseID string `json:"course_id"`
+	Term     string `json:"term"`
+	Type     string `json:"type"` // Defaults to final
+}
+
+// releasedKeys returns the published grades as a set of releaseKeys.
+func releasedKeys() (map[string]bool, error) {
+	list, err := grades.Releases("")
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for _, rel := range list {
+		keys[releaseKey(rel.CourseID, rel.Term, rel.Type)] = true
+	}
+	return keys, nil
+}
+
+// releasedOnly drops draft grades.
+func releasedOnly(records []GradeRecord) ([]GradeRecord, error) {
+	released, err := releasedKeys()
+	if err != nil {
+		return nil, err
+	}
+	var list []GradeRecord
+	for _, rec := range records {
+		if released[releaseKey(rec.CourseID, rec.Term, rec.Type)] {
+			list = append(list, rec)
+		}
+	}
+	return list, nil
+}
+
+// studentVisible drops the grades a student may not see yet: those not
+// released, and those in a term still under embargo.
+func studentVisible(records []GradeRecord, now time.Time) ([]GradeRecord, error) {
+	released, err := releasedOnly(records)
+	if err != nil {
+		return nil, err
+	}
+	var visible []GradeRecord
+	for _, rec := range released {
+		if !isEmbargoed(rec.Term, now) {
+			visible = append(visible, rec)
+		}
+	}
+	return visible, nil
+}
+
+// releaseLoaded releases every course, term and type in a batch of loaded
+// grades.
+func releaseLoaded(actor string, loaded []GradeUpload) error {
+	done := make(map[string]bool)
+	for _, edit := range loaded {
+		key := releaseKey(edit.CourseID, edit.Term, edit.Type)
+		if done[key] {
+			continue
+		}
+		done[key] = true
+		if _, err := grades.Release(edit.CourseID, edit.Term, edit.Type, actor, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gradeReleases lists releases (GET [?term=], staff) or releases a course's
+// grades (POST, its instructor or the registrar).
+func gradeReleases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if _, ok := requireRole(w, r, "faculty", "registrar", "admin"); !ok {
+			return
+		}
+		list, err := grades.Releases(r.URL.Query().Get("term"))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: releases", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if list == nil {
+			list = []GradeRelease{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		user, ok := requireRole(w, r, "faculty", "registrar")
+		if !ok {
+			return
+		}
+		var req ReleaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.CourseID == "" {
+			http.Error(w, "Missing course_id", http.StatusBadRequest)
+			return
+		}
+		if req.Term == "" {
+			req.Term = currentTerm()
+		}
+		gradeType, err := normalizeGradeType(req.Type)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// RULE: Faculty can only release the courses they teach
+		if user.Role == "faculty" {
+			taught, err := taughtCourses(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			if err != nil {
+				http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+				return
+			}
+			if !taught[req.CourseID] {
+				http.Error(w, "Forbidden: You do not teach this course", http.StatusForbidden)
+				return
+			}
+		}
+
+		recorded, err := grades.List(req.Term, req.CourseID)
+		count := 0
+		for _, rec := range recorded {
+			if rec.Type == gradeType {
+				count++
+			}
+		}
+		if err == nil && count == 0 {
+			http.Error(w, "No "+gradeType+" grades recorded for this course and term", http.StatusConflict)
+			return
+		}
+		var released bool
+		if err == nil {
+			released, err = grades.Release(req.CourseID, req.Term, gradeType, user.Username, announceReleased)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: release", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !released {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status": "grades already released"}`))
+			return
+		}
+		notifyReleased(req.CourseID, req.Term, gradeType)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status": "grades released"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"shared/config"
+)
+
+// --- Repeated Courses ---
+
+// A course retaken in a later term is the same catalog code under another
+// offering ID (CODE@TERM). REPEAT_POLICY decides which graded attempts count
+// toward GPA and earned credits:
+//   - "highest" (the default): only the best grade, the latest on a tie;
+//   - "latest": only the most recent attempt;
+//   - "all": every attempt.
+//
+// The other attempts stay on the transcript, marked as superseded. Only grades
+// on the scale compete; a W, INC or pass/fail attempt is never superseded and
+// never supersedes another.
+
+const (
+	repeatHighest = "highest"
+	repeatLatest  = "latest"
+	repeatAll     = "all"
+)
+
+var repeatPolicy = repeatHighest
+
+// loadRepeatPolicy applies REPEAT_POLICY.
+func loadRepeatPolicy() {
+	repeatPolicy = config.Get("REPEAT_POLICY")
+	slog.Info("repeat policy", "policy", repeatPolicy)
+}
+
+// courseCode strips the term from an offering ID.
+func courseCode(courseID string) string {
+	code, _, _ := strings.Cut(courseID, "@")
+	return code
+}
+
+// supersededAttempts returns the attempts that do not count under the
+// repeat policy, keyed by attemptKey. records is one grade per course and
+// term, as latestPerCourse returns.
+func supersededAttempts(records []GradeRecord) map[string]bool {
+	superseded := make(map[string]bool)
+	if repeatPolicy == repeatAll {
+		return superseded
+	}
+	attempts := make(map[string][]GradeRecord) // Course code -> graded attempts
+	for _, rec := range records {
+		if inGradeScale(rec.Grade) {
+			code := courseCode(rec.CourseID)
+			attempts[code] = append(attempts[code], rec)
+		}
+	}
+	for _, list := range attempts {
+		if len(list) < 2 {
+			continue
+		}
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Term < list[j].Term })
+		kept := len(list) - 1
+		if repeatPolicy == repeatHighest {
+			best, _ := gradePoints(list[kept].Grade)
+			for i := kept - 1; i >= 0; i-- {
+				if points, _ := gradePoints(list[i].Grade); points > best {
+					kept, best = i, points
+				}
+			}
+		}
+		for i, rec := range list {
+			if i != kept {
+				superseded[attemptKey(rec)] = true
+			}
+		}
+	}
+	return superseded
+}
+
+func attemptKey(rec GradeRecord) string {
+	return rec.CourseID + "/" + rec.Term
+}
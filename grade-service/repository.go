@@ -0,0 +1,629 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"shared/config"
+	"shared/events"
+)
+
+// --- Grade Storage ---
+
+// Grades go through a GradeRepository. With GRADE_DB_PATH set they live in a
+// SQLite database at that path and survive restarts; otherwise they are kept
+// in memory, which is enough for demos. The schema is versioned by the
+// migrations below, applied in order at startup, each in its own transaction.
+//
+// A grade is identified by student, course, term and type. Saving one again
+// replaces its value and appends a GradeChange, so corrections keep a full
+// history of who changed what, when and why. A course's grades for a term
+// stay drafts until a GradeRelease publishes them to students.
+//
+// The repository is also the grade events' outbox: Save and Release write
+// the events announcing a change in the same transaction as the change, and
+// the relay in shared/events publishes them from there.
+
+type GradeChange struct {
+	StudentID string    `json:"student_id"`
+	CourseID  string    `json:"course_id"`
+	Term      string    `json:"term"`
+	Type      string    `json:"type"`
+	OldGrade  string    `json:"old_grade,omitempty"` // Empty when first recorded
+	NewGrade  string    `json:"new_grade"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type GradeRelease struct {
+	CourseID   string    `json:"course_id"`
+	Term       string    `json:"term"`
+	Type       string    `json:"type"`
+	ReleasedBy string    `json:"released_by"`
+	ReleasedAt time.Time `json:"released_at"`
+}
+
+type GradeRepository interface {
+	// ForStudent returns a student's grades in the order they were recorded.
+	ForStudent(studentID string) ([]GradeRecord, error)
+	// List returns grades in recording order, filtered by term and course
+	// when those are non-empty.
+	List(term, courseID string) ([]GradeRecord, error)
+	// Save records or replaces a batch of grades atomically, all of them or
+	// none, and returns the changes it made. Saving an unchanged value is a
+	// no-op and produces no change. The events announce returns for the
+	// changes are written with them; announce may be nil.
+	Save(actor string, announce func([]GradeChange) []events.Message, edits ...GradeUpload) ([]GradeChange, error)
+	// History returns the changes to one grade, oldest first.
+	History(studentID, courseID, term, gradeType string) ([]GradeChange, error)
+	// Release publishes a course's grades of one type for a term. It reports
+	// false if they were already released, which leaves the first release in
+	// place. The events announce returns for the released grades are written
+	// with the release; announce may be nil.
+	Release(courseID, term, gradeType, actor string, announce func([]GradeRecord) []events.Message) (bool, error)
+	// Releases lists the published courses, filtered by term when non-empty.
+	Releases(term string) ([]GradeRelease, error)
+	// Announce writes events that go with no change to the grades.
+	Announce(msgs ...events.Message) error
+	// Ping reports whether the store can be reached.
+	Ping() error
+
+	events.Outbox
+}
+
+var grades GradeRepository = &memoryGrades{
+	book: []GradeRecord{
+		{StudentID: "student1", CourseID: "CCPROG1", Grade: "4.0", Term: "2025-T3", Type: finalGrade},
+		{StudentID: "student1", CourseID: "MTH101A", Grade: "3.5", Term: "2025-T3", Type: finalGrade},
+		{StudentID: "student2", CourseID: "CCPROG1", Grade: "2.0", Term: "2025-T3", Type: finalGrade},
+	},
+	releases: []GradeRelease{
+		{CourseID: "CCPROG1", Term: "2025-T3", Type: finalGrade, ReleasedBy: "registrar1", ReleasedAt: time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)},
+		{CourseID: "MTH101A", Term: "2025-T3", Type: finalGrade, ReleasedBy: "registrar1", ReleasedAt: time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)},
+	},
+}
+
+// memoryGrades keeps the grade book, its history and the releases in slices.
+type memoryGrades struct {
+	mu       sync.Mutex
+	book     []GradeRecord
+	changes  []GradeChange
+	releases []GradeRelease
+
+	events.MemoryOutbox
+}
+
+func (m *memoryGrades) ForStudent(studentID string) ([]GradeRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []GradeRecord
+	for _, rec := range m.book {
+		if rec.StudentID == studentID {
+			list = append(list, rec)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryGrades) List(term, courseID string) ([]GradeRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []GradeRecord
+	for _, rec := range m.book {
+		if (term == "" || rec.Term == term) && (courseID == "" || rec.CourseID == courseID) {
+			list = append(list, rec)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryGrades) Save(actor string, announce func([]GradeChange) []events.Message, edits ...GradeUpload) ([]GradeChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC().Truncate(time.Second)
+	var made []GradeChange
+	for _, edit := range edits {
+		rec := edit.GradeRecord
+		change := GradeChange{StudentID: rec.StudentID, CourseID: rec.CourseID, Term: rec.Term, Type: rec.Type,
+			NewGrade: rec.Grade, ChangedBy: actor, ChangedAt: now, Reason: edit.Reason}
+		found := false
+		for i := range m.book {
+			if m.book[i].StudentID == rec.StudentID && m.book[i].CourseID == rec.CourseID &&
+				m.book[i].Term == rec.Term && m.book[i].Type == rec.Type {
+				change.OldGrade, found = m.book[i].Grade, true
+				m.book[i].Grade = rec.Grade
+				break
+			}
+		}
+		if !found {
+			m.book = append(m.book, rec)
+		}
+		if change.OldGrade != change.NewGrade {
+			made = append(made, change)
+		}
+	}
+	m.changes = append(m.changes, made...)
+	if announce != nil {
+		m.announce(announce(made))
+	}
+	return made, nil
+}
+
+// announce writes msgs to the outbox and wakes the relay. Callers hold m.mu.
+func (m *memoryGrades) announce(msgs []events.Message) {
+	if len(msgs) > 0 {
+		m.Add(msgs...)
+		events.Kick()
+	}
+}
+
+func (m *memoryGrades) History(studentID, courseID, term, gradeType string) ([]GradeChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []GradeChange
+	for _, c := range m.changes {
+		if c.StudentID == studentID && c.CourseID == courseID && c.Term == term && c.Type == gradeType {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryGrades) Release(courseID, term, gradeType, actor string, announce func([]GradeRecord) []events.Message) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rel := range m.releases {
+		if rel.CourseID == courseID && rel.Term == term && rel.Type == gradeType {
+			return false, nil
+		}
+	}
+	m.releases = append(m.releases, GradeRelease{CourseID: courseID, Term: term, Type: gradeType, ReleasedBy: actor,
+		ReleasedAt: time.Now().UTC().Truncate(time.Second)})
+	if announce != nil {
+		var released []GradeRecord
+		for _, rec := range m.book {
+			if rec.CourseID == courseID && rec.Term == term && rec.Type == gradeType {
+				released = append(released, rec)
+			}
+		}
+		m.announce(announce(released))
+	}
+	return true, nil
+}
+
+func (m *memoryGrades) Releases(term string) ([]GradeRelease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []GradeRelease
+	for _, rel := range m.releases {
+		if term == "" || rel.Term == term {
+			list = append(list, rel)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryGrades) Announce(msgs ...events.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.announce(msgs)
+	return nil
+}
+
+func (m *memoryGrades) Ping() error {
+	return nil
+}
+
+// migrations are applied in order and never edited once released; a schema
+// change is a new entry at the end. Data to start with belongs in a fixture,
+// not here.
+var migrations = []string{
+	// 1. Grade book
+	`CREATE TABLE grades (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		student_id TEXT NOT NULL,
+		course_id  TEXT NOT NULL,
+		grade      TEXT NOT NULL,
+		term       TEXT NOT NULL DEFAULT '',
+		recorded_at TEXT NOT NULL
+	);
+	CREATE INDEX grades_student ON grades (student_id);
+	CREATE INDEX grades_term_course ON grades (term, course_id);`,
+
+	// 2. Demo accounts' history, matching the in-memory seed
+	`INSERT INTO grades (student_id, course_id, grade, term, recorded_at) VALUES
+		('student1', 'CCPROG1', '4.0', '2025-T3', '2025-12-15T00:00:00Z'),
+		('student1', 'MTH101A', '3.5', '2025-T3', '2025-12-15T00:00:00Z'),
+		('student2', 'CCPROG1', '2.0', '2025-T3', '2025-12-15T00:00:00Z');`,
+
+	// 3. One row per student, course and term, plus the change history.
+	// Earlier duplicate uploads become the first entries of that history.
+	`CREATE TABLE grade_changes (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		student_id TEXT NOT NULL,
+		course_id  TEXT NOT NULL,
+		term       TEXT NOT NULL,
+		old_grade  TEXT NOT NULL DEFAULT '',
+		new_grade  TEXT NOT NULL,
+		changed_by TEXT NOT NULL,
+		changed_at TEXT NOT NULL,
+		reason     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX grade_changes_key ON grade_changes (student_id, course_id, term);
+	INSERT INTO grade_changes (student_id, course_id, term, old_grade, new_grade, changed_by, changed_at, reason)
+		SELECT student_id, course_id, term,
+			COALESCE(LAG(grade) OVER (PARTITION BY student_id, course_id, term ORDER BY id), ''),
+			grade, 'unknown', recorded_at, 'recorded before change history'
+		FROM grades ORDER BY id;
+	DELETE FROM grades WHERE id NOT IN (SELECT MAX(id) FROM grades GROUP BY student_id, course_id, term);
+	CREATE UNIQUE INDEX grades_key ON grades (student_id, course_id, term);`,
+
+	// 4. Grade releases. Grades recorded before releases existed were
+	// already visible, so their courses start out released.
+	`CREATE TABLE grade_releases (
+		course_id   TEXT NOT NULL,
+		term        TEXT NOT NULL,
+		released_by TEXT NOT NULL,
+		released_at TEXT NOT NULL,
+		PRIMARY KEY (course_id, term)
+	);
+	INSERT INTO grade_releases (course_id, term, released_by, released_at)
+		SELECT course_id, term, 'unknown', MAX(recorded_at) FROM grades GROUP BY course_id, term;`,
+
+	// 5. Midterm and final grades. Every grade so far is a final grade.
+	`ALTER TABLE grades ADD COLUMN grade_type TEXT NOT NULL DEFAULT 'final';
+	DROP INDEX grades_key;
+	CREATE UNIQUE INDEX grades_key ON grades (student_id, course_id, term, grade_type);
+	ALTER TABLE grade_changes ADD COLUMN grade_type TEXT NOT NULL DEFAULT 'final';
+	DROP INDEX grade_changes_key;
+	CREATE INDEX grade_changes_key ON grade_changes (student_id, course_id, term, grade_type);
+	CREATE TABLE grade_releases_typed (
+		course_id   TEXT NOT NULL,
+		term        TEXT NOT NULL,
+		grade_type  TEXT NOT NULL,
+		released_by TEXT NOT NULL,
+		released_at TEXT NOT NULL,
+		PRIMARY KEY (course_id, term, grade_type)
+	);
+	INSERT INTO grade_releases_typed SELECT course_id, term, 'final', released_by, released_at FROM grade_releases;
+	DROP TABLE grade_releases;
+	ALTER TABLE grade_releases_typed RENAME TO grade_releases;`,
+
+	// 6. Event outbox: events waiting for the relay, as JSON
+	`CREATE TABLE event_outbox (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		subject TEXT NOT NULL,
+		event   TEXT NOT NULL
+	);`,
+
+	// 7. Take out the demo grades of migration 2, with the history and
+	// releases 3 and 4 made for them; they now come from fixtures/dev.yaml.
+	// Grades changed since are real and stay.
+	`CREATE TEMP TABLE demo_grades (student_id TEXT, course_id TEXT, grade TEXT);
+	INSERT INTO demo_grades VALUES
+		('student1', 'CCPROG1', '4.0'),
+		('student1', 'MTH101A', '3.5'),
+		('student2', 'CCPROG1', '2.0');
+	DELETE FROM grades WHERE term = '2025-T3' AND grade_type = 'final' AND recorded_at = '2025-12-15T00:00:00Z'
+		AND (student_id, course_id, grade) IN (SELECT student_id, course_id, grade FROM demo_grades);
+	DELETE FROM grade_changes WHERE term = '2025-T3' AND grade_type = 'final' AND changed_at = '2025-12-15T00:00:00Z'
+		AND changed_by = 'unknown' AND reason = 'recorded before change history'
+		AND (student_id, course_id, new_grade) IN (SELECT student_id, course_id, grade FROM demo_grades)
+		AND NOT EXISTS (SELECT 1 FROM grades g WHERE g.student_id = grade_changes.student_id
+			AND g.course_id = grade_changes.course_id AND g.term = '2025-T3' AND g.grade_type = 'final');
+	DELETE FROM grade_releases WHERE term = '2025-T3' AND grade_type = 'final' AND released_by = 'unknown'
+		AND released_at = '2025-12-15T00:00:00Z'
+		AND NOT EXISTS (SELECT 1 FROM grades g WHERE g.course_id = grade_releases.course_id AND g.term = '2025-T3');
+	DROP TABLE demo_grades;`,
+}
+
+// sqlGrades keeps the grade book in a SQL database.
+type sqlGrades struct {
+	db *sql.DB
+}
+
+func (s sqlGrades) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 2*time.Second)
+}
+
+func (s sqlGrades) query(where string, args ...interface{}) ([]GradeRecord, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, "SELECT student_id, course_id, grade, term, grade_type FROM grades WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []GradeRecord
+	for rows.Next() {
+		var rec GradeRecord
+		if err := rows.Scan(&rec.StudentID, &rec.CourseID, &rec.Grade, &rec.Term, &rec.Type); err != nil {
+			return nil, err
+		}
+		list = append(list, rec)
+	}
+	return list, rows.Err()
+}
+
+func (s sqlGrades) ForStudent(studentID string) ([]GradeRecord, error) {
+	return s.query("student_id = ?", studentID)
+}
+
+func (s sqlGrades) List(term, courseID string) ([]GradeRecord, error) {
+	return s.query("(? = '' OR term = ?) AND (? = '' OR course_id = ?)", term, term, courseID, courseID)
+}
+
+func (s sqlGrades) Save(actor string, announce func([]GradeChange) []events.Message, edits ...GradeUpload) ([]GradeChange, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // No-op once committed
+
+	now := time.Now().UTC()
+	stamp := now.Format(time.RFC3339)
+	var made []GradeChange
+	for _, edit := range edits {
+		rec := edit.GradeRecord
+		change := GradeChange{StudentID: rec.StudentID, CourseID: rec.CourseID, Term: rec.Term, Type: rec.Type,
+			NewGrade: rec.Grade, ChangedBy: actor, ChangedAt: now, Reason: edit.Reason}
+		err := tx.QueryRowContext(ctx, "SELECT grade FROM grades WHERE student_id = ? AND course_id = ? AND term = ? AND grade_type = ?",
+			rec.StudentID, rec.CourseID, rec.Term, rec.Type).Scan(&change.OldGrade)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if change.OldGrade == change.NewGrade {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO grades (student_id, course_id, grade, term, grade_type, recorded_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (student_id, course_id, term, grade_type) DO UPDATE SET grade = excluded.grade, recorded_at = excluded.recorded_at`,
+			rec.StudentID, rec.CourseID, rec.Grade, rec.Term, rec.Type, stamp); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO grade_changes (student_id, course_id, term, grade_type, old_grade, new_grade, changed_by, changed_at, reason)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			rec.StudentID, rec.CourseID, rec.Term, rec.Type, change.OldGrade, rec.Grade, actor, stamp, edit.Reason); err != nil {
+			return nil, err
+		}
+		made = append(made, change)
+	}
+	if announce != nil {
+		if err := s.announce(ctx, tx, announce(made)); err != nil {
+			return nil, err
+		}
+	}
+	return made, s.commit(tx)
+}
+
+// announce writes msgs to the outbox in tx.
+func (s sqlGrades) announce(ctx context.Context, tx *sql.Tx, msgs []events.Message) error {
+	for _, m := range msgs {
+		ev, _ := json.Marshal(m.Event)
+		if _, err := tx.ExecContext(ctx, "INSERT INTO event_outbox (subject, event) VALUES (?, ?)", m.Subject, string(ev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit commits tx and wakes the relay for the events it wrote.
+func (s sqlGrades) commit(tx *sql.Tx) error {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	events.Kick()
+	return nil
+}
+
+func (s sqlGrades) History(studentID, courseID, term, gradeType string) ([]GradeChange, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT old_grade, new_grade, changed_by, changed_at, reason FROM grade_changes
+		WHERE student_id = ? AND course_id = ? AND term = ? AND grade_type = ? ORDER BY id`, studentID, courseID, term, gradeType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []GradeChange
+	for rows.Next() {
+		c := GradeChange{StudentID: studentID, CourseID: courseID, Term: term, Type: gradeType}
+		var at string
+		if err := rows.Scan(&c.OldGrade, &c.NewGrade, &c.ChangedBy, &at, &c.Reason); err != nil {
+			return nil, err
+		}
+		c.ChangedAt, _ = time.Parse(time.RFC3339, at)
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (s sqlGrades) Release(courseID, term, gradeType, actor string, announce func([]GradeRecord) []events.Message) (bool, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // No-op once committed
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO grade_releases (course_id, term, grade_type, released_by, released_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (course_id, term, grade_type) DO NOTHING`,
+		courseID, term, gradeType, actor, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if announce != nil {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT student_id, grade FROM grades WHERE course_id = ? AND term = ? AND grade_type = ? ORDER BY id", courseID, term, gradeType)
+		if err != nil {
+			return false, err
+		}
+		var released []GradeRecord
+		for rows.Next() {
+			rec := GradeRecord{CourseID: courseID, Term: term, Type: gradeType}
+			if err := rows.Scan(&rec.StudentID, &rec.Grade); err != nil {
+				rows.Close()
+				return false, err
+			}
+			released = append(released, rec)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, err
+		}
+		if err := s.announce(ctx, tx, announce(released)); err != nil {
+			return false, err
+		}
+	}
+	return true, s.commit(tx)
+}
+
+func (s sqlGrades) Releases(term string) ([]GradeRelease, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT course_id, term, grade_type, released_by, released_at FROM grade_releases
+		WHERE ? = '' OR term = ? ORDER BY released_at, course_id`, term, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []GradeRelease
+	for rows.Next() {
+		var rel GradeRelease
+		var at string
+		if err := rows.Scan(&rel.CourseID, &rel.Term, &rel.Type, &rel.ReleasedBy, &at); err != nil {
+			return nil, err
+		}
+		rel.ReleasedAt, _ = time.Parse(time.RFC3339, at)
+		list = append(list, rel)
+	}
+	return list, rows.Err()
+}
+
+func (s sqlGrades) Announce(msgs ...events.Message) error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once committed
+	if err := s.announce(ctx, tx, msgs); err != nil {
+		return err
+	}
+	return s.commit(tx)
+}
+
+func (s sqlGrades) Pending(limit int) ([]events.Entry, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, subject, event FROM event_outbox ORDER BY id LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []events.Entry
+	for rows.Next() {
+		var e events.Entry
+		var ev string
+		if err := rows.Scan(&e.Seq, &e.Subject, &ev); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(ev), &e.Event); err != nil {
+			return nil, fmt.Errorf("outbox entry %d: %w", e.Seq, err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func (s sqlGrades) Sent(seq int64) error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM event_outbox WHERE id <= ?", seq)
+	return err
+}
+
+func (s sqlGrades) Ping() error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
+
+// migrate brings the schema up to date, recording each applied step in
+// schema_migrations.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL)`); err != nil {
+		return err
+	}
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for i := current; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		slog.Info("grade store: applied migration", "migration", i+1)
+	}
+	return nil
+}
+
+// openGradeStore switches to the database at GRADE_DB_PATH, if set, after
+// migrating it. It must run before the server starts.
+func openGradeStore() {
+	path := config.Get("GRADE_DB_PATH")
+	if path == "" {
+		return
+	}
+	// WAL lets readers proceed during a write; busy_timeout waits out the
+	// single writer instead of failing with "database is locked".
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+	if err != nil {
+		log.Fatalf("invalid GRADE_DB_PATH: %v", err)
+	}
+	if err := migrate(db); err != nil {
+		log.Fatalf("grade store migration failed: %v", err)
+	}
+	grades = sqlGrades{db: db}
+	slog.Info("grade store", "path", path)
+}
+
+// closeGradeStore closes the database at shutdown, checkpointing its WAL.
+func closeGradeStore(ctx context.Context) error {
+	if store, ok := grades.(sqlGrades); ok {
+		return store.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// --- Section Grade Statistics ---
+
+type GradeCount struct {
+	Grade string `json:"grade"`
+	Count int    `json:"count"`
+}
+
+type SectionGradeStats struct {
+	CourseID     string       `json:"course_id"`
+	Term         string       `json:"term"`
+	Type         string       `json:"type"`
+	Released     bool         `json:"released"` // Whether students can see these grades
+	Graded       int          `json:"graded"`
+	Average      *float64     `json:"average,omitempty"` // Grade points, over grades on the scale
+	Distribution []GradeCount `json:"distribution"`      // Every grade on the scale, best first
+}
+
+// buildSectionStats counts a section's grades. Grade codes, and grades that
+// are not on the current scale (recorded before GRADE_SCALE changed), are
+// listed after the scale and left out of the average.
+func buildSectionStats(courseID, term, gradeType string, records []GradeRecord) SectionGradeStats {
+	stats := SectionGradeStats{CourseID: courseID, Term: term, Type: gradeType, Distribution: []GradeCount{}}
+	counts := make(map[string]int)
+	for _, rec := range records {
+		if rec.Type == gradeType {
+			counts[rec.Grade]++
+			stats.Graded++
+		}
+	}
+
+	scaled, total := 0, 0.0
+	for _, g := range gradeScale.Grades {
+		stats.Distribution = append(stats.Distribution, GradeCount{Grade: g.Grade, Count: counts[g.Grade]})
+		scaled += counts[g.Grade]
+		total += float64(counts[g.Grade]) * g.Points
+		delete(counts, g.Grade)
+	}
+	var other []GradeCount
+	for grade, n := range counts {
+		other = append(other, GradeCount{Grade: grade, Count: n})
+	}
+	sort.Slice(other, func(i, j int) bool { return other[i].Grade < other[j].Grade })
+	stats.Distribution = append(stats.Distribution, other...)
+
+	if scaled > 0 {
+		stats.Average = round2(total / float64(scaled))
+	}
+	return stats
+}
+
+// sectionGradeStats (GET /courses/{id}/grade-stats[?term=&type=]) returns a
+// section's final (or midterm) grade distribution and average. Restricted to
+// the instructor, the chair of the course's department and the registrar, who
+// can also read courses no longer in the catalog. Unlike the public statistics nothing is
+// suppressed, and embargoed terms are included.
+func sectionGradeStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := requireRole(w, r, "faculty", "registrar")
+	if !ok {
+		return
+	}
+	courseID := r.PathValue("id")
+	term := r.URL.Query().Get("term")
+	if term == "" {
+		term = currentTerm()
+	}
+	gradeType, err := normalizeGradeType(r.URL.Query().Get("type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if user.Role == "faculty" && !canViewSection(w, r, user, courseID) {
+		return
+	}
+
+	records, err := grades.List(term, courseID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: section stats", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	released, err := releasedKeys()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: section stats", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	stats := buildSectionStats(courseID, term, gradeType, records)
+	stats.Released = released[releaseKey(courseID, term, gradeType)]
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// canViewSection checks that a faculty member teaches a course or chairs its
+// department. It writes the error response itself.
+func canViewSection(w http.ResponseWriter, r *http.Request, user *AuthResponse, courseID string) bool {
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return false
+	}
+	course, ok := catalog[courseID]
+	if !ok {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return false
+	}
+	if course.Chair == user.Username {
+		return true
+	}
+	// Assignments are checked live, not against the cached catalog
+	taught, err := taughtCourses(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return false
+	}
+	if !taught[courseID] {
+		http.Error(w, "Forbidden: You neither teach this course nor chair its department", http.StatusForbidden)
+		return false
+	}
+	return true
+}
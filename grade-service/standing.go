@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Academic Standing ---
+
+// Each term a student with released final grades is in good standing, on the
+// dean's list or on probation, by the rules for that term (or the default
+// rules). Probation comes first: a cumulative GPA through the term below
+// ProbationBelowGPA. The dean's list needs a term GPA of at least
+// DeansListMinGPA on at least DeansListMinCredits GPA credits, and no failing,
+// NP or INC grade in the term. Draft grades never count.
+
+const (
+	standingGood      = "good"
+	standingDeansList = "deans_list"
+	standingProbation = "probation"
+)
+
+type StandingRules struct {
+	DeansListMinGPA     float64 `json:"deans_list_min_gpa"`
+	DeansListMinCredits int     `json:"deans_list_min_credits"`
+	ProbationBelowGPA   float64 `json:"probation_below_gpa"` // Cumulative GPA through the term
+}
+
+type TermStanding struct {
+	Term          string  `json:"term"`
+	GPA           float64 `json:"gpa"`
+	Credits       int     `json:"credits"`
+	CumulativeGPA float64 `json:"cumulative_gpa"`
+	Standing      string  `json:"standing"` // "good", "deans_list" or "probation"
+	Reason        string  `json:"reason,omitempty"`
+}
+
+type StandingReport struct {
+	StudentID string         `json:"student_id"`
+	Terms     []TermStanding `json:"terms"`
+}
+
+// StudentStanding is one line of a term's standing list.
+type StudentStanding struct {
+	StudentID string `json:"student_id"`
+	TermStanding
+}
+
+var (
+	standingRulesMu      sync.Mutex
+	defaultStandingRules = StandingRules{DeansListMinGPA: 3.5, DeansListMinCredits: 12, ProbationBelowGPA: 2.0}
+	termStandingRules    = make(map[string]StandingRules) // Key: term
+)
+
+func standingRulesFor(term string) StandingRules {
+	standingRulesMu.Lock()
+	defer standingRulesMu.Unlock()
+	if rules, ok := termStandingRules[term]; ok {
+		return rules
+	}
+	return defaultStandingRules
+}
+
+// computeStanding evaluates every term a student has GPA data for, oldest
+// first. The caller decides which grades count.
+func computeStanding(studentID string, records []GradeRecord, catalog map[string]Course) StandingReport {
+	gpa := computeGPA(studentID, records, catalog)
+	report := StandingReport{StudentID: studentID, Terms: []TermStanding{}}
+
+	// Courses that keep a term off the dean's list
+	blocking := make(map[string][]string)
+	for _, rec := range latestPerCourse(finalsOnly(records)) {
+		points, scaled := gradePoints(rec.Grade)
+		if (scaled && points < passingPoints) || rec.Grade == noPassGrade || rec.Grade == incompleteGrade {
+			blocking[rec.Term] = append(blocking[rec.Term], rec.CourseID+" ("+rec.Grade+")")
+		}
+	}
+
+	var cumulative GPASummary
+	for _, t := range gpa.Terms {
+		cumulative.Credits += t.Credits
+		cumulative.QualityPoints += t.QualityPoints
+		cumulative.finish()
+
+		rules := standingRulesFor(t.Term)
+		ts := TermStanding{Term: t.Term, GPA: t.GPA, Credits: t.Credits, CumulativeGPA: cumulative.GPA, Standing: standingGood}
+		switch {
+		case cumulative.Credits > 0 && cumulative.GPA < rules.ProbationBelowGPA:
+			ts.Standing = standingProbation
+			ts.Reason = fmt.Sprintf("cumulative GPA %.2f is below %.2f", cumulative.GPA, rules.ProbationBelowGPA)
+		case t.Credits < rules.DeansListMinCredits || t.GPA < rules.DeansListMinGPA:
+			// Good standing
+		case len(blocking[t.Term]) > 0:
+			ts.Reason = "dean's list GPA, but not all courses passed: " + strings.Join(blocking[t.Term], ", ")
+		default:
+			ts.Standing = standingDeansList
+			ts.Reason = fmt.Sprintf("term GPA %.2f on %d credits", t.GPA, t.Credits)
+		}
+		report.Terms = append(report.Terms, ts)
+	}
+	return report
+}
+
+// standingHandler (GET /standing?student_id=) returns a student's standing
+// in every term. Students can read only their own.
+func standingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	records, ok := visibleGrades(w, r, studentID)
+	if !ok {
+		return
+	}
+	// Staff see drafts through visibleGrades, but drafts are not standing
+	records, err := releasedOnly(records)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: standing", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(computeStanding(studentID, records, catalog))
+}
+
+// termStandingHandler (GET /standing/term?term=[&standing=]) lists the
+// standing of every student graded in a term, e.g. standing=deans_list for
+// the dean's list. Registrar or admin only.
+func termStandingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+		return
+	}
+	term, only := r.URL.Query().Get("term"), r.URL.Query().Get("standing")
+	if term == "" {
+		term = currentTerm()
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	graded, err := grades.List(term, "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: term standing", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	list := []StudentStanding{}
+	done := make(map[string]bool)
+	for _, rec := range graded {
+		if done[rec.StudentID] {
+			continue
+		}
+		done[rec.StudentID] = true
+		// Cumulative GPA needs the student's earlier terms too
+		records, err := grades.ForStudent(rec.StudentID)
+		if err == nil {
+			records, err = releasedOnly(records)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: term standing", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		for _, ts := range computeStanding(rec.StudentID, records, catalog).Terms {
+			if ts.Term == term && (only == "" || ts.Standing == only) {
+				list = append(list, StudentStanding{StudentID: rec.StudentID, TermStanding: ts})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StudentID < list[j].StudentID })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// standingRulesHandler shows (GET) or sets (PUT, registrar/admin) the rules
+// for a term, or the default rules when no term is given.
+func standingRulesHandler(w http.ResponseWriter, r *http.Request) {
+	term := r.URL.Query().Get("term")
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(standingRulesFor(term))
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+			return
+		}
+		var rules StandingRules
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if rules.DeansListMinGPA <= rules.ProbationBelowGPA || rules.ProbationBelowGPA < 0 || rules.DeansListMinCredits < 0 {
+			http.Error(w, "deans_list_min_gpa must be above probation_below_gpa, and neither that nor deans_list_min_credits can be negative", http.StatusBadRequest)
+			return
+		}
+		standingRulesMu.Lock()
+		if term == "" {
+			defaultStandingRules = rules
+		} else {
+			termStandingRules[term] = rules
+		}
+		standingRulesMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "rules updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
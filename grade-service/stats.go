@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"shared/config"
+)
+
+// --- Public Grade Statistics ---
+
+// Aggregate pass rates and average GPA per course and term, published without
+// authentication for student councils and transparency reports. Small cohorts
+// are suppressed so no published figure can be traced back to a student.
+
+// StatsPolicy controls suppression:
+//   - cohorts smaller than MinCohortSize (k) are withheld entirely;
+//   - a pass rate is withheld when fewer than MinCellSize students passed or
+//     failed, since a tiny group of failures is as identifying as a name;
+//   - with SuppressUnanimous, a 0% or 100% pass rate is withheld because it
+//     discloses every member's outcome.
+//
+// The average GPA is withheld with the pass rate: next to the cohort size it
+// narrows the outcomes down as well, and all-passing or all-failing cohorts
+// could be read straight off it.
+type StatsPolicy struct {
+	MinCohortSize     int  `json:"min_cohort_size"`
+	MinCellSize       int  `json:"min_cell_size"`
+	SuppressUnanimous bool `json:"suppress_unanimous"`
+}
+
+type CourseStats struct {
+	CourseID   string   `json:"course_id"`
+	Term       string   `json:"term"`
+	CohortSize *int     `json:"cohort_size,omitempty"`
+	PassRate   *float64 `json:"pass_rate,omitempty"`
+	AverageGPA *float64 `json:"average_gpa,omitempty"`
+	Suppressed bool     `json:"suppressed"`
+	Reason     string   `json:"reason,omitempty"`
+}
+
+var (
+	statsPolicyMu sync.Mutex
+	statsPolicy   = StatsPolicy{MinCellSize: 3, SuppressUnanimous: true}
+)
+
+// loadStatsPolicy applies STATS_MIN_COHORT_SIZE. It must run before the
+// server starts.
+func loadStatsPolicy() {
+	statsPolicy.MinCohortSize = config.Int("STATS_MIN_COHORT_SIZE")
+}
+
+func round2(f float64) *float64 {
+	r := math.Round(f*100) / 100
+	return &r
+}
+
+// buildStats aggregates the latest grade per student for every course and
+// term. Draft grades and embargoed terms are left out so the API cannot leak
+// early results.
+func buildStats(policy StatsPolicy, term, courseID string, now time.Time) ([]CourseStats, error) {
+	type cohortKey struct{ CourseID, Term string }
+	latest := make(map[cohortKey]map[string]float64) // Cohort -> student -> grade
+
+	recorded, err := grades.List(term, courseID)
+	if err != nil {
+		return nil, err
+	}
+	released, err := releasedKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range finalsOnly(recorded) {
+		if !released[releaseKey(rec.CourseID, rec.Term, rec.Type)] {
+			continue
+		}
+		grade, ok := gradePoints(rec.Grade)
+		if !ok {
+			continue
+		}
+		k := cohortKey{rec.CourseID, rec.Term}
+		if latest[k] == nil {
+			latest[k] = make(map[string]float64)
+		}
+		latest[k][rec.StudentID] = grade // Later uploads supersede earlier ones
+	}
+
+	stats := []CourseStats{}
+	for k, students := range latest {
+		if isEmbargoed(k.Term, now) {
+			continue
+		}
+		s := CourseStats{CourseID: k.CourseID, Term: k.Term}
+		n := len(students)
+		if n < policy.MinCohortSize {
+			s.Suppressed = true
+			s.Reason = "cohort smaller than " + strconv.Itoa(policy.MinCohortSize)
+			stats = append(stats, s)
+			continue
+		}
+
+		passed, total := 0, 0.0
+		for _, g := range students {
+			if g >= passingPoints {
+				passed++
+			}
+			total += g
+		}
+		s.CohortSize = &n
+
+		failed := n - passed
+		switch {
+		case policy.SuppressUnanimous && (passed == 0 || failed == 0):
+			s.Reason = "pass rate and average withheld: unanimous outcome"
+		case (passed > 0 && passed < policy.MinCellSize) || (failed > 0 && failed < policy.MinCellSize):
+			s.Reason = "pass rate and average withheld: too few passes or failures"
+		default:
+			s.PassRate = round2(float64(passed) / float64(n))
+			s.AverageGPA = round2(total / float64(n))
+		}
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Term != stats[j].Term {
+			return stats[i].Term > stats[j].Term
+		}
+		return stats[i].CourseID < stats[j].CourseID
+	})
+	return stats, nil
+}
+
+// publicGradeStats is deliberately unauthenticated: everything it returns has
+// already passed the suppression rules.
+func publicGradeStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	statsPolicyMu.Lock()
+	policy := statsPolicy
+	statsPolicyMu.Unlock()
+
+	q := r.URL.Query()
+	stats, err := buildStats(policy, q.Get("term"), q.Get("course_id"), time.Now())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "grade store: stats", "err", err)
+		http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// statsPolicyHandler shows (GET) or replaces (PUT, registrar/admin) the suppression policy.
+func statsPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		statsPolicyMu.Lock()
+		policy := statsPolicy
+		statsPolicyMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policy)
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+			return
+		}
+		var policy StatsPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// k below 2 would publish single students' grades
+		if policy.MinCohortSize < 2 || policy.MinCellSize < 0 {
+			http.Error(w, "min_cohort_size must be at least 2 and min_cell_size cannot be negative", http.StatusBadRequest)
+			return
+		}
+		statsPolicyMu.Lock()
+		statsPolicy = policy
+		statsPolicyMu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "policy updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --- Grade Submission Windows ---
+
+// The registrar can give a term a window for grade submission. Outside it
+// faculty can neither record nor change that term's grades; the registrar
+// still can, and as with every registrar override a reason is required. Terms
+// without a window are open. Imports, fixtures and lapsed incompletes are not
+// submissions and ignore the window.
+
+type SubmissionWindow struct {
+	Term   string    `json:"term"`
+	Opens  time.Time `json:"opens"` // Zero when open from the start
+	Closes time.Time `json:"closes"`
+}
+
+var (
+	windowsMu         sync.Mutex
+	submissionWindows = make(map[string]SubmissionWindow) // Key: term
+)
+
+// submissionClosed explains why faculty cannot submit grades for a term now,
+// or returns "" if they can.
+func submissionClosed(term string, now time.Time) string {
+	windowsMu.Lock()
+	win, ok := submissionWindows[term]
+	windowsMu.Unlock()
+	switch {
+	case !ok:
+		return ""
+	case now.Before(win.Opens):
+		return "grade submission for " + term + " opens " + win.Opens.Format(time.RFC3339)
+	case !now.Before(win.Closes):
+		return "grade submission for " + term + " closed " + win.Closes.Format(time.RFC3339) + "; ask the registrar for a late change"
+	}
+	return ""
+}
+
+// submissionWindowsHandler lists the windows (GET, public so faculty can see
+// their deadlines) or lets the registrar set one (PUT).
+func submissionWindowsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		windowsMu.Lock()
+		list := []SubmissionWindow{}
+		for _, win := range submissionWindows {
+			list = append(list, win)
+		}
+		windowsMu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].Term < list[j].Term })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPut:
+		if _, ok := requireRole(w, r, "registrar", "admin"); !ok {
+			return
+		}
+		var win SubmissionWindow
+		if err := json.NewDecoder(r.Body).Decode(&win); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if win.Term == "" || win.Closes.IsZero() {
+			http.Error(w, "term and closes are required", http.StatusBadRequest)
+			return
+		}
+		if !win.Opens.Before(win.Closes) {
+			http.Error(w, "opens must be before closes", http.StatusBadRequest)
+			return
+		}
+
+		windowsMu.Lock()
+		submissionWindows[win.Term] = win
+		windowsMu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "submission window set"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// --- Transcript ---
+
+// A transcript lists a student's grades term by term with each term's GPA and
+// the cumulative GPA. GET /transcript?student_id= returns JSON; add
+// format=pdf (or send Accept: application/pdf) for the printable document.
+// Within a term only the latest grade for a course appears, as in the GPA, and
+// midterm grades are left out. Attempts of a retaken course that the repeat
+// policy does not count are marked superseded. A final transcript (final=true),
+// issued at graduation, also states the graduation GPA and any Latin honors.
+
+type TranscriptLine struct {
+	CourseID   string `json:"course_id"`
+	Title      string `json:"title,omitempty"`
+	Credits    int    `json:"credits"`
+	Grade      string `json:"grade"`
+	Superseded bool   `json:"superseded,omitempty"` // A repeated attempt left out of the GPA
+}
+
+type TranscriptTerm struct {
+	Term    string           `json:"term"`
+	Courses []TranscriptLine `json:"courses"`
+	Summary GPASummary       `json:"summary"`
+}
+
+type Transcript struct {
+	StudentID   string           `json:"student_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Terms       []TranscriptTerm `json:"terms"`
+	Cumulative  GPASummary       `json:"cumulative"`
+	Final       bool             `json:"final"`
+	Honors      *HonorsResult    `json:"honors,omitempty"` // Final transcripts only
+}
+
+// buildTranscript groups a student's grades by term, oldest term first.
+func buildTranscript(studentID string, records []GradeRecord, catalog map[string]Course, now time.Time) Transcript {
+	gpa := computeGPA(studentID, records, catalog)
+	t := Transcript{StudentID: studentID, GeneratedAt: now.UTC(), Terms: []TranscriptTerm{}, Cumulative: gpa.Cumulative}
+
+	byTerm := make(map[string][]TranscriptLine)
+	latest := latestPerCourse(finalsOnly(records))
+	superseded := supersededAttempts(latest)
+	for _, rec := range latest {
+		course, ok := catalog[rec.CourseID]
+		if !ok {
+			course.Credits = defaultCourseCredits()
+		}
+		byTerm[rec.Term] = append(byTerm[rec.Term], TranscriptLine{
+			CourseID:   rec.CourseID,
+			Title:      course.Title,
+			Credits:    course.Credits,
+			Grade:      rec.Grade,
+			Superseded: superseded[attemptKey(rec)],
+		})
+	}
+	// computeGPA already orders the terms
+	for _, term := range gpa.Terms {
+		t.Terms = append(t.Terms, TranscriptTerm{Term: term.Term, Courses: byTerm[term.Term], Summary: term.GPASummary})
+	}
+	return t
+}
+
+// renderTranscriptPDF lays the transcript out on A4 pages.
+func renderTranscriptPDF(t Transcript) *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Transcript of Records - "+t.StudentID, true)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Generated %s - page %d", t.GeneratedAt.Format("2006-01-02 15:04 MST"), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	title := "Official Transcript of Records"
+	if t.Final {
+		title = "Final Transcript of Records"
+	}
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, title, "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(0, 7, "Student ID: "+t.StudentID, "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{35, 95, 25, 25}
+	repeated := false
+	for _, term := range t.Terms {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, "Term "+term.Term, "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 10)
+		for i, h := range []string{"Course", "Title", "Credits", "Grade"} {
+			pdf.CellFormat(widths[i], 7, h, "B", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 10)
+		for _, line := range term.Courses {
+			pdf.CellFormat(widths[0], 6, line.CourseID, "", 0, "L", false, 0, "")
+			pdf.CellFormat(widths[1], 6, line.Title, "", 0, "L", false, 0, "")
+			pdf.CellFormat(widths[2], 6, fmt.Sprint(line.Credits), "", 0, "L", false, 0, "")
+			grade := line.Grade
+			if line.Superseded {
+				grade += " (R)"
+				repeated = true
+			}
+			pdf.CellFormat(widths[3], 6, grade, "", 1, "L", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "I", 10)
+		pdf.CellFormat(0, 7, fmt.Sprintf("Term GPA %.2f on %d credits", term.Summary.GPA, term.Summary.Credits), "T", 1, "R", false, 0, "")
+		pdf.Ln(3)
+	}
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 9, fmt.Sprintf("Cumulative GPA %.2f on %d credits", t.Cumulative.GPA, t.Cumulative.Credits), "TB", 1, "R", false, 0, "")
+	if t.Honors != nil {
+		pdf.SetFont("Helvetica", "B", 11)
+		line := fmt.Sprintf("Graduation GPA %.2f", t.Honors.GraduationGPA)
+		if t.Honors.Honors != "" {
+			line += " - " + honorsTitle(t.Honors.Honors)
+		}
+		pdf.CellFormat(0, 8, line, "", 1, "R", false, 0, "")
+	}
+	if repeated {
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 6, "(R) Repeated course: this attempt is superseded and not counted in the GPA.", "", 1, "L", false, 0, "")
+	}
+	return pdf
+}
+
+// transcriptHandler serves GET /transcript?student_id=[&format=pdf][&final=true].
+func transcriptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	studentID := r.URL.Query().Get("student_id")
+	records, ok := visibleGrades(w, r, studentID)
+	if !ok {
+		return
+	}
+	catalog, err := courseCatalog()
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	t := buildTranscript(studentID, records, catalog, time.Now())
+	if r.URL.Query().Get("final") == "true" {
+		// Honors go by released grades even when staff can see drafts
+		released, err := releasedOnly(records)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "grade store: honors", "err", err)
+			http.Error(w, "Grade store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		honors := determineHonors(studentID, released, catalog)
+		t.Final, t.Honors = true, &honors
+	}
+
+	if r.URL.Query().Get("format") != "pdf" && !strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+		return
+	}
+	// Render fully first so a failure can still be reported as an error
+	var doc bytes.Buffer
+	if err := renderTranscriptPDF(t).Output(&doc); err != nil {
+		http.Error(w, "Could not render transcript", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="transcript-`+studentID+`.pdf"`)
+	doc.WriteTo(w)
+}
@@ -0,0 +1,117 @@
+package main
+
+// enrollbench hammers a running Course Service with a registration-day mix of
+// enrollments and catalog refreshes and reports throughput and latency.
+//
+//	go run . -url http://localhost:8082 -duration 10s -workers 64
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type course struct {
+	ID           string   `json:"id"`
+	CoRequisites []string `json:"co_requisites"`
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8082", "Course Service base URL")
+	duration := flag.Duration("duration", 10*time.Second, "how long to run")
+	workers := flag.Int("workers", 64, "concurrent clients")
+	readRatio := flag.Float64("reads", 0.8, "share of requests that are catalog reads")
+	flag.Parse()
+
+	// 1. Discover the courses a single /enroll can target
+	resp, err := http.Get(*baseURL + "/courses")
+	if err != nil {
+		log.Fatalf("cannot reach course service: %v", err)
+	}
+	var catalog []course
+	json.NewDecoder(resp.Body).Decode(&catalog)
+	resp.Body.Close()
+	var targets []string
+	for _, c := range catalog {
+		if len(c.CoRequisites) == 0 {
+			targets = append(targets, c.ID)
+		}
+	}
+	if len(targets) == 0 {
+		log.Fatal("no courses without co-requisites to enroll in")
+	}
+
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: *workers}}
+	var (
+		nextStudent atomic.Int64
+		reads       atomic.Int64
+		writes      atomic.Int64
+		failures    atomic.Int64
+		latMu       sync.Mutex
+		latencies   []time.Duration
+		wg          sync.WaitGroup
+	)
+
+	// 2. Run the mix until the deadline
+	deadline := time.Now().Add(*duration)
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			var local []time.Duration
+			for time.Now().Before(deadline) {
+				start := time.Now()
+				var resp *http.Response
+				var err error
+				if rng.Float64() < *readRatio {
+					resp, err = client.Get(*baseURL + "/courses")
+					reads.Add(1)
+				} else {
+					body, _ := json.Marshal(map[string]string{
+						"course_id":  targets[rng.Intn(len(targets))],
+						"student_id": fmt.Sprintf("student%d", 1000+nextStudent.Add(1)),
+					})
+					resp, err = client.Post(*baseURL+"/enroll", "application/json", bytes.NewReader(body))
+					writes.Add(1)
+				}
+				if err != nil {
+					failures.Add(1)
+					continue
+				}
+				var sink bytes.Buffer
+				sink.ReadFrom(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode >= 500 {
+					failures.Add(1)
+				}
+				local = append(local, time.Since(start))
+			}
+			latMu.Lock()
+			latencies = append(latencies, local...)
+			latMu.Unlock()
+		}(int64(i))
+	}
+	wg.Wait()
+
+	// 3. Report
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	pct := func(p float64) time.Duration {
+		if len(latencies) == 0 {
+			return 0
+		}
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	total := reads.Load() + writes.Load()
+	fmt.Printf("requests:   %d (%d reads, %d enrolls, %d failures)\n", total, reads.Load(), writes.Load(), failures.Load())
+	fmt.Printf("throughput: %.0f req/s\n", float64(total)/duration.Seconds())
+	fmt.Printf("latency:    p50 %v  p95 %v  p99 %v\n", pct(0.50), pct(0.95), pct(0.99))
+}
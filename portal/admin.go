@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/httpjson"
+	"shared/models"
+	"shared/mtls"
+)
+
+// --- Admin Area ---
+
+// /admin gives admins forms for the backends' admin APIs: accounts on the
+// Auth Service, and courses, capacity, registration holds and the
+// enrollment history on the Course Service. Every change is a POST to
+// /admin that names an action; the outcome comes back as a flash message.
+
+type AdminUser struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Program   string `json:"program"`
+	YearLevel int    `json:"year_level"`
+	Email     string `json:"email"`
+	Advisor   string `json:"advisor"`
+}
+
+type AdminCourse = models.Course
+
+type AdminHold struct {
+	ID        string    `json:"hold_id"`
+	StudentID string    `json:"student_id"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	PlacedBy  string    `json:"placed_by"`
+	PlacedAt  time.Time `json:"placed_at"`
+}
+
+type AuditEvent struct {
+	Seq          int64     `json:"seq"`
+	Type         string    `json:"type"`
+	StudentID    string    `json:"student_id"`
+	CourseID     string    `json:"course_id"`
+	FromCourseID string    `json:"from_course_id"`
+	Actor        string    `json:"actor"`
+	Detail       string    `json:"detail"`
+	At           time.Time `json:"at"`
+}
+
+// auditPageSize is how many of the newest history events the page shows.
+const auditPageSize = 100
+
+type AdminData struct {
+	Username     string
+	Flash        *Flash
+	Term         string
+	Users        []AdminUser
+	UserError    string
+	Courses      []AdminCourse
+	CourseError  string
+	Holds        []AdminHold
+	HoldError    string
+	AuditStudent string
+	AuditCourse  string
+	Audit        []AuditEvent
+	AuditTotal   int
+	AuditError   string
+}
+
+// adminPage is what the template renders: the data plus the role choices.
+type adminPage struct {
+	AdminData
+	Roles []string
+}
+
+// adminHandler shows the admin area (GET) or carries out one action (POST).
+func adminHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login?next=/admin", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost {
+		setFlash(sess, adminAction(r, sess))
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+		return
+	}
+
+	q := r.URL.Query()
+	data := AdminData{Username: sess.Username, Flash: takeFlash(sess), AuditStudent: q.Get("audit_student"), AuditCourse: q.Get("audit_course")}
+	authURL, courseURL := backendURL("auth"), backendURL("course")
+
+	if err := fetchFromNode(authURL+"/admin/users", sess.Token, &data.Users); err != nil {
+		data.UserError = "Auth Service Unreachable"
+	}
+	var terms []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	}
+	fetchFromNode(courseURL+"/terms", sess.Token, &terms)
+	for _, t := range terms {
+		if t.Current {
+			data.Term = t.ID
+		}
+	}
+	if err := fetchFromNode(courseURL+"/courses?include_archived=true&term="+url.QueryEscape(data.Term), sess.Token, &data.Courses); err != nil {
+		data.CourseError = "Course Service Unreachable"
+	}
+	if err := fetchFromNode(courseURL+"/registration-holds", sess.Token, &data.Holds); err != nil {
+		data.HoldError = "Course Service Unreachable"
+	}
+	audit := url.Values{}
+	if data.AuditStudent != "" {
+		audit.Set("student_id", data.AuditStudent)
+	}
+	if data.AuditCourse != "" {
+		audit.Set("course_id", data.AuditCourse)
+	}
+	if err := fetchFromNode(courseURL+"/enrollment-history?"+audit.Encode(), sess.Token, &data.Audit); err != nil {
+		data.AuditError = "Cannot load the history: check the filters, or the Course Service is unreachable"
+	}
+	data.AuditTotal = len(data.Audit)
+	sort.Slice(data.Audit, func(i, j int) bool { return data.Audit[i].Seq > data.Audit[j].Seq })
+	if len(data.Audit) > auditPageSize {
+		data.Audit = data.Audit[:auditPageSize]
+	}
+
+	render(w, "admin", adminPage{AdminData: data, Roles: []string{"student", "faculty", "registrar", "admin"}})
+}
+
+// adminAction carries out the action a form posted and says how it went.
+func adminAction(r *http.Request, sess *Session) Flash {
+	r.ParseForm()
+	f := r.PostForm
+	number := func(field string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(f.Get(field)))
+		return n
+	}
+	authURL, courseURL := backendURL("auth"), backendURL("course")
+	courseID := f.Get("course_id")
+
+	var done string
+	var err error
+	switch f.Get("action") {
+	case "user-create":
+		done = "Added user " + f.Get("username") + "."
+		err = callNode("POST", authURL+"/admin/users", sess.Token, "", map[string]interface{}{
+			"username": strings.TrimSpace(f.Get("username")), "password": f.Get("password"), "role": f.Get("role"),
+			"program": f.Get("program"), "year_level": number("year_level"),
+		})
+	case "user-update":
+		done = "Updated user " + f.Get("username") + "."
+		err = callNode("PUT", authURL+"/admin/users", sess.Token, "", map[string]string{
+			"username": f.Get("username"), "role": f.Get("role"), "password": f.Get("password"),
+			"advisor": strings.TrimSpace(f.Get("advisor")),
+		})
+	case "user-delete":
+		done = "Deleted user " + f.Get("username") + "."
+		err = callNode("DELETE", authURL+"/admin/users?username="+url.QueryEscape(f.Get("username")), sess.Token, "", nil)
+	case "course-create":
+		done = "Added course " + strings.ToUpper(f.Get("code")) + "."
+		err = callNode("POST", courseURL+"/courses", sess.Token, "", map[string]interface{}{
+			"code": f.Get("code"), "term": f.Get("term"), "title": f.Get("title"), "credits": number("credits"),
+			"capacity": number("capacity"), "department_id": f.Get("department_id"), "instructor": f.Get("instructor"),
+		})
+	case "course-update":
+		done = "Updated " + courseID + "."
+		err = callNode("PUT", courseURL+"/courses/details", sess.Token, f.Get("version"), map[string]interface{}{
+			"course_id": courseID, "title": f.Get("title"), "credits": number("credits"), "department_id": f.Get("department_id"),
+		})
+	case "course-capacity":
+		done = "Set the capacity of " + courseID + " to " + f.Get("capacity") + "."
+		err = callNode("PUT", courseURL+"/courses/capacity", sess.Token, f.Get("version"), map[string]interface{}{
+			"course_id": courseID, "capacity": number("capacity"),
+		})
+	case "course-archive":
+		done = "Archived " + courseID + "."
+		err = callNode("POST", courseURL+"/courses/archive", sess.Token, f.Get("version"), map[string]string{"course_id": courseID, "reason": f.Get("reason")})
+	case "course-restore":
+		done = "Restored " + courseID + "."
+		err = callNode("POST", courseURL+"/courses/restore", sess.Token, f.Get("version"), map[string]string{"course_id": courseID})
+	case "hold-place":
+		done = "Placed a " + f.Get("type") + " hold on " + f.Get("student_id") + "."
+		err = callNode("POST", courseURL+"/registration-holds", sess.Token, "", map[string]string{
+			"student_id": strings.TrimSpace(f.Get("student_id")), "type": f.Get("type"), "reason": f.Get("reason"),
+		})
+	case "hold-release":
+		done = "Released the hold."
+		err = callNode("POST", courseURL+"/registration-holds/release", sess.Token, "", map[string]string{"hold_id": f.Get("hold_id")})
+	default:
+		return Flash{Kind: "error", Message: "Unknown action"}
+	}
+	if err != nil {
+		return Flash{Kind: "error", Message: err.Error()}
+	}
+	if strings.HasPrefix(f.Get("action"), "course-") {
+		invalidateCatalog()
+	}
+	return Flash{Kind: "success", Message: done}
+}
+
+// callNode sends one admin request. ifMatch is the course version for
+// course updates. A refusal comes back as an error holding the backend's
+// message, such as the version conflict when someone else got there first.
+func callNode(method, url, token, ifMatch string, payload interface{}) error {
+	req, err := httpjson.NewRequest(context.Background(), method, url, token, payload)
+	if err != nil {
+		return err
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", `"`+ifMatch+`"`)
+	}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: mtls.Transport}
+	err = httpjson.Do(client, req, nil)
+	var refused *httpjson.StatusError
+	if err != nil && !errors.As(err, &refused) {
+		return fmt.Errorf("Service Unreachable")
+	}
+	return err
+}
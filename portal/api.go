@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- JSON API ---
+
+// /api/v1 gives the same data and actions as the HTML pages to clients that
+// want JSON, such as the web front-end and the mobile app. Instead of the
+// session cookie they send the Auth Service token as a bearer token; the
+// portal validates it on every call. Requests are forwarded to the backends
+// with the caller's token, so the backends still decide what is allowed.
+// Errors are always {"error": "..."}, and a backend's JSON error body (for
+// example an enrollment error code) is passed through as is.
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// apiUser validates the request's bearer token.
+func apiUser(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		apiError(w, http.StatusUnauthorized, "Missing bearer token")
+		return nil, false
+	}
+	user, err := validateToken(token)
+	if err != nil {
+		apiError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return nil, false
+	}
+	return &Session{AuthUser: *user, Token: token}, true
+}
+
+// relay sends payload to a backend as the caller and writes its answer back.
+// Plain-text bodies are wrapped so the client always gets JSON.
+func relay(w http.ResponseWriter, r *http.Request, sess *Session, service, method, path string, payload interface{}) {
+	target, baseURL := routeFor(service, r, sess.Username)
+	jsonData, _ := json.Marshal(payload)
+
+	client := http.Client{Timeout: 5 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest(method, baseURL+path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode >= 500 {
+		err = fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	trackCall(service, target, start, err)
+	if resp == nil {
+		apiError(w, http.StatusBadGateway, "Service Unreachable")
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if v := resp.Header.Get("X-Enrollment-Version"); v != "" {
+		w.Header().Set("X-Enrollment-Version", v)
+	}
+	if json.Valid(body) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+		return
+	}
+	text := strings.TrimSpace(string(body))
+	if resp.StatusCode >= 400 {
+		apiError(w, resp.StatusCode, text)
+		return
+	}
+	writeJSON(w, resp.StatusCode, map[string]string{"status": text})
+}
+
+// apiLogin (POST /api/v1/login) trades a username and password for a token:
+// {"token": "...", "role": "student"}.
+func apiLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	jsonData, _ := json.Marshal(creds)
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	resp, err := client.Post(backendURL("auth")+"/login", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		apiError(w, http.StatusBadGateway, "Auth Service Unreachable")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiError(w, http.StatusUnauthorized, "Login Failed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.Copy(w, resp.Body)
+}
+
+// apiDashboard (GET /api/v1/dashboard[?min_version=]) returns the dashboard
+// data. Pass the X-Enrollment-Version of the last enroll as min_version to
+// see it in the course list.
+func apiDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	sess, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, loadDashboard(r, sess, r.URL.Query().Get("min_version")))
+}
+
+// apiEnroll (POST /api/v1/enroll) enrolls the caller in {"course_id": "..."}
+// or, for sections with co-requisites, in all of {"course_ids": [...]} at once.
+func apiEnroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	sess, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		CourseID  string   `json:"course_id"`
+		CourseIDs []string `json:"course_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.CourseIDs) > 0 {
+		relay(w, r, sess, "course", "POST", "/enroll-batch", map[string]interface{}{"course_ids": req.CourseIDs, "student_id": sess.Username})
+		invalidateCatalog()
+		return
+	}
+	relay(w, r, sess, "course", "POST", "/enroll", map[string]string{"course_id": req.CourseID, "student_id": sess.Username})
+	invalidateCatalog()
+}
+
+// apiDrop (POST /api/v1/drop) drops the caller from {"course_id": "..."}.
+func apiDrop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	sess, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		CourseID string `json:"course_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	relay(w, r, sess, "course", "POST", "/drop", map[string]string{"course_id": req.CourseID, "student_id": sess.Username})
+	invalidateCatalog()
+}
+
+// apiGrades (POST /api/v1/grades) records one grade, with the same fields
+// as the Grade Service's /upload-grade. Faculty and registrar only.
+func apiGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	sess, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	var grade map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&grade); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	relay(w, r, sess, "grade", "POST", "/upload-grade", grade)
+}
+
+// apiReleaseGrades (POST /api/v1/grades/release) releases a course's grades:
+// {"course_id": "...", "term": "...", "type": "final"}.
+func apiReleaseGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	sess, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	var release map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&release); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	relay(w, r, sess, "grade", "POST", "/grades/release", release)
+}
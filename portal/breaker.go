@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// --- Circuit Breakers ---
+
+// Every backend host gets its own breaker. After breakerThreshold failed
+// calls in a row (no answer, a timeout or a 5xx) it opens, and calls fail at
+// once instead of waiting out the timeout. After breakerCooldown it lets one
+// trial call through: success closes it again, failure keeps it open for
+// another cooldown. A 4xx is the backend answering, so it counts as success.
+
+const (
+	breakerThreshold = 3
+	breakerCooldown  = 10 * time.Second
+
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+var errBreakerOpen = errors.New("circuit breaker open")
+
+type breaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int // Consecutive
+	openedAt time.Time
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker) // Host -> breaker
+)
+
+// breakerFor returns the breaker guarding the host of rawURL.
+func breakerFor(rawURL string) *breaker {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[host]
+	if !ok {
+		b = &breaker{state: breakerClosed}
+		breakers[host] = b
+	}
+	return b
+}
+
+// allow says whether a call may go out now.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return false
+		}
+		b.state = breakerHalfOpen // This caller makes the trial call
+		return true
+	case breakerHalfOpen:
+		return false
+	}
+	return true
+}
+
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= breakerThreshold {
+		b.state, b.openedAt = breakerOpen, time.Now()
+	}
+}
+
+// breakerStates reports every breaker's state by host.
+func breakerStates() map[string]string {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	states := make(map[string]string, len(breakers))
+	for host, b := range breakers {
+		b.mu.Lock()
+		states[host] = b.state
+		b.mu.Unlock()
+	}
+	return states
+}
+
+// --- Retries ---
+
+// fetchFromNode retries a failed GET up to fetchRetries times, waiting
+// retryBackoff, then twice that, and so on, with some jitter so callers do
+// not retry in step. All attempts share one fetchTimeout.
+const (
+	fetchTimeout = 2 * time.Second
+	fetchRetries = 2
+	retryBackoff = 100 * time.Millisecond
+)
+
+// backoff is how long to wait before the given retry (1 for the first).
+func backoff(retry int) time.Duration {
+	d := retryBackoff << (retry - 1)
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// withRetries runs attempt until it succeeds, fails for good, runs out of
+// retries or time, or the breaker refuses. attempt reports whether its error
+// is worth retrying. It also stops when parent is done.
+func withRetries(parent context.Context, rawURL string, attempt func(ctx context.Context) (retry bool, err error)) error {
+	b := breakerFor(rawURL)
+	ctx, cancel := context.WithTimeout(parent, fetchTimeout)
+	defer cancel()
+
+	var err error
+	for i := 0; i <= fetchRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff(i)):
+			case <-ctx.Done():
+				return err
+			}
+		}
+		if !b.allow() {
+			if err == nil {
+				err = errBreakerOpen
+			}
+			return err
+		}
+		var retry bool
+		retry, err = attempt(ctx)
+		if errors.Is(parent.Err(), context.Canceled) {
+			return err // The caller gave up; that says nothing about the backend
+		}
+		b.record(!retry)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"shared/config"
+)
+
+// --- Canary Routing ---
+
+// A backend gets a canary when <SERVICE>_CANARY_URL is set, e.g.
+// COURSE_SERVICE_CANARY_URL plus COURSE_SERVICE_CANARY_PERCENT=10.
+// Users are bucketed by a hash of their username, so each user sticks to
+// one target across reads and writes. The X-Canary header ("always" or
+// "never") overrides the bucket for testing.
+
+type canaryConfig struct {
+	URL     string
+	Percent uint32
+}
+
+func canaryFor(service string) (canaryConfig, bool) {
+	prefix := strings.ToUpper(service) + "_SERVICE_CANARY_"
+	url := config.Get(prefix + "URL")
+	if url == "" {
+		return canaryConfig{}, false
+	}
+	return canaryConfig{URL: url, Percent: uint32(config.Int(prefix + "PERCENT"))}, true
+}
+
+// canarySettings declares every backend's canary URL and percentage.
+func canarySettings() []config.Setting {
+	var settings []config.Setting
+	for _, b := range backends {
+		prefix := strings.ToUpper(b.Name) + "_SERVICE_CANARY_"
+		settings = append(settings,
+			config.Setting{Name: prefix + "URL", Usage: "Base URL of the " + b.Name + " canary; unset for none", Check: config.ValidURL},
+			config.Setting{Name: prefix + "PERCENT", Default: "0", Usage: "Percentage of users sent to the " + b.Name + " canary", Check: config.IntBetween(0, 100)},
+		)
+	}
+	return settings
+}
+
+func userBucket(username string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(username))
+	return h.Sum32() % 100
+}
+
+// routeFor picks the primary or canary instance of a backend for this request.
+func routeFor(service string, r *http.Request, username string) (target string, baseURL string) {
+	primary := backendURL(service)
+
+	canary, ok := canaryFor(service)
+	if !ok {
+		return "primary", primary
+	}
+	switch r.Header.Get("X-Canary") {
+	case "always":
+		return "canary", canary.URL
+	case "never":
+		return "primary", primary
+	}
+	if userBucket(username) < canary.Percent {
+		return "canary", canary.URL
+	}
+	return "primary", primary
+}
+
+type targetMetrics struct {
+	Requests       int64   `json:"requests"`
+	Errors         int64   `json:"errors"`
+	ErrorRate      float64 `json:"error_rate"`
+	AvgLatencyMS   float64 `json:"avg_latency_ms"`
+	MaxLatencyMS   int64   `json:"max_latency_ms"`
+	totalLatencyMS int64
+}
+
+var (
+	canaryMu      sync.Mutex
+	canaryMetrics = make(map[string]*targetMetrics) // Key: "service/target"
+
+	backendCalls = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "portal_backend_call_duration_seconds",
+		Help:    "Calls from the portal to a backend, by service, target (stable or canary) and result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "target", "result"})
+)
+
+// trackCall records the outcome of one backend call against its target.
+func trackCall(service, target string, start time.Time, err error) {
+	latency := time.Since(start).Milliseconds()
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	backendCalls.WithLabelValues(service, target, result).Observe(time.Since(start).Seconds())
+
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	key := service + "/" + target
+	m, ok := canaryMetrics[key]
+	if !ok {
+		m = &targetMetrics{}
+		canaryMetrics[key] = m
+	}
+	m.Requests++
+	if err != nil {
+		m.Errors++
+	}
+	m.totalLatencyMS += latency
+	if latency > m.MaxLatencyMS {
+		m.MaxLatencyMS = latency
+	}
+}
+
+// canaryMetricsHandler exposes per-target error and latency figures so a
+// canary can be compared against its primary.
+func canaryMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	canaryMu.Lock()
+	out := make(map[string]targetMetrics, len(canaryMetrics))
+	for key, m := range canaryMetrics {
+		snapshot := *m
+		snapshot.ErrorRate = float64(m.Errors) / float64(m.Requests)
+		snapshot.AvgLatencyMS = float64(m.totalLatencyMS) / float64(m.Requests)
+		out[key] = snapshot
+	}
+	canaryMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
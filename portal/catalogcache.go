@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"shared/config"
+)
+
+// --- Course List Cache ---
+
+// The dashboard's course list and departments barely change between loads,
+// so the portal keeps each Course Service answer for CATALOG_CACHE_TTL (0
+// turns the cache off). Entries are keyed by
+// path, which for students includes their ID, so enrollment flags stay
+// theirs. Enrolling, dropping, the waitlist and course edits made through
+// this portal clear the cache; seat counts changed elsewhere show up within
+// the TTL, and live on the dashboard through /seats/stream meanwhile. When
+// the Course Service cannot answer, an entry up to catalogStaleLimit old
+// stands in and the dashboard says how old it is.
+
+const catalogStaleLimit = 10 * time.Minute
+
+type catalogEntry struct {
+	body    []byte
+	fetched time.Time
+}
+
+var (
+	catalogMu    sync.Mutex
+	catalogCache = make(map[string]catalogEntry) // Key: Course Service path
+)
+
+// storeCatalog caches a fresh answer for path.
+func storeCatalog(path string, value interface{}) {
+	if config.Duration("CATALOG_CACHE_TTL") == 0 {
+		return
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	catalogMu.Lock()
+	catalogCache[path] = catalogEntry{body: body, fetched: time.Now()}
+	catalogMu.Unlock()
+}
+
+// cachedCatalog returns the entry for path if it is younger than maxAge.
+func cachedCatalog(path string, maxAge time.Duration) (catalogEntry, bool) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	entry, ok := catalogCache[path]
+	return entry, ok && time.Since(entry.fetched) < maxAge
+}
+
+// invalidateCatalog forgets every cached answer after a write that changes
+// seat counts or courses.
+func invalidateCatalog() {
+	catalogMu.Lock()
+	clear(catalogCache)
+	catalogMu.Unlock()
+}
+
+// fetchCatalog reads path from the Course Service through the cache. It
+// asks the service unless the entry is fresh or the health poller knows the
+// service is down, and falls back to an entry up to catalogStaleLimit old.
+// stale is when that fallback was fetched, zero for a fresh answer.
+func fetchCatalog(ctx context.Context, courseTarget, courseURL, path, token string, target interface{}) (stale time.Time, err error) {
+	if entry, ok := cachedCatalog(path, config.Duration("CATALOG_CACHE_TTL")); ok {
+		return time.Time{}, json.Unmarshal(entry.body, target)
+	}
+	if backendStatus("course") != "down" {
+		start := time.Now()
+		err = fetchFromNodeCtx(ctx, courseURL+path, token, target)
+		trackCall("course", courseTarget, start, err)
+		if err == nil {
+			storeCatalog(path, target)
+			return time.Time{}, nil
+		}
+	}
+	if entry, ok := cachedCatalog(path, catalogStaleLimit); ok {
+		return entry.fetched, json.Unmarshal(entry.body, target)
+	}
+	if err == nil {
+		err = errors.New("course service is down")
+	}
+	return time.Time{}, err
+}
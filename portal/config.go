@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+
+	"shared/config"
+	"shared/discovery"
+	"shared/logging"
+	"shared/mtls"
+	"shared/server"
+)
+
+// --- Configuration ---
+
+// settings are everything the portal can be configured with. Run it with
+// dump-config to see the values it would use.
+var settings = slices.Concat(logging.Settings, server.Settings, mtls.Settings, canarySettings(), []config.Setting{
+	{Name: "PORT", Default: "8080", Usage: "Port to listen on", Check: config.IntBetween(1, 65535)},
+	{Name: "AUTH_SERVICE_URL", Default: "http://localhost:8081", Usage: "Where the Auth Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "COURSE_SERVICE_URL", Default: "http://localhost:8082", Usage: "Where the Course Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "GRADE_SERVICE_URL", Default: "http://localhost:8083", Usage: "Where the Grade Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "CATALOG_CACHE_TTL", Default: "30s", Usage: "How long course lists are cached; 0 turns the cache off", Check: config.ValidDuration},
+	{Name: "CONTENT_SECURITY_POLICY", Default: defaultCSP, Usage: "Content-Security-Policy header sent with every page"},
+	{Name: "TLS_CERT_FILE", Usage: "Certificate to serve HTTPS with, together with TLS_KEY_FILE"},
+	{Name: "TLS_KEY_FILE", Usage: "Private key of TLS_CERT_FILE"},
+	{Name: "HTTP_REDIRECT_PORT", Usage: "Port of a plain HTTP listener that redirects to HTTPS", Check: config.IntBetween(1, 65535)},
+	{Name: "COOKIE_SECURE", Default: "false", Usage: "Mark cookies Secure when TLS ends at a proxy in front of the portal", Check: config.ValidBool},
+})
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- Dropping Courses ---
+
+// The Drop button on the dashboard opens a confirmation page built from the
+// Course Service's drop preview: what else goes with the course, whether the
+// drop is refunded and whether the student could enroll again. Confirming
+// posts the drop and returns to the dashboard with a flash message.
+
+type DropPreview struct {
+	CourseID           string     `json:"course_id"`
+	Dropped            []string   `json:"dropped"`
+	Credits            int        `json:"credits"`
+	Refund             string     `json:"refund"`
+	RefundUntil        *time.Time `json:"refund_until"`
+	RegistrationCloses *time.Time `json:"registration_closes"`
+	CanReEnroll        bool       `json:"can_re_enroll"`
+	Waitlisted         int        `json:"waitlisted"`
+}
+
+type DropData struct {
+	Username string
+	Preview  DropPreview
+	Error    string
+}
+
+// dropHandler asks the student to confirm a drop (GET) or makes it (POST).
+func dropHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "student" {
+		http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+		return
+	}
+	courseTarget, courseURL := routeFor("course", r, sess.Username)
+	courseID := r.FormValue("course_id")
+
+	if r.Method != http.MethodPost {
+		data := DropData{Username: sess.Username}
+		query := url.Values{"student_id": {sess.Username}, "course_id": {courseID}}
+		if err := fetchFromNode(courseURL+"/drop/preview?"+query.Encode(), sess.Token, &data.Preview); err != nil {
+			data.Error = "Cannot drop " + courseID + ": you may not be enrolled in it, or the Course Service is unreachable"
+		}
+		render(w, "drop", data)
+		return
+	}
+
+	jsonData, _ := json.Marshal(map[string]string{"course_id": courseID, "student_id": sess.Username})
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("POST", courseURL+"/drop", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+		if v := resp.Header.Get("X-Enrollment-Version"); v != "" {
+			setCookie(w, &http.Cookie{Name: "enroll_version", Value: v, Path: "/", MaxAge: 300})
+		}
+	}
+	trackCall("course", courseTarget, start, err)
+	invalidateCatalog()
+	switch {
+	case err != nil:
+		setFlash(sess, Flash{Kind: "error", Message: "Dropping is unavailable right now. Please try again."})
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := io.ReadAll(resp.Body)
+		setFlash(sess, Flash{Kind: "error", Message: "Could not drop " + courseID + ": " + strings.TrimSpace(string(msg))})
+	default:
+		var result struct {
+			Dropped []string `json:"dropped"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		setFlash(sess, Flash{Kind: "success", Message: "Dropped " + strings.Join(result.Dropped, ", ") + "."})
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
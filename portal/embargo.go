@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --- Grade Release Countdown ---
+
+type TermRelease struct {
+	Term      string    `json:"term"`
+	ReleaseAt time.Time `json:"release_at"`
+	Released  bool      `json:"released"`
+}
+
+type EmbargoNotice struct {
+	Term      string    `json:"term"`
+	ReleaseAt time.Time `json:"release_at"`
+	Countdown string    `json:"countdown"`
+}
+
+// formatCountdown renders a duration as e.g. "2d 5h" or "45m".
+func formatCountdown(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	mins := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+// upcomingReleases turns the Grade Service's release calendar into notices
+// for terms whose grades are still embargoed.
+func upcomingReleases(releases []TermRelease, now time.Time) []EmbargoNotice {
+	var notices []EmbargoNotice
+	for _, rel := range releases {
+		if rel.Released || !now.Before(rel.ReleaseAt) {
+			continue
+		}
+		notices = append(notices, EmbargoNotice{Term: rel.Term, ReleaseAt: rel.ReleaseAt, Countdown: formatCountdown(rel.ReleaseAt.Sub(now))})
+	}
+	return notices
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"shared/models"
+)
+
+// --- Flash Messages ---
+
+// A handler that redirects to the dashboard can leave one message in the
+// session. The dashboard shows it once and drops it.
+
+type Flash struct {
+	Kind    string // "success" or "error"
+	Message string
+}
+
+func setFlash(sess *Session, f Flash) {
+	sessionsMu.Lock()
+	sess.Flash = &f
+	sessionsMu.Unlock()
+}
+
+func takeFlash(sess *Session) *Flash {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	f := sess.Flash
+	sess.Flash = nil
+	return f
+}
+
+// EnrollmentFailure is the Course Service's body for a refused enrollment.
+type EnrollmentFailure = models.EnrollmentFailure
+
+// enrollmentMessage turns the Course Service's answer to an enrollment into
+// something a student can act on.
+func enrollmentMessage(resp *http.Response, courseIDs []string) Flash {
+	courses := strings.Join(courseIDs, " and ")
+	if resp.StatusCode == http.StatusOK {
+		return Flash{Kind: "success", Message: "Enrolled in " + courses + "."}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var f EnrollmentFailure
+	if json.Unmarshal(body, &f) != nil {
+		f.Message = strings.TrimSpace(string(body))
+	}
+	if f.CourseID != "" {
+		courses = f.CourseID
+	}
+
+	var message string
+	switch f.Code {
+	case models.CodeFull:
+		message = courses + " is full."
+	case models.CodeAlreadyEnrolled:
+		message = "You are already enrolled in " + courses + "."
+	case models.CodeSeatHeld:
+		message = "You already hold a seat in " + courses + ". Confirm the hold to enroll."
+	case models.CodeCreditLimit:
+		message = "Enrolling in " + courses + " would put you over this term's credit limit."
+	case models.CodePrereqMissing:
+		message = courses + " has to be taken with its co-requisites. Enroll from the course's own button."
+		if missing, ok := strings.CutPrefix(f.Message, "Prerequisite required: "); ok {
+			message = "You need to pass " + missing + " before taking " + courses + "."
+		}
+	case models.CodeHoldPresent:
+		message = "You have a registration hold. Contact the registrar's office to clear it."
+	case models.CodeRegistrationEarly:
+		message = "Registration is not open yet."
+	case models.CodeDeadlinePassed:
+		message = "Registration has closed."
+	case models.CodeArchived:
+		message = courses + " is no longer offered."
+	case models.CodeNotFound:
+		message = "Course " + courses + " was not found."
+	case models.CodePermissionInvalid:
+		message = "That permission number is not valid."
+	case models.CodePaymentDeclined:
+		message = "The charge for " + courses + " was declined. Contact the cashier's office."
+	case models.CodeHoldExpired, models.CodeUnavailable:
+		message = "Could not finish enrolling in " + courses + ". Nothing was charged; please try again."
+	default:
+		if f.Message == "" {
+			f.Message = http.StatusText(resp.StatusCode)
+		}
+		message = fmt.Sprintf("Could not enroll in %s: %s", courses, f.Message)
+	}
+	return Flash{Kind: "error", Message: message}
+}
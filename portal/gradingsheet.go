@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+)
+
+// --- Grading Sheet ---
+
+// The grading sheet shows a course's roster with every student's midterm and
+// final grade as dropdowns. Saving sends only the grades that changed to the
+// Grade Service's bulk upload, which checks them exactly as it checks a CSV.
+
+type GradeOption struct {
+	Value    string
+	Selected bool
+}
+
+type SheetRow struct {
+	StudentID string `json:"student_id"`
+	Enrolled  bool   `json:"enrolled"`
+	Midterm   string `json:"midterm"`
+	Final     string `json:"final"`
+
+	MidtermOptions []GradeOption `json:"-"`
+	FinalOptions   []GradeOption `json:"-"`
+}
+
+type GradingSheetData struct {
+	Username         string
+	CourseID         string     `json:"course_id"`
+	Term             string     `json:"term"`
+	MidtermReleased  bool       `json:"midterm_released"`
+	FinalReleased    bool       `json:"final_released"`
+	SubmissionClosed string     `json:"submission_closed"`
+	Students         []SheetRow `json:"students"`
+	Error            string
+}
+
+// gradeOptions lists the grades a dropdown offers with the current one
+// selected, keeping a current grade that is no longer on the scale.
+func gradeOptions(grades []string, current string) []GradeOption {
+	options := []GradeOption{{Value: "", Selected: current == ""}}
+	found := current == ""
+	for _, g := range grades {
+		options = append(options, GradeOption{Value: g, Selected: g == current})
+		found = found || g == current
+	}
+	if !found {
+		options = append(options, GradeOption{Value: current, Selected: true})
+	}
+	return options
+}
+
+// gradingSheetHandler shows the sheet (GET) or saves its changes (POST).
+func gradingSheetHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method == http.MethodPost {
+		saveGradingSheet(w, r, sess.Username, sess.Token)
+		return
+	}
+
+	data := GradingSheetData{Username: sess.Username, CourseID: r.FormValue("course_id")}
+	_, gradeURL := routeFor("grade", r, sess.Username)
+	if err := fetchFromNode(gradeURL+"/courses/"+url.PathEscape(data.CourseID)+"/grades", sess.Token, &data); err != nil {
+		data.Error = "Cannot load the grading sheet: you may not teach " + data.CourseID + ", or the Grading Service is unreachable"
+	}
+	var scale GradeScale
+	fetchFromNode(gradeURL+"/grade-scale", sess.Token, &scale)
+	var onScale []string
+	for _, g := range scale.Grades {
+		onScale = append(onScale, g.Grade)
+	}
+	finals := append([]string{}, onScale...) // Codes apply to final grades only
+	for _, c := range scale.Codes {
+		finals = append(finals, c.Code)
+	}
+	for i, row := range data.Students {
+		data.Students[i].MidtermOptions = gradeOptions(onScale, row.Midterm)
+		data.Students[i].FinalOptions = gradeOptions(finals, row.Final)
+	}
+
+	render(w, "grading-sheet", data)
+}
+
+// saveGradingSheet turns the grades that changed into a CSV upload. A grade
+// cannot be blanked once recorded, so clearing a dropdown changes nothing.
+func saveGradingSheet(w http.ResponseWriter, r *http.Request, username, token string) {
+	r.ParseForm()
+	courseID, term, reason := r.PostForm.Get("course_id"), r.PostForm.Get("term"), r.PostForm.Get("reason")
+	students := r.PostForm["student"]
+	columns := map[string][]string{
+		"midterm": r.PostForm["midterm"], "was_midterm": r.PostForm["was_midterm"],
+		"final": r.PostForm["final"], "was_final": r.PostForm["was_final"],
+	}
+	for _, values := range columns {
+		if len(values) != len(students) {
+			http.Error(w, "Malformed grading sheet", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	out := csv.NewWriter(&buf)
+	out.Write([]string{"student_id", "course_id", "term", "type", "grade", "reason"})
+	changed := 0
+	for i, studentID := range students {
+		for _, gradeType := range []string{"midterm", "final"} {
+			grade := columns[gradeType][i]
+			if grade == "" || grade == columns["was_"+gradeType][i] {
+				continue
+			}
+			out.Write([]string{studentID, courseID, term, gradeType, grade, reason})
+			changed++
+		}
+	}
+	out.Flush()
+	back := "/grading-sheet?course_id=" + url.QueryEscape(courseID)
+	if changed == 0 {
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	sendGradesCSV(w, r, username, token, &buf, back)
+}
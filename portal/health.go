@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"shared/discovery"
+	"shared/mtls"
+)
+
+// --- Backend Health ---
+
+type backend struct {
+	Name   string
+	EnvVar string
+	Hard   bool // The portal cannot serve anything useful without it
+}
+
+var backends = []backend{
+	{Name: "auth", EnvVar: "AUTH_SERVICE_URL", Hard: true},
+	{Name: "course", EnvVar: "COURSE_SERVICE_URL"},
+	{Name: "grade", EnvVar: "GRADE_SERVICE_URL"},
+}
+
+// URL is the base URL of the backend instance to call next.
+func (b backend) URL() string {
+	return discovery.Peer(b.EnvVar).URL()
+}
+
+// backendURL returns the configured base URL of a backend by name.
+func backendURL(name string) string {
+	for _, b := range backends {
+		if b.Name == name {
+			return b.URL()
+		}
+	}
+	return ""
+}
+
+type BackendHealth struct {
+	Status    string    `json:"status"` // "ok", "degraded", "down" or "unknown"
+	Hard      bool      `json:"hard"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// healthHistoryLen is how many polls of each backend are kept, five minutes
+// at the default interval.
+const healthHistoryLen = 60
+
+var (
+	healthMu      sync.Mutex
+	backendHealth = make(map[string]BackendHealth)
+	healthHistory = make(map[string][]BackendHealth) // Oldest first
+	lastFailure   = make(map[string]BackendHealth)   // Latest poll that was not "ok"
+)
+
+// backendStatus returns the last polled status of a backend, or "unknown"
+// before the first poll completes.
+func backendStatus(name string) string {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if h, ok := backendHealth[name]; ok {
+		return h.Status
+	}
+	return "unknown"
+}
+
+// checkBackend reads a backend's own readiness verdict, so a node that is up
+// but missing a dependency shows as "degraded" rather than healthy.
+func checkBackend(b backend) BackendHealth {
+	client := http.Client{Timeout: 1 * time.Second, Transport: mtls.Transport}
+	start := time.Now()
+	h := BackendHealth{Hard: b.Hard, CheckedAt: start}
+
+	resp, err := client.Get(b.URL() + "/readyz")
+	h.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		h.Status = "down"
+		h.Error = err.Error()
+		return h
+	}
+	defer resp.Body.Close()
+
+	var report struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil || report.Status == "" {
+		h.Status = "down"
+		h.Error = fmt.Sprintf("unreadable readiness response (status code %d)", resp.StatusCode)
+		return h
+	}
+	h.Status = report.Status
+	return h
+}
+
+// pollBackends refreshes backendHealth forever.
+func pollBackends(interval time.Duration) {
+	for {
+		for _, b := range backends {
+			h := checkBackend(b)
+			healthMu.Lock()
+			backendHealth[b.Name] = h
+			history := append(healthHistory[b.Name], h)
+			if len(history) > healthHistoryLen {
+				history = history[len(history)-healthHistoryLen:]
+			}
+			healthHistory[b.Name] = history
+			if h.Status != "ok" {
+				lastFailure[b.Name] = h
+			}
+			healthMu.Unlock()
+		}
+		time.Sleep(interval)
+	}
+}
+
+// readyz reports the portal as "down" only if a hard backend is down, and
+// "degraded" if any other backend is not fully healthy. It also lists the
+// circuit breakers' states.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	healthMu.Lock()
+	deps := make(map[string]BackendHealth, len(backendHealth))
+	status := "ok"
+	for name, h := range backendHealth {
+		deps[name] = h
+		switch {
+		case h.Status == "down" && h.Hard:
+			status = "down"
+		case h.Status != "ok" && status == "ok":
+			status = "degraded"
+		}
+	}
+	healthMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status == "down" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "dependencies": deps, "breakers": breakerStates()})
+}
@@ -2,239 +2,345 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
+	"io"
 	"log"
+	"net"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"shared/config"
+	"shared/health"
+	"shared/httpjson"
+	"shared/logging"
+	"shared/metrics"
+	"shared/models"
+	"shared/mtls"
+	"shared/server"
+	"shared/tracing"
 )
 
 // --- Domain Models ---
-type Course struct {
-	ID         string `json:"id"`
-	Title      string `json:"title"`
-	Credits    int    `json:"credits"`
-	OpenSlots  int    `json:"open_slots"`
-	IsEnrolled bool   `json:"is_enrolled"`
-}
+type Course = models.Course
+
+type GradeRecord = models.GradeRecord
 
-type GradeRecord struct {
+// GradeRow is one course in the grades table, with both of its grades.
+type GradeRow struct {
+	Term     string `json:"term"`
 	CourseID string `json:"course_id"`
-	Grade    string `json:"grade"`
+	Midterm  string `json:"midterm,omitempty"`
+	Final    string `json:"final,omitempty"`
 }
 
-type DashboardData struct {
-	Username    string
-	Role        string
-	Courses     []Course
-	Grades      []GradeRecord
-	GradeError  string
-	CourseError string
+// gradeRows pairs each course's midterm and final grade, in recorded order.
+func gradeRows(records []GradeRecord) []GradeRow {
+	var rows []GradeRow
+	index := make(map[string]int)
+	for _, rec := range records {
+		key := rec.Term + "/" + rec.CourseID
+		i, ok := index[key]
+		if !ok {
+			i = len(rows)
+			index[key] = i
+			rows = append(rows, GradeRow{Term: rec.Term, CourseID: rec.CourseID})
+		}
+		if rec.Type == "midterm" {
+			rows[i].Midterm = rec.Grade
+		} else {
+			rows[i].Final = rec.Grade
+		}
+	}
+	return rows
 }
 
-// --- HTML Templates ---
-const loginHTML = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <title>University Login</title>
-    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/@picocss/pico@1/css/pico.min.css">
-</head>
-<body>
-    <main class="container">
-        <article style="max-width: 400px; margin: auto;">
-            <header><hgroup><h2>Welcome Back</h2><h3>University Portal</h3></hgroup></header>
-            <form action="/login" method="POST">
-                <input type="text" name="username" placeholder="Username" required>
-                <input type="password" name="password" placeholder="Password" required>
-                <button type="submit" class="contrast">Log In</button>
-            </form>
-        </article>
-    </main>
-</body>
-</html>
-`
-
-const dashboardHTML = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <title>Dashboard</title>
-    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/@picocss/pico@1/css/pico.min.css">
-    <style>
-        .status-down { border-left: 5px solid #e74c3c; background-color: #2c0b0e; padding: 15px; margin-bottom: 20px;}
-        .course-card { padding: 10px; border-bottom: 1px solid #333; display: flex; justify-content: space-between; align-items: center; }
-        .enrolled-badge { color: #2ecc71; font-weight: bold; border: 1px solid #2ecc71; padding: 5px 10px; border-radius: 4px; }
-    </style>
-</head>
-<body>
-    <nav class="container-fluid">
-        <ul><li><strong>University Portal</strong></li></ul>
-        <ul>
-            <li>User: {{.Username}} <mark>{{.Role}}</mark></li>
-            <li><a href="/logout" role="button" class="outline secondary">Logout</a></li>
-        </ul>
-    </nav>
-    <main class="container">
-        <div class="grid">
-
-            <article>
-                <header><h3>📚 Open Courses</h3></header>
-                {{if .CourseError}}
-                    <div class="status-down"><strong>⚠️ Course Service Offline</strong></div>
-                {{else}}
-                    {{range .Courses}}
-                        <div class="course-card">
-                            <div><strong>{{.ID}}</strong>: {{.Title}}<br><small>Slots: {{.OpenSlots}}</small></div>
-
-                            {{/* LOGIC: Only Students can Enroll */}}
-                            {{if eq $.Role "student"}}
-                                {{if .IsEnrolled}}
-                                    <button disabled class="outline" style="width: auto; padding: 5px 15px; font-size: 0.8rem; border-color: #2ecc71; color: #2ecc71;">✅ Enrolled</button>
-                                {{else if gt .OpenSlots 0}}
-                                    <form action="/enroll" method="POST" style="margin:0;">
-                                        <input type="hidden" name="course_id" value="{{.ID}}">
-                                        <button type="submit" style="width: auto; padding: 5px 15px; font-size: 0.8rem;">Enroll</button>
-                                    </form>
-                                {{else}}
-                                    <button disabled style="width: auto; padding: 5px 15px; font-size: 0.8rem;">Full</button>
-                                {{end}}
-                            {{else}}
-                                <button disabled class="outline" style="width: auto; padding: 5px 15px; font-size: 0.8rem;">View Only</button>
-                            {{end}}
-                        </div>
-                    {{end}}
-                {{end}}
-            </article>
-
-            <article>
-                {{if eq .Role "student"}}
-                    <header><h3>🎓 My Grades</h3></header>
-                    {{if .GradeError}}
-                        <div class="status-down"><strong>⚠️ Grading Service Offline</strong></div>
-                    {{else}}
-                        <table role="grid">
-                            <thead><tr><th>Course</th><th>Grade</th></tr></thead>
-                            <tbody>
-                                {{range .Grades}}
-                                <tr><td>{{.CourseID}}</td><td><strong>{{.Grade}}</strong></td></tr>
-                                {{else}}<tr><td colspan="2">No grades recorded.</td></tr>{{end}}
-                            </tbody>
-                        </table>
-                    {{end}}
-                {{end}}
-
-                {{if eq .Role "faculty"}}
-                    <header><h3>📝 Faculty Tools</h3></header>
-                    <h5>Upload New Grade</h5>
-                    <form action="/upload-grade" method="POST">
-                        <div class="grid">
-                            <input type="text" name="student_id" placeholder="Student ID" required>
-                            <input type="text" name="course_id" placeholder="Course ID" required>
-                            <input type="text" name="grade" placeholder="Grade" required>
-                        </div>
-                        <button type="submit" class="secondary">Submit Grade</button>
-                    </form>
-                {{end}}
-            </article>
-        </div>
-    </main>
-</body>
-</html>
-`
+// NotificationPrefs are the channels a student is notified on.
+type NotificationPrefs struct {
+	Email  bool `json:"email"`
+	Portal bool `json:"portal"`
+}
+
+// TermStanding is a student's academic standing in one term.
+type TermStanding struct {
+	Term     string  `json:"term"`
+	GPA      float64 `json:"gpa"`
+	Standing string  `json:"standing"` // "good", "deans_list" or "probation"
+	Reason   string  `json:"reason"`
+}
+
+// GradeScale lists the grades the Grade Service accepts, best first, and the
+// special codes (INC, W, ...) a final grade can be instead.
+type GradeScale struct {
+	Name   string `json:"name"`
+	Grades []struct {
+		Grade string `json:"grade"`
+	} `json:"grades"`
+	Codes []struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	} `json:"codes"`
+}
+
+type DashboardData struct {
+	Username     string             `json:"username"`
+	Role         string             `json:"role"`
+	Courses      []Course           `json:"courses"`
+	Teaching     []TaughtSection    `json:"teaching,omitempty"`
+	Filter       CourseFilter       `json:"filter,omitzero"`
+	Page         int                `json:"page"`
+	Pages        int                `json:"pages"`
+	TotalCourses int                `json:"total_courses"`
+	Departments  []Department       `json:"-"`
+	Grades       []GradeRow         `json:"grades,omitempty"`
+	Scale        GradeScale         `json:"grade_scale,omitzero"`
+	Embargoes    []EmbargoNotice    `json:"embargoes,omitempty"`
+	Standing     []TermStanding     `json:"standing,omitempty"`
+	Notices      []Notification     `json:"notifications,omitempty"`
+	Unread       int                `json:"unread_notifications,omitempty"`
+	NotifyPrefs  *NotificationPrefs `json:"notification_preferences,omitempty"`
+	GradeError   string             `json:"grade_error,omitempty"`
+	CourseError  string             `json:"course_error,omitempty"`
+	Warnings     []string           `json:"warnings,omitempty"`
+	Flash        *Flash             `json:"-"`
+	Refresh      int                `json:"-"` // Seconds between reloads, 0 for none
+}
 
 // --- Helpers ---
+
+// nodeClient is shared by every fetchFromNode call so connections to the
+// backends are reused. Timeouts come from the request context.
+var nodeClient = &http.Client{Transport: mtls.Transport}
+
+// fetchFromNode GETs url as the token's owner and decodes the JSON answer,
+// retrying through the backend's circuit breaker.
 func fetchFromNode(url string, token string, target interface{}) error {
-	client := http.Client{Timeout: 2 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status code %d", resp.StatusCode)
-	}
-	return json.NewDecoder(resp.Body).Decode(target)
+	return fetchFromNodeCtx(context.Background(), url, token, target)
+}
+
+// fetchFromNodeCtx is fetchFromNode that also gives up when ctx is done.
+func fetchFromNodeCtx(ctx context.Context, url string, token string, target interface{}) error {
+	return withRetries(ctx, url, func(ctx context.Context) (bool, error) {
+		req, err := httpjson.NewRequest(ctx, http.MethodGet, url, token, nil)
+		if err != nil {
+			return false, err
+		}
+		err = httpjson.Do(nodeClient, req, target)
+		var refused *httpjson.StatusError
+		if errors.As(err, &refused) {
+			return refused.StatusCode >= 500, err
+		}
+		var unreachable net.Error // Transport failures; a bad body is not worth a retry
+		return errors.As(err, &unreachable), err
+	})
 }
 
 // --- Handlers ---
 func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	cookieToken, err := r.Cookie("session_token")
-	cookieUser, _ := r.Cookie("username")
-	cookieRole, _ := r.Cookie("role")
-
-	if err != nil || cookieUser == nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	sess, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/logout", http.StatusSeeOther)
 		return
 	}
 
-	// Validate Token
-	authURL := os.Getenv("AUTH_SERVICE_URL")
-	if authURL == "" {
-		authURL = "http://localhost:8081"
+	var version string
+	if v, err := r.Cookie("enroll_version"); err == nil {
+		version = v.Value
 	}
-
-	client := http.Client{Timeout: 2 * time.Second}
-	req, _ := http.NewRequest("GET", authURL+"/validate", nil)
-	req.Header.Set("Authorization", "Bearer "+cookieToken.Value)
-	if resp, err := client.Do(req); err != nil || resp.StatusCode != 200 {
-		http.Redirect(w, r, "/logout", http.StatusSeeOther)
-		return
+	data := loadDashboard(r, sess, version)
+	data.Flash = takeFlash(sess)
+	for _, c := range data.Courses {
+		if c.WaitlistPosition > 0 {
+			data.Refresh = waitlistRefresh
+		}
 	}
 
-	data := DashboardData{Username: cookieUser.Value, Role: cookieRole.Value}
+	render(w, "dashboard", data)
+}
 
-	// 1. Fetch Courses (Everyone sees courses)
-	courseURL := os.Getenv("COURSE_SERVICE_URL")
-	if courseURL == "" {
-		courseURL = "http://localhost:8082"
-	}
-	if err := fetchFromNode(courseURL+"/courses?student_id="+cookieUser.Value, cookieToken.Value, &data.Courses); err != nil {
-		data.CourseError = "Service Unreachable"
+// dashboardTimeout bounds how long the dashboard waits on the backends.
+// Whatever has not arrived by then is shown as unreachable.
+const dashboardTimeout = 3 * time.Second
+
+// loadDashboard gathers what the user's dashboard shows, fetching courses,
+// grades and notifications at the same time. version is the last
+// enrollment's read-your-writes token, if any.
+func loadDashboard(r *http.Request, sess *Session, version string) DashboardData {
+	ctx, cancel := context.WithTimeout(r.Context(), dashboardTimeout)
+	defer cancel()
+	data := DashboardData{Username: sess.Username, Role: sess.Role, Filter: courseFilter(r, sess)}
+
+	// Each half fills its own fields; only the warnings need merging
+	var courseWarnings, gradeWarnings []string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		courseWarnings = loadCourses(ctx, r, sess, version, &data)
+	}()
+	go func() {
+		defer wg.Done()
+		gradeWarnings = loadGrades(ctx, r, sess, &data)
+	}()
+	// Notifications are best-effort: a failure just leaves them out
+	if data.Role == "student" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data.Notices, _ = loadInbox(ctx, r, sess)
+			data.Unread = unread(data.Notices)
+		}()
 	}
+	wg.Wait()
+	data.Warnings = append(courseWarnings, gradeWarnings...)
+	return data
+}
 
-	// 2. Fetch Grades (ONLY IF STUDENT)
-	// Optimization: Don't bother calling Node 4 for grades if we are Faculty
+// loadCourses fills in the course list. Everyone sees courses.
+func loadCourses(ctx context.Context, r *http.Request, sess *Session, version string, data *DashboardData) (warnings []string) {
+	courseTarget, courseURL := routeFor("course", r, sess.Username)
+	query := url.Values{}
 	if data.Role == "student" {
-		gradeURL := os.Getenv("GRADE_SERVICE_URL")
-		if gradeURL == "" {
-			gradeURL = "http://localhost:8083"
+		query.Set("student_id", sess.Username)
+	}
+	data.Filter.addTo(query)
+	coursesPath := "/courses?" + query.Encode()
+	// Read-your-writes: after an enrollment, ask for a view that includes it
+	var versionPath string
+	if version != "" && data.Role == "student" {
+		versionPath = "&min_version=" + url.QueryEscape(version)
+	}
+	// A node the health poller already knows is down is routed around
+	// (fetchCatalog serves the cached list instead of waiting out the timeout)
+	status := backendStatus("course")
+	if status == "degraded" {
+		warnings = append(warnings, "Course Service is degraded: some features may be unavailable")
+	}
+	var err error
+	if versionPath != "" && status != "down" {
+		start := time.Now()
+		err = fetchFromNodeCtx(ctx, courseURL+coursesPath+versionPath, sess.Token, &data.Courses)
+		trackCall("course", courseTarget, start, err)
+		if err == nil {
+			storeCatalog(coursesPath, data.Courses)
 		}
-		if err := fetchFromNode(gradeURL+"/grades?student_id="+cookieUser.Value, cookieToken.Value, &data.Grades); err != nil {
+	}
+	if versionPath == "" || err != nil {
+		var stale time.Time
+		stale, err = fetchCatalog(ctx, courseTarget, courseURL, coursesPath, sess.Token, &data.Courses)
+		switch {
+		case err != nil:
+			data.CourseError = "Service Unreachable"
+			return warnings
+		case !stale.IsZero():
+			warnings = append(warnings, "Course Service Unreachable: showing the course list from "+stale.Format("15:04:05"))
+		case versionPath != "":
+			// The node could not catch up in time; show what it has rather than nothing
+			warnings = append(warnings, "Your latest enrollment may take a moment to appear")
+		}
+	}
+	paginate(r, data)
+	// Only fills the department filter; without it the filter lists none
+	fetchCatalog(ctx, courseTarget, courseURL, "/departments", sess.Token, &data.Departments)
+	if data.Role == "faculty" {
+		if status == "down" || fetchFromNodeCtx(ctx, courseURL+"/my-courses", sess.Token, &data.Teaching) != nil {
+			warnings = append(warnings, "Your sections could not be loaded")
+		}
+	}
+	return warnings
+}
+
+// loadGrades fills in a student's grades and what goes with them, or the
+// grade scale for a faculty member's upload form.
+func loadGrades(ctx context.Context, r *http.Request, sess *Session, data *DashboardData) (warnings []string) {
+	gradeTarget, gradeURL := routeFor("grade", r, sess.Username)
+
+	// The grade scale is best-effort: the upload form falls back to a text
+	// field and the Grade Service still validates.
+	if data.Role == "faculty" && backendStatus("grade") != "down" {
+		fetchFromNodeCtx(ctx, gradeURL+"/grade-scale", sess.Token, &data.Scale)
+	}
+	// Optimization: Don't bother calling Node 4 for grades if we are Faculty
+	if data.Role != "student" {
+		return warnings
+	}
+	switch backendStatus("grade") {
+	case "down":
+		data.GradeError = "Service Unreachable"
+		return warnings
+	case "degraded":
+		warnings = append(warnings, "Grading Service is degraded: grades may be incomplete")
+	}
+
+	var wg sync.WaitGroup
+	fetch := func(path string, target interface{}, done func(err error)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done(fetchFromNodeCtx(ctx, gradeURL+path, sess.Token, target))
+		}()
+	}
+
+	start := time.Now()
+	var records []GradeRecord
+	fetch("/grades?student_id="+sess.Username, &records, func(err error) {
+		trackCall("grade", gradeTarget, start, err)
+		if err != nil {
 			data.GradeError = "Service Unreachable"
 		}
+		data.Grades = gradeRows(records)
+	})
+
+	var prefs NotificationPrefs
+	fetch("/notifications/preferences", &prefs, func(err error) {
+		if err == nil {
+			data.NotifyPrefs = &prefs
+		}
+	})
+
+	// Standing is best-effort: a failure just hides the table
+	var standing struct {
+		Terms []TermStanding `json:"terms"`
 	}
+	fetch("/standing?student_id="+sess.Username, &standing, func(err error) {
+		if err == nil {
+			data.Standing = standing.Terms
+		}
+	})
 
-	tmpl, _ := template.New("dash").Parse(dashboardHTML)
-	tmpl.Execute(w, data)
+	// The release calendar is best-effort: a failure just hides the countdown
+	var releases []TermRelease
+	fetch("/terms/release-dates", &releases, func(err error) {
+		if err == nil {
+			data.Embargoes = upcomingReleases(releases, time.Now())
+		}
+	})
+	wg.Wait()
+	return warnings
+}
+
+type LoginData struct {
+	Next     string
+	Verified bool // Arrived from a signup verification link
+	Revoked  bool // Just logged out everywhere
 }
 
 func loginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "GET" {
-		tmpl, _ := template.New("login").Parse(loginHTML)
-		tmpl.Execute(w, nil)
+		render(w, "login", LoginData{Next: r.URL.Query().Get("next"), Verified: r.URL.Query().Has("verified"), Revoked: r.URL.Query().Has("revoked")})
 		return
 	}
 	username := r.FormValue("username")
 	password := r.FormValue("password")
-	authURL := os.Getenv("AUTH_SERVICE_URL")
-	if authURL == "" {
-		authURL = "http://localhost:8081"
-	}
+	authURL := backendURL("auth")
 
 	jsonData, _ := json.Marshal(map[string]string{"username": username, "password": password})
-	resp, err := http.Post(authURL+"/login", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := nodeClient.Post(authURL+"/login", "application/json", bytes.NewBuffer(jsonData))
 
 	if err != nil || resp.StatusCode != 200 {
 		http.Error(w, "Login Failed", http.StatusUnauthorized)
@@ -244,65 +350,268 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	var result map[string]string
 	json.NewDecoder(resp.Body).Decode(&result)
 
-	expire := time.Now().Add(1 * time.Hour)
-	http.SetCookie(w, &http.Cookie{Name: "session_token", Value: result["token"], Path: "/", Expires: expire})
-	http.SetCookie(w, &http.Cookie{Name: "username", Value: username, Path: "/", Expires: expire})
-	http.SetCookie(w, &http.Cookie{Name: "role", Value: result["role"], Path: "/", Expires: expire})
-	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+	if err := startSession(w, result["token"]); err != nil {
+		http.Error(w, "Login Failed", http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, safeNext(r.FormValue("next")), http.StatusSeeOther)
 }
 
 func enrollHandler(w http.ResponseWriter, r *http.Request) {
-	cookieUser, _ := r.Cookie("username")
-	courseURL := os.Getenv("COURSE_SERVICE_URL")
-	if courseURL == "" {
-		courseURL = "http://localhost:8082"
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	courseTarget, courseURL := routeFor("course", r, sess.Username)
+
+	// Sections with co-requisites post several course_ids and must be enrolled as one batch
+	r.ParseForm()
+	endpoint := "/enroll"
+	var payload interface{} = map[string]string{"course_id": r.FormValue("course_id"), "student_id": sess.Username}
+	if ids := r.PostForm["course_id"]; len(ids) > 1 {
+		endpoint = "/enroll-batch"
+		payload = map[string]interface{}{"course_ids": ids, "student_id": sess.Username}
 	}
-
-	payload := map[string]string{"course_id": r.FormValue("course_id"), "student_id": cookieUser.Value}
 	jsonData, _ := json.Marshal(payload)
-	http.Post(courseURL+"/enroll", "application/json", bytes.NewBuffer(jsonData))
+
+	// Forward the token so the Course Service can apply cohort seat pools
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("POST", courseURL+endpoint, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+		// Carry the write's version to the dashboard so it shows the new enrollment
+		if v := resp.Header.Get("X-Enrollment-Version"); v != "" {
+			setCookie(w, &http.Cookie{Name: "enroll_version", Value: v, Path: "/", MaxAge: 300})
+		}
+	}
+	trackCall("course", courseTarget, start, err)
+	invalidateCatalog()
+	if err != nil {
+		setFlash(sess, Flash{Kind: "error", Message: "Enrollment is unavailable right now. Please try again."})
+	} else {
+		setFlash(sess, enrollmentMessage(resp, r.PostForm["course_id"]))
+	}
 	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
 func uploadGradeHandler(w http.ResponseWriter, r *http.Request) {
-	cookieToken, _ := r.Cookie("session_token")
-	gradeURL := os.Getenv("GRADE_SERVICE_URL")
-	if gradeURL == "" {
-		gradeURL = "http://localhost:8083"
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
 	}
+	gradeTarget, gradeURL := routeFor("grade", r, sess.Username)
 
 	data := map[string]string{
 		"student_id": r.FormValue("student_id"),
 		"course_id":  r.FormValue("course_id"),
 		"grade":      r.FormValue("grade"),
+		"type":       r.FormValue("type"),
+		"reason":     r.FormValue("reason"),
 	}
 	jsonData, _ := json.Marshal(data)
 
-	client := http.Client{}
+	client := http.Client{Transport: mtls.Transport}
 	req, _ := http.NewRequest("POST", gradeURL+"/upload-grade", bytes.NewBuffer(jsonData))
-	req.Header.Set("Authorization", "Bearer "+cookieToken.Value)
-	client.Do(req)
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+	}
+	trackCall("grade", gradeTarget, start, err)
 
 	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
+// releaseGradesHandler publishes a course's grades for the current term.
+func releaseGradesHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	gradeTarget, gradeURL := routeFor("grade", r, sess.Username)
+
+	jsonData, _ := json.Marshal(map[string]string{"course_id": r.FormValue("course_id"), "type": r.FormValue("type")})
+
+	client := http.Client{Transport: mtls.Transport}
+	req, _ := http.NewRequest("POST", gradeURL+"/grades/release", bytes.NewBuffer(jsonData))
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+	}
+	trackCall("grade", gradeTarget, start, err)
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// uploadGradesHandler sends a CSV of grades to the Grade Service. If any row
+// was rejected the faculty member gets the error report as a download.
+func uploadGradesHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a CSV file to upload", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	sendGradesCSV(w, r, sess.Username, sess.Token, file, "/dashboard")
+}
+
+// sendGradesCSV posts a CSV of grades to the Grade Service and relays the
+// outcome: a redirect to next if every row was recorded, otherwise the
+// rejected rows as a CSV download.
+func sendGradesCSV(w http.ResponseWriter, r *http.Request, username, token string, body io.Reader, next string) {
+	gradeTarget, gradeURL := routeFor("grade", r, username)
+
+	client := http.Client{Timeout: 10 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("POST", gradeURL+"/upload-grades?format=csv", body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "text/csv")
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode >= 500 {
+		resp.Body.Close()
+		err = fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	trackCall("grade", gradeTarget, start, err)
+	if err != nil {
+		http.Error(w, "Grading Service Unreachable", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		http.Error(w, strings.TrimSpace(string(body)), resp.StatusCode)
+		return
+	}
+	if resp.Header.Get("X-Grades-Rejected") == "0" {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	for _, h := range []string{"Content-Type", "Content-Disposition"} {
+		w.Header().Set(h, resp.Header.Get(h))
+	}
+	io.Copy(w, resp.Body)
+}
+
+// transcriptHandler streams the student's PDF transcript from the Grade Service.
+func transcriptHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	gradeTarget, gradeURL := routeFor("grade", r, sess.Username)
+
+	client := http.Client{Timeout: 5 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("GET", gradeURL+"/transcript?format=pdf&student_id="+url.QueryEscape(sess.Username), nil)
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode >= 500 {
+		resp.Body.Close()
+		err = fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	trackCall("grade", gradeTarget, start, err)
+	if err != nil {
+		http.Error(w, "Grading Service Unreachable", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range []string{"Content-Type", "Content-Disposition"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
 func logoutHandler(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{Name: "session_token", MaxAge: -1, Path: "/"})
+	endSession(w, r)
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
 func main() {
+	config.Load("portal", settings...)
+	logging.Setup("portal")
+	mtls.Setup()
+	tracing.Setup("portal")
 	http.HandleFunc("/login", loginHandler)
 	http.HandleFunc("/logout", logoutHandler)
+	http.HandleFunc("/logout/everywhere", logoutEverywhereHandler)
+	http.HandleFunc("/session", sessionStatusHandler)
+	http.HandleFunc("/signup", signupHandler)
+	http.HandleFunc("/signup/verify", verifySignupHandler)
 	http.HandleFunc("/dashboard", dashboardHandler)
 	http.HandleFunc("/enroll", enrollHandler)
+	http.HandleFunc("/drop", dropHandler)
+	http.HandleFunc("/profile", profileHandler)
+	http.HandleFunc("/schedule", scheduleHandler)
+	http.HandleFunc("/schedule.ics", scheduleICSHandler)
+	http.HandleFunc("/progress", progressHandler)
+	http.HandleFunc("/waitlist", waitlistHandler)
+	http.HandleFunc("/waitlist/leave", waitlistHandler)
+	http.HandleFunc("/seats/stream", seatStreamHandler)
+	http.Handle("/static/", staticHandler())
 	http.HandleFunc("/upload-grade", uploadGradeHandler)
+	http.HandleFunc("/upload-grades", uploadGradesHandler)
+	http.HandleFunc("/release-grades", releaseGradesHandler)
+	http.HandleFunc("/grading-sheet", gradingSheetHandler)
+	http.HandleFunc("/roster", rosterHandler)
+	http.HandleFunc("/section-stats", sectionStatsHandler)
+	http.HandleFunc("/transcript", transcriptHandler)
+	http.HandleFunc("/notifications", notificationsHandler)
+	http.HandleFunc("/notifications/read", markReadHandler)
+	http.HandleFunc("/notifications/accept", acceptOfferHandler)
+	http.HandleFunc("/notification-preferences", notificationPrefsHandler)
+	http.HandleFunc("/settings", settingsHandler)
+	http.HandleFunc("/healthz", health.Liveness)
+	http.HandleFunc("/readyz", readyz)
+	http.Handle("/metrics", metrics.Handler())
+	http.HandleFunc("/canary/metrics", canaryMetricsHandler)
+	http.HandleFunc("/admin/trace", adminTraceHandler)
+	http.HandleFunc("/ops", opsHandler)
+	http.HandleFunc("/status", statusHandler)
+	http.HandleFunc("/admin", adminHandler)
+	http.HandleFunc("/oidc/consent", consentHandler)
+	http.HandleFunc("/api/v1/login", apiLogin)
+	http.HandleFunc("/api/v1/dashboard", apiDashboard)
+	http.HandleFunc("/api/v1/enroll", apiEnroll)
+	http.HandleFunc("/api/v1/drop", apiDrop)
+	http.HandleFunc("/api/v1/grades", apiGrades)
+	http.HandleFunc("/api/v1/grades/release", apiReleaseGrades)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/login", http.StatusSeeOther) })
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	port := config.Get("PORT")
+	go pollBackends(5 * time.Second)
+	server.OnShutdown("tracing", tracing.Shutdown)
+
+	if err := serve(port, tracing.Middleware(logging.Middleware(withSecurityHeaders(withSupportTracing(metrics.Middleware(http.DefaultServeMux)))))); err != nil {
+		log.Fatal(err)
 	}
-	fmt.Printf("Node 1 (Portal) running on port %s...\n", port)
-	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, nil))
 }
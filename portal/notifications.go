@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Notifications ---
+
+// Students get notes from two services: the Grade Service when a grade is
+// posted, and the Course Service for waitlist seat offers and deadline
+// reminders. The portal merges both inboxes behind the bell on the
+// dashboard and on /notifications. Each service keeps its notes' read
+// state, so marking a note read goes back to the service it came from.
+
+// Notification is one note in a student's inbox.
+type Notification struct {
+	ID       string    `json:"id"`
+	Source   string    `json:"source"` // "course" or "grade", filled in by the portal
+	Type     string    `json:"type"`
+	CourseID string    `json:"course_id,omitempty"`
+	HoldID   string    `json:"hold_id,omitempty"` // Waitlist offers only
+	Message  string    `json:"message"`
+	At       time.Time `json:"at"`
+	Read     bool      `json:"read"`
+}
+
+// inboxSources are the services with a /notifications inbox.
+var inboxSources = []string{"course", "grade"}
+
+// loadInbox fetches and merges the student's inboxes, newest first. It
+// names the services that could not be reached; their notes are missing.
+func loadInbox(ctx context.Context, r *http.Request, sess *Session) (inbox []Notification, unreachable []string) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, source := range inboxSources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, baseURL := routeFor(source, r, sess.Username)
+			var notes []Notification
+			err := fetchFromNodeCtx(ctx, baseURL+"/notifications", sess.Token, &notes)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				unreachable = append(unreachable, source)
+				return
+			}
+			for _, n := range notes {
+				n.Source = source
+				inbox = append(inbox, n)
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Slice(inbox, func(i, j int) bool { return inbox[i].At.After(inbox[j].At) })
+	sort.Strings(unreachable)
+	return inbox, unreachable
+}
+
+// unread counts the notes not yet read.
+func unread(inbox []Notification) int {
+	n := 0
+	for _, note := range inbox {
+		if !note.Read {
+			n++
+		}
+	}
+	return n
+}
+
+type NotificationsData struct {
+	Username string
+	Inbox    []Notification
+	Unread   int
+	Warnings []string
+	Flash    *Flash
+}
+
+// notificationsHandler shows the inbox.
+func notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "student" {
+		http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), dashboardTimeout)
+	defer cancel()
+	inbox, unreachable := loadInbox(ctx, r, sess)
+	data := NotificationsData{Username: sess.Username, Inbox: inbox, Unread: unread(inbox), Flash: takeFlash(sess)}
+	for _, source := range unreachable {
+		data.Warnings = append(data.Warnings, "Notifications from the "+strings.ToUpper(source[:1])+source[1:]+" Service could not be loaded")
+	}
+	render(w, "notifications", data)
+}
+
+// markReadHandler marks one note read (source and id), or every note when
+// neither is given.
+func markReadHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sources, ids := inboxSources, []string{}
+	if id := r.FormValue("id"); id != "" {
+		sources, ids = []string{r.FormValue("source")}, []string{id}
+	}
+	for _, source := range sources {
+		_, baseURL := routeFor(source, r, sess.Username)
+		if err := callNode("POST", baseURL+"/notifications/read", sess.Token, "", map[string][]string{"ids": ids}); err != nil {
+			setFlash(sess, Flash{Kind: "error", Message: "Some notifications could not be marked as read. Please try again."})
+		}
+	}
+	http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+}
+
+// acceptOfferHandler takes the seat a waitlist offer holds for the student.
+func acceptOfferHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	courseID := r.FormValue("course_id")
+	_, courseURL := routeFor("course", r, sess.Username)
+	err = callNode("POST", courseURL+"/holds/confirm", sess.Token, "", map[string]string{"hold_id": r.FormValue("hold_id")})
+	invalidateCatalog()
+	if err != nil {
+		setFlash(sess, Flash{Kind: "error", Message: courseID + ": " + err.Error()})
+	} else {
+		setFlash(sess, Flash{Kind: "success", Message: "You are enrolled in " + courseID + "."})
+		callNode("POST", courseURL+"/notifications/read", sess.Token, "", map[string][]string{"ids": {r.FormValue("id")}})
+	}
+	http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+}
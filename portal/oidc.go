@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- OIDC Consent ---
+
+// The Auth Service acts as an OpenID Connect provider for campus apps and
+// sends users here to approve or deny sharing their identity.
+
+type ConsentData struct {
+	Username    string
+	ClientName  string
+	ClientID    string
+	RedirectURI string
+	Scope       string
+	Scopes      []string
+	State       string
+	Nonce       string
+	CSRF        string
+}
+
+// safeNext only allows same-site paths as post-login redirect targets.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/dashboard"
+	}
+	return next
+}
+
+func consentHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		var client struct {
+			Name string `json:"name"`
+		}
+		if err := fetchFromNode(backendURL("auth")+"/oidc/clients/info?client_id="+url.QueryEscape(q.Get("client_id")), user.Token, &client); err != nil {
+			http.Error(w, "Unknown application", http.StatusBadRequest)
+			return
+		}
+
+		// Double-submit token so another site cannot post a consent on the user's behalf
+		b := make([]byte, 16)
+		rand.Read(b)
+		csrf := hex.EncodeToString(b)
+		setCookie(w, &http.Cookie{Name: "oidc_csrf", Value: csrf, Path: "/oidc/consent", Expires: time.Now().Add(10 * time.Minute), SameSite: http.SameSiteStrictMode})
+
+		data := ConsentData{
+			Username:    user.Username,
+			ClientName:  client.Name,
+			ClientID:    q.Get("client_id"),
+			RedirectURI: q.Get("redirect_uri"),
+			Scope:       q.Get("scope"),
+			Scopes:      strings.Fields(q.Get("scope")),
+			State:       q.Get("state"),
+			Nonce:       q.Get("nonce"),
+			CSRF:        csrf,
+		}
+		render(w, "consent", data)
+
+	case http.MethodPost:
+		csrfCookie, err := r.Cookie("oidc_csrf")
+		if err != nil || subtle.ConstantTimeCompare([]byte(csrfCookie.Value), []byte(r.FormValue("csrf"))) != 1 {
+			http.Error(w, "Consent form expired, please try again", http.StatusForbidden)
+			return
+		}
+		setCookie(w, &http.Cookie{Name: "oidc_csrf", MaxAge: -1, Path: "/oidc/consent"})
+
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"client_id":    r.FormValue("client_id"),
+			"redirect_uri": r.FormValue("redirect_uri"),
+			"scope":        r.FormValue("scope"),
+			"state":        r.FormValue("state"),
+			"nonce":        r.FormValue("nonce"),
+			"approve":      r.FormValue("decision") == "approve",
+		})
+		client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+		req, _ := http.NewRequest("POST", backendURL("auth")+"/oidc/authorize", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+user.Token)
+		resp, err := client.Do(req)
+		if err != nil {
+			http.Error(w, "Auth Service Unreachable", http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			http.Error(w, "Authorization request rejected", http.StatusBadRequest)
+			return
+		}
+		var result map[string]string
+		json.NewDecoder(resp.Body).Decode(&result)
+		http.Redirect(w, r, result["redirect"], http.StatusFound)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- Ops Dashboard ---
+
+type hourLoad struct {
+	Hour              time.Time `json:"hour"`
+	Logins            int64     `json:"logins"`
+	FailedLogins      int64     `json:"failed_logins"`
+	TokensIssued      int64     `json:"tokens_issued"`
+	Validations       int64     `json:"validations"`
+	PeakValidationQPS int64     `json:"peak_validation_qps"`
+}
+
+type CapacityReport struct {
+	TotalLogins         int64      `json:"total_logins"`
+	TotalTokensIssued   int64      `json:"total_tokens_issued"`
+	TotalValidations    int64      `json:"total_validations"`
+	AvgTokensPerHour    float64    `json:"avg_tokens_per_hour"`
+	AvgValidationQPS    float64    `json:"avg_validation_qps"`
+	PeakValidationQPS   int64      `json:"peak_validation_qps"`
+	PeakLoginHour       *time.Time `json:"peak_login_hour"`
+	PeakLoginHourLogins int64      `json:"peak_login_hour_logins"`
+	Hourly              []hourLoad `json:"hourly"`
+}
+
+type OpsData struct {
+	Username    string
+	Report      CapacityReport
+	ReportError string
+}
+
+func opsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+
+	data := OpsData{Username: user.Username}
+	if err := fetchFromNode(backendURL("auth")+"/reports/capacity?days=7", user.Token, &data.Report); err != nil {
+		data.ReportError = "Service Unreachable"
+	}
+
+	render(w, "ops", data)
+}
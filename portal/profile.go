@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// --- Profile ---
+
+// /profile shows who the user is to the registrar: program, year level,
+// advisor and contact email from the Auth Service, and for students any
+// registration holds from the Course Service. The contact email is changed
+// in /settings; the rest is kept by the registrar.
+
+type Profile struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Program   string `json:"program"`
+	YearLevel int    `json:"year_level"`
+	Email     string `json:"email"`
+	Advisor   string `json:"advisor"`
+}
+
+type ProfileData struct {
+	Username  string
+	Role      string
+	Profile   Profile
+	Holds     []AdminHold
+	Error     string
+	HoldError string
+}
+
+// profileHandler shows the profile.
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := ProfileData{Username: sess.Username, Role: sess.Role}
+	if err := fetchFromNode(backendURL("auth")+"/profile", sess.Token, &data.Profile); err != nil {
+		data.Error = "Auth Service Unreachable"
+	}
+	if sess.Role == "student" {
+		_, courseURL := routeFor("course", r, sess.Username)
+		if err := fetchFromNode(courseURL+"/registration-holds?student_id="+url.QueryEscape(sess.Username), sess.Token, &data.Holds); err != nil {
+			data.HoldError = "Course Service Unreachable"
+		}
+	}
+	render(w, "profile", data)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// --- Degree Progress ---
+
+// /progress is a student's checklist of their program's requirements from
+// the Grade Service's degree audit, with the credits still to earn. The
+// program comes from the student's profile. Requirements the student is
+// taking this term, by the Course Service's /schedule, show as in progress.
+
+type RequirementStatus struct {
+	Name        string   `json:"name"`
+	Courses     []string `json:"courses"`
+	Done        bool     `json:"done"`
+	CompletedBy string   `json:"completed_by"`
+	Grade       string   `json:"grade"`
+	Term        string   `json:"term"`
+	InProgress  string   `json:"-"` // The enrolled course that would complete it
+}
+
+type DegreeAudit struct {
+	Program          string              `json:"program"`
+	ProgramName      string              `json:"program_name"`
+	TotalCredits     int                 `json:"total_credits"`
+	EarnedCredits    int                 `json:"earned_credits"`
+	CreditsRemaining int                 `json:"credits_remaining"`
+	Requirements     []RequirementStatus `json:"requirements"`
+}
+
+type ProgressData struct {
+	Username string
+	Audit    DegreeAudit
+	Done     int
+	Error    string
+}
+
+// progressHandler shows the degree checklist.
+func progressHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "student" {
+		http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+		return
+	}
+
+	data := ProgressData{Username: sess.Username}
+	var profile Profile
+	if err := fetchFromNode(backendURL("auth")+"/profile", sess.Token, &profile); err != nil {
+		data.Error = "Auth Service Unreachable"
+		render(w, "progress", data)
+		return
+	}
+	if profile.Program == "" {
+		data.Error = "You have no program on record. Contact the registrar."
+		render(w, "progress", data)
+		return
+	}
+	_, gradeURL := routeFor("grade", r, sess.Username)
+	if err := fetchFromNode(gradeURL+"/degree-audit?student_id="+url.QueryEscape(sess.Username)+"&program="+url.QueryEscape(profile.Program), sess.Token, &data.Audit); err != nil {
+		data.Error = "The degree audit for " + profile.Program + " could not be loaded"
+		render(w, "progress", data)
+		return
+	}
+
+	// The schedule only marks work in progress; the checklist stands without it
+	enrolled := map[string]bool{}
+	if s, err := fetchSchedule(r, sess); err == nil {
+		for _, sec := range s.Sections {
+			code, _, _ := strings.Cut(sec.CourseID, "@") // Offering IDs are CODE@TERM
+			enrolled[code] = true
+		}
+	}
+	for i, req := range data.Audit.Requirements {
+		if req.Done {
+			data.Done++
+			continue
+		}
+		if j := slices.IndexFunc(req.Courses, func(code string) bool { return enrolled[code] }); j >= 0 {
+			data.Audit.Requirements[i].InProgress = req.Courses[j]
+		}
+	}
+	render(w, "progress", data)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shared/models"
+)
+
+// --- Weekly Schedule ---
+
+// /schedule draws a student's enrolled sections on a week grid and
+// /schedule.ics exports the same meetings as weekly repeating events for
+// Google or Apple Calendar. Both come from the Course Service's /schedule.
+// Meeting times are campus-local, so the calendar file uses floating times
+// that show at the same clock time in any calendar.
+
+type Meeting = models.Meeting
+
+type ScheduledSection struct {
+	CourseID   string    `json:"course_id"`
+	Title      string    `json:"title"`
+	Instructor string    `json:"instructor"`
+	Meetings   []Meeting `json:"meetings"`
+}
+
+type Schedule struct {
+	Term         string             `json:"term"`
+	ClassesStart *time.Time         `json:"classes_start"`
+	ClassesEnd   *time.Time         `json:"classes_end"`
+	Sections     []ScheduledSection `json:"sections"`
+}
+
+// The grid has one row per slotMinutes, from gridFirstHour to gridLastHour
+// or wider if a meeting falls outside them.
+const (
+	slotMinutes   = 15
+	gridFirstHour = 8
+	gridLastHour  = 18
+	termWeeks     = 14 // Repeats in the export when CLASSES_END is unset
+)
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday, "Wed": time.Wednesday,
+	"Thu": time.Thursday, "Fri": time.Friday, "Sat": time.Saturday,
+}
+
+// ScheduleBlock is one meeting placed on the grid. Rows and columns are CSS
+// grid lines: row 1 and column 1 hold the headings.
+type ScheduleBlock struct {
+	Meeting
+	CourseID string
+	Title    string
+	Column   int
+	Row      int
+	Span     int
+}
+
+type ScheduleHour struct {
+	Label string
+	Row   int
+	Span  int
+}
+
+type ScheduleData struct {
+	Username    string
+	Term        string
+	Days        []string
+	Hours       []ScheduleHour
+	Blocks      []ScheduleBlock
+	Unscheduled []string // Enrolled sections with no meeting times
+	Error       string
+}
+
+// minutes turns "09:15" into minutes after midnight.
+func minutes(clock string) int {
+	t, _ := time.Parse("15:04", clock)
+	return t.Hour()*60 + t.Minute()
+}
+
+// layoutSchedule places every meeting on the week grid. Saturday and Sunday
+// only get a column when something meets on them.
+func layoutSchedule(s Schedule) ScheduleData {
+	data := ScheduleData{Term: s.Term, Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}}
+	first, last := gridFirstHour, gridLastHour
+	used := map[string]bool{}
+	for _, sec := range s.Sections {
+		if len(sec.Meetings) == 0 {
+			data.Unscheduled = append(data.Unscheduled, sec.CourseID)
+		}
+		for _, m := range sec.Meetings {
+			first = min(first, minutes(m.Start)/60)
+			last = max(last, (minutes(m.End)+59)/60)
+			used[m.Day] = true
+		}
+	}
+	for _, day := range []string{"Sat", "Sun"} {
+		if used[day] {
+			data.Days = append(data.Days, day)
+		}
+	}
+	column := map[string]int{}
+	for i, day := range data.Days {
+		column[day] = i + 2
+	}
+	row := func(clock string) int { return (minutes(clock)-first*60)/slotMinutes + 2 }
+
+	for h := first; h < last; h++ {
+		data.Hours = append(data.Hours, ScheduleHour{Label: fmt.Sprintf("%02d:00", h), Row: (h-first)*60/slotMinutes + 2, Span: 60 / slotMinutes})
+	}
+	for _, sec := range s.Sections {
+		for _, m := range sec.Meetings {
+			start, end := row(m.Start), row(m.End)
+			data.Blocks = append(data.Blocks, ScheduleBlock{Meeting: m, CourseID: sec.CourseID, Title: sec.Title,
+				Column: column[m.Day], Row: start, Span: max(end-start, 1)})
+		}
+	}
+	return data
+}
+
+// fetchSchedule loads the logged-in student's schedule for the current term.
+func fetchSchedule(r *http.Request, sess *Session) (Schedule, error) {
+	_, courseURL := routeFor("course", r, sess.Username)
+	var s Schedule
+	err := fetchFromNode(courseURL+"/schedule?student_id="+url.QueryEscape(sess.Username), sess.Token, &s)
+	return s, err
+}
+
+// scheduleHandler shows the week grid.
+func scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "student" {
+		http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+		return
+	}
+	s, err := fetchSchedule(r, sess)
+	data := layoutSchedule(s)
+	if err != nil {
+		data.Error = "Course Service Unreachable"
+	}
+	data.Username = sess.Username
+	render(w, "schedule", data)
+}
+
+// scheduleICSHandler exports the schedule as an iCalendar file.
+func scheduleICSHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if sess.Role != "student" {
+		http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+		return
+	}
+	s, err := fetchSchedule(r, sess)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="schedule-`+s.Term+`.ics"`)
+	w.Write([]byte(scheduleICS(s, time.Now())))
+}
+
+// scheduleICS writes one weekly repeating event per meeting, from the first
+// day of classes (or this week) to the last (or termWeeks later).
+func scheduleICS(s Schedule, now time.Time) string {
+	y, m, d := now.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(int(now.Weekday())+6)%7) // This week's Monday
+	if s.ClassesStart != nil {
+		y, m, d = s.ClassesStart.Date()
+		from = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	until := from.AddDate(0, 0, 7*termWeeks-1)
+	if s.ClassesEnd != nil {
+		y, m, d = s.ClassesEnd.Date()
+		until = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//University Portal//Schedule//EN", "CALSCALE:GREGORIAN", "X-WR-CALNAME:Classes " + icsText(s.Term)}
+	for _, sec := range s.Sections {
+		for _, mt := range sec.Meetings {
+			day := from.AddDate(0, 0, (int(weekdays[mt.Day])-int(from.Weekday())+7)%7)
+			start := day.Add(time.Duration(minutes(mt.Start)) * time.Minute)
+			end := day.Add(time.Duration(minutes(mt.End)) * time.Minute)
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				"UID:"+strings.ToLower(sec.CourseID+"-"+s.Term+"-"+mt.Day+"-"+strings.ReplaceAll(mt.Start, ":", ""))+"@university-portal",
+				"DTSTAMP:"+now.UTC().Format("20060102T150405Z"),
+				"DTSTART:"+start.Format("20060102T150405"),
+				"DTEND:"+end.Format("20060102T150405"),
+				"RRULE:FREQ=WEEKLY;UNTIL="+until.Format("20060102")+"T235959",
+				"SUMMARY:"+icsText(sec.CourseID+" "+sec.Title),
+			)
+			if mt.Room != "" {
+				lines = append(lines, "LOCATION:"+icsText(mt.Room))
+			}
+			if sec.Instructor != "" {
+				lines = append(lines, "DESCRIPTION:"+icsText("Instructor: "+sec.Instructor))
+			}
+			lines = append(lines, "END:VEVENT")
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icsFold(line))
+	}
+	return b.String()
+}
+
+// icsText escapes a TEXT value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsFold ends a content line with CRLF, folding it so no line is longer
+// than 75 bytes and no character is split.
+func icsFold(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
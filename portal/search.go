@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// --- Course Search ---
+
+// The dashboard's search box and filters narrow the course list through the
+// Course Service's /courses query parameters. The last selection is kept in
+// the session, so it survives enrolling, dropping and reloading until the
+// student changes or clears it.
+
+type CourseFilter struct {
+	Query        string `json:"q,omitempty"`
+	DepartmentID string `json:"department_id,omitempty"`
+	Credits      int    `json:"credits,omitempty"`
+	OpenOnly     bool   `json:"open,omitempty"`
+}
+
+type Department struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// filterParams are the query parameters that set a new filter.
+var filterParams = []string{"q", "department_id", "credits", "open"}
+
+// courseFilter returns the filter the request asks for, remembering it in
+// the session, or the session's last filter if the request names none.
+func courseFilter(r *http.Request, sess *Session) CourseFilter {
+	q := r.URL.Query()
+	given := false
+	for _, p := range filterParams {
+		given = given || q.Has(p)
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if !given {
+		return sess.Filter
+	}
+	credits, _ := strconv.Atoi(q.Get("credits"))
+	sess.Filter = CourseFilter{
+		Query:        strings.TrimSpace(q.Get("q")),
+		DepartmentID: q.Get("department_id"),
+		Credits:      credits,
+		OpenOnly:     q.Get("open") == "true",
+	}
+	return sess.Filter
+}
+
+// addTo adds the filter to a /courses query.
+func (f CourseFilter) addTo(query url.Values) {
+	if f.Query != "" {
+		query.Set("q", f.Query)
+	}
+	if f.DepartmentID != "" {
+		query.Set("department_id", f.DepartmentID)
+	}
+	if f.Credits > 0 {
+		query.Set("credits", strconv.Itoa(f.Credits))
+	}
+	if f.OpenOnly {
+		query.Set("open", "true")
+	}
+}
+
+// Active reports whether the filter narrows the list at all.
+func (f CourseFilter) Active() bool {
+	return f != CourseFilter{}
+}
+
+// --- Pagination ---
+
+// The course list is cut into pages after it is fetched, so the template
+// renders at most one page of cards. ?per_page= (up to maxPerPage) is for
+// JSON API clients that load the next page as the user scrolls.
+const (
+	coursesPerPage = 25
+	maxPerPage     = 100
+)
+
+// paginate keeps one page of data.Courses, as asked for by ?page= and
+// ?per_page=, and records where that page is.
+func paginate(r *http.Request, data *DashboardData) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = coursesPerPage
+	}
+	perPage = min(perPage, maxPerPage)
+	data.TotalCourses = len(data.Courses)
+	data.Pages = max(1, (data.TotalCourses+perPage-1)/perPage)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	data.Page = max(page, 1)
+	// Past the last page is empty, which tells a scrolling client to stop
+	start := min((data.Page-1)*perPage, data.TotalCourses)
+	data.Courses = data.Courses[start:min(start+perPage, data.TotalCourses)]
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"shared/server"
+)
+
+// --- Live Seat Counts ---
+
+// The dashboard opens an EventSource on /seats/stream for the courses it
+// shows. The portal relays the Course Service's seat stream as it arrives,
+// so browsers never talk to the backend directly. Each update refreshes a
+// card's open slots and switches it between Enroll and Join Waitlist.
+
+// seatStreamHandler relays GET /courses/seats/stream?course_id=A,B.
+func seatStreamHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	_, courseURL := routeFor("course", r, sess.Username)
+
+	// No client timeout: the stream lasts until the browser leaves, or until
+	// the portal shuts down
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-server.Stopping():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, _ := http.NewRequestWithContext(ctx, "GET", courseURL+"/courses/seats/stream?course_id="+url.QueryEscape(r.URL.Query().Get("course_id")), nil)
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	resp, err := nodeClient.Do(req)
+	if err != nil {
+		http.Error(w, "Course Service Unreachable", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		http.Error(w, "Course Service Unreachable", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if rc.Flush() != nil {
+				return
+			}
+		}
+		if err != nil {
+			return // The backend closed; EventSource reconnects on its own
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// --- Faculty Sections ---
+
+// Faculty see the sections they teach on the dashboard, each linking to its
+// roster, its grading sheet and its statistics. The Course Service only lets
+// a course's instructor (or the registrar) read the roster and statistics.
+
+type TaughtSection struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Term       string `json:"term"`
+	Capacity   int    `json:"capacity"`
+	OpenSlots  int    `json:"open_slots"`
+	Waitlisted int    `json:"waitlisted"`
+}
+
+type RosterData struct {
+	Username   string
+	CourseID   string   `json:"course_id"`
+	Term       string   `json:"term"`
+	Instructor string   `json:"instructor"`
+	Students   []string `json:"students"`
+	Error      string
+}
+
+type EnrollmentStats struct {
+	Capacity      int     `json:"capacity"`
+	Enrolled      int     `json:"enrolled"`
+	Held          int     `json:"held"`
+	FillRate      float64 `json:"fill_rate"`
+	Enrollments   int     `json:"enrollments"`
+	Drops         int     `json:"drops"`
+	DropRate      float64 `json:"drop_rate"`
+	WaitlistDepth int     `json:"waitlist_depth"`
+}
+
+type GradeDistribution struct {
+	Type         string   `json:"type"`
+	Released     bool     `json:"released"`
+	Graded       int      `json:"graded"`
+	Average      *float64 `json:"average"`
+	Distribution []struct {
+		Grade string `json:"grade"`
+		Count int    `json:"count"`
+	} `json:"distribution"`
+}
+
+type SectionStatsData struct {
+	Username   string
+	CourseID   string
+	Enrollment *EnrollmentStats
+	Grades     []GradeDistribution
+	Error      string
+	GradeError string
+}
+
+// rosterHandler shows who is enrolled in a section the faculty member teaches.
+func rosterHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := RosterData{Username: sess.Username, CourseID: r.FormValue("course_id")}
+	_, courseURL := routeFor("course", r, sess.Username)
+	if err := fetchFromNode(courseURL+"/roster?course_id="+url.QueryEscape(data.CourseID), sess.Token, &data); err != nil {
+		data.Error = "Cannot load the roster: you may not teach " + data.CourseID + ", or the Course Service is unreachable"
+	}
+	render(w, "roster", data)
+}
+
+// sectionStatsHandler shows how a section filled and how it was graded.
+func sectionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := SectionStatsData{Username: sess.Username, CourseID: r.FormValue("course_id")}
+	_, courseURL := routeFor("course", r, sess.Username)
+	_, gradeURL := routeFor("grade", r, sess.Username)
+	id := url.PathEscape(data.CourseID)
+	if err := fetchFromNode(courseURL+"/courses/"+id+"/stats", sess.Token, &data.Enrollment); err != nil {
+		data.Error = "Cannot load the statistics: you may not teach " + data.CourseID + ", or the Course Service is unreachable"
+	}
+	for _, gradeType := range []string{"midterm", "final"} {
+		var g GradeDistribution
+		if err := fetchFromNode(gradeURL+"/courses/"+id+"/grade-stats?type="+gradeType, sess.Token, &g); err != nil {
+			data.GradeError = "Grading Service Unreachable"
+			data.Grades = nil
+			break
+		}
+		data.Grades = append(data.Grades, g)
+	}
+	render(w, "section-stats", data)
+}
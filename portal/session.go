@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/auth"
+	"shared/logging"
+	"shared/mtls"
+)
+
+// --- Sessions ---
+
+// The browser only holds an opaque session ID. The token, username and role
+// live here, and the username and role are always the ones the Auth Service
+// read from the validated token at login, never anything the browser sent.
+// A session ends with its token or on logout. Within refreshBefore of the
+// token's expiry the next request trades it at the Auth Service's /refresh
+// for a new one, so a user who keeps working is not logged out mid-form;
+// static/js/session.js makes that request on quiet pages too, and counts
+// down to the end when the token cannot be refreshed.
+
+const (
+	sessionCookie = "session_id"
+	sessionTTL    = 1 * time.Hour // Assumed when a token's expiry cannot be read
+	refreshBefore = 10 * time.Minute
+	refreshRetry  = 1 * time.Minute // Between attempts when refreshing fails
+)
+
+type AuthUser = auth.User
+
+type Session struct {
+	AuthUser
+	Token     string
+	Expires   time.Time // When the token expires
+	Flash     *Flash    // Shown on the next dashboard load
+	Filter    CourseFilter
+	refreshAt time.Time // No refresh before this: one is running or just failed
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]*Session) // Session ID -> session
+)
+
+// validateToken asks the Auth Service who a token belongs to.
+func validateToken(token string) (*AuthUser, error) {
+	var user AuthUser
+	if err := fetchFromNode(backendURL("auth")+"/validate", token, &user); err != nil {
+		return nil, err
+	}
+	if user.Username == "" {
+		return nil, errors.New("token carries no username")
+	}
+	return &user, nil
+}
+
+// startSession validates a freshly issued token, stores a session for it and
+// sets the session cookie.
+func startSession(w http.ResponseWriter, token string) error {
+	user, err := validateToken(token)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(b)
+	now := time.Now()
+	sess := &Session{AuthUser: *user, Token: token, Expires: tokenExpiry(token, now)}
+
+	sessionsMu.Lock()
+	for old, s := range sessions {
+		if now.After(s.Expires) {
+			delete(sessions, old)
+		}
+	}
+	sessions[id] = sess
+	sessionsMu.Unlock()
+
+	// No Expires: refreshing keeps the session alive past the first token
+	setCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/"})
+	return nil
+}
+
+// currentSession looks up the request's session without calling the Auth
+// Service, unless its token is due for a refresh. The backends still check
+// the token on every call.
+func currentSession(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, err
+	}
+	sessionsMu.Lock()
+	sess, ok := sessions[cookie.Value]
+	if !ok {
+		sessionsMu.Unlock()
+		return nil, errors.New("unknown session")
+	}
+	if time.Now().After(sess.Expires) {
+		delete(sessions, cookie.Value)
+		sessionsMu.Unlock()
+		return nil, errors.New("session expired")
+	}
+	now := time.Now()
+	due := sess.Expires.Sub(now) < refreshBefore && now.After(sess.refreshAt)
+	if due {
+		sess.refreshAt = now.Add(refreshRetry)
+	}
+	sessionsMu.Unlock()
+	logging.SetUser(r.Context(), sess.Username)
+
+	if due {
+		fresh, err := refreshSession(cookie.Value, sess)
+		if err == nil {
+			return fresh, nil
+		}
+		slog.WarnContext(r.Context(), "token refresh failed", "username", sess.Username, "err", err)
+	}
+	return sess, nil
+}
+
+// refreshSession trades the session's token for a new one. Handlers read
+// the token without locking, so the session is replaced by an updated copy
+// rather than changed in place.
+func refreshSession(id string, sess *Session) (*Session, error) {
+	var result struct {
+		Token string `json:"token"`
+	}
+	err := postToAuth("/refresh", sess.Token, &result)
+	if err != nil {
+		return nil, err
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	fresh := *sess
+	fresh.Token, fresh.Expires = result.Token, tokenExpiry(result.Token, time.Now())
+	if sessions[id] == sess {
+		sessions[id] = &fresh
+	}
+	return &fresh, nil
+}
+
+// postToAuth POSTs to the Auth Service with a token and decodes the reply.
+func postToAuth(path, token string, target interface{}) error {
+	req, _ := http.NewRequest("POST", backendURL("auth")+path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// tokenExpiry reads a token's exp claim without checking the signature; it
+// only schedules the refresh. It assumes sessionTTL from issued when the
+// claim cannot be read.
+func tokenExpiry(token string, issued time.Time) time.Time {
+	parts := strings.Split(token, ".")
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if len(parts) == 3 {
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil && json.Unmarshal(payload, &claims) == nil && claims.Exp > 0 {
+			return time.Unix(claims.Exp, 0)
+		}
+	}
+	return issued.Add(sessionTTL)
+}
+
+// sessionStatusHandler (GET /session) tells session.js how long the session
+// has left. Asking refreshes a token that is due.
+func sessionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Error(w, "No session", http.StatusUnauthorized)
+		return
+	}
+	sessionsMu.Lock()
+	left := time.Until(sess.Expires)
+	sessionsMu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"expires_in": int(left.Seconds()),
+		"refresh_in": int(max(left-refreshBefore, 0).Seconds()),
+	})
+}
+
+// currentUser looks up the session and revalidates its token with the Auth
+// Service, for pages that must not outlive a revoked token.
+func currentUser(r *http.Request) (*Session, error) {
+	sess, err := currentSession(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validateToken(sess.Token); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// endUserSessions forgets every session this portal holds for a user.
+// Other portal replicas find out when the Auth Service rejects the token.
+func endUserSessions(username string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for id, s := range sessions {
+		if s.Username == username {
+			delete(sessions, id)
+		}
+	}
+}
+
+// endSession forgets the request's session and clears the cookie.
+func endSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		sessionsMu.Lock()
+		delete(sessions, cookie.Value)
+		sessionsMu.Unlock()
+	}
+	setCookie(w, &http.Cookie{Name: sessionCookie, MaxAge: -1, Path: "/"})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- Account Settings ---
+
+// /settings is where users manage their own account: their password and
+// contact email (Auth Service) and, for students, the channels grade
+// notifications arrive on (Grade Service). Each form saves on its own and
+// comes back to the page with a flash message. "Log out everywhere" revokes
+// every session the user has, on any device, and ends this one.
+
+type SettingsData struct {
+	Username    string
+	Role        string
+	Email       string
+	NotifyPrefs *NotificationPrefs
+	Flash       *Flash
+	Error       string
+}
+
+// settingsHandler shows the settings (GET) or saves the password or
+// contact email form (POST with action=password or action=email).
+func settingsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		setFlash(sess, settingsAction(r, sess))
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+
+	data := SettingsData{Username: sess.Username, Role: sess.Role, Flash: takeFlash(sess)}
+	var profile Profile
+	if err := fetchFromNode(backendURL("auth")+"/profile", sess.Token, &profile); err != nil {
+		data.Error = "Auth Service Unreachable"
+	}
+	data.Email = profile.Email
+	if sess.Role == "student" {
+		_, gradeURL := routeFor("grade", r, sess.Username)
+		var prefs NotificationPrefs
+		if err := fetchFromNode(gradeURL+"/notifications/preferences", sess.Token, &prefs); err == nil {
+			data.NotifyPrefs = &prefs
+		}
+	}
+	render(w, "settings", data)
+}
+
+// settingsAction saves one settings form and says how it went.
+func settingsAction(r *http.Request, sess *Session) Flash {
+	authURL := backendURL("auth")
+	switch r.FormValue("action") {
+	case "password":
+		if r.FormValue("new_password") != r.FormValue("confirm") {
+			return Flash{Kind: "error", Message: "The new passwords do not match."}
+		}
+		err := callNode("POST", authURL+"/password", sess.Token, "", map[string]string{
+			"current_password": r.FormValue("current_password"), "new_password": r.FormValue("new_password"),
+		})
+		if err != nil {
+			return Flash{Kind: "error", Message: err.Error() + "."}
+		}
+		return Flash{Kind: "success", Message: "Your password has been changed."}
+	case "email":
+		email := strings.TrimSpace(r.FormValue("email"))
+		if err := callNode("PUT", authURL+"/profile", sess.Token, "", map[string]string{"email": email}); err != nil {
+			return Flash{Kind: "error", Message: err.Error()}
+		}
+		return Flash{Kind: "success", Message: "Your contact email is now " + email + "."}
+	}
+	return Flash{Kind: "error", Message: "Unknown settings form."}
+}
+
+// notificationPrefsHandler saves the student's notification channels. An
+// unchecked box is simply missing from the form.
+func notificationPrefsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	gradeTarget, gradeURL := routeFor("grade", r, sess.Username)
+
+	jsonData, _ := json.Marshal(map[string]bool{"email": r.FormValue("email") == "true", "portal": r.FormValue("portal") == "true"})
+
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("PUT", gradeURL+"/notifications/preferences", bytes.NewBuffer(jsonData))
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+	}
+	trackCall("grade", gradeTarget, start, err)
+
+	if err != nil || resp.StatusCode != http.StatusOK {
+		setFlash(sess, Flash{Kind: "error", Message: "Your notification preferences could not be saved. Please try again."})
+	} else {
+		setFlash(sess, Flash{Kind: "success", Message: "Your notification preferences have been saved."})
+	}
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// logoutEverywhereHandler revokes all of the user's sessions at the Auth
+// Service and sends them to the login page.
+func logoutEverywhereHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := callNode("POST", backendURL("auth")+"/sessions/revoke", sess.Token, "", nil); err != nil {
+		setFlash(sess, Flash{Kind: "error", Message: "Your sessions could not be revoked: " + err.Error() + ". Please try again."})
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	endUserSessions(sess.Username)
+	endSession(w, r)
+	http.Redirect(w, r, "/login?revoked=1", http.StatusSeeOther)
+}
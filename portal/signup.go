@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- Sign-Up ---
+
+// New students create their account at /signup with the student ID from
+// their admission letter. The Auth Service checks each field and emails a
+// verification link; following it (/signup/verify) creates the account and
+// sends the student to the login page.
+
+type SignupData struct {
+	Username string
+	Email    string
+	Errors   map[string]string // Field -> message from the Auth Service
+	Error    string
+	Sent     bool
+}
+
+// signupHandler shows the form (GET) or submits it (POST).
+func signupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		render(w, "signup", SignupData{})
+		return
+	}
+	data := SignupData{Username: strings.TrimSpace(r.FormValue("username")), Email: strings.TrimSpace(r.FormValue("email"))}
+	if r.FormValue("password") != r.FormValue("confirm") {
+		data.Errors = map[string]string{"confirm": "The passwords do not match."}
+		render(w, "signup", data)
+		return
+	}
+
+	jsonData, _ := json.Marshal(map[string]string{"username": data.Username, "password": r.FormValue("password"), "email": data.Email})
+	client := http.Client{Timeout: 5 * time.Second, Transport: mtls.Transport}
+	resp, err := client.Post(backendURL("auth")+"/register", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		data.Error = "Sign-up is unavailable right now. Please try again."
+		render(w, "signup", data)
+		return
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		data.Sent = true
+	case resp.StatusCode == http.StatusBadRequest:
+		var body struct {
+			Errors map[string]string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		data.Errors = body.Errors
+		if len(data.Errors) == 0 {
+			data.Error = "Please check the form and try again."
+		}
+	default:
+		data.Error = "Sign-up is unavailable right now. Please try again."
+	}
+	render(w, "signup", data)
+}
+
+// verifySignupHandler follows the link from the verification email.
+func verifySignupHandler(w http.ResponseWriter, r *http.Request) {
+	client := http.Client{Timeout: 5 * time.Second, Transport: mtls.Transport}
+	resp, err := client.Post(backendURL("auth")+"/register/verify?token="+url.QueryEscape(r.URL.Query().Get("token")), "application/json", nil)
+	if err != nil {
+		renderError(w, http.StatusBadGateway, "Your account could not be verified right now. Please open the link again later.")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		renderError(w, resp.StatusCode, strings.TrimSpace(string(msg)))
+		return
+	}
+	http.Redirect(w, r, "/login?verified=1", http.StatusSeeOther)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+)
+
+// --- Static Assets ---
+
+// The stylesheet and scripts in static/ are built into the binary and served
+// under /static/, so the portal works without reaching a CDN. Pages link to
+// them through the "asset" template function, which adds ?v=<content hash>.
+// A request with the current hash may be cached for a year; any other is
+// revalidated, so a new build's files are picked up at once.
+
+//go:embed static
+var staticFS embed.FS
+
+// assetHashes maps each file under static/ to a short hash of its content.
+var assetHashes = hashAssets()
+
+func hashAssets() map[string]string {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(staticFS, "static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := staticFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hashes[path[len("static/"):]] = hex.EncodeToString(sum[:6])
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return hashes
+}
+
+// assetURL is the versioned URL of a file under static/.
+func assetURL(name string) string {
+	hash, ok := assetHashes[name]
+	if !ok {
+		slog.Error("unknown asset", "name", name)
+	}
+	return "/static/" + name + "?v=" + hash
+}
+
+// staticHandler serves /static/. Directories and unknown files are 404s.
+func staticHandler() http.Handler {
+	files, _ := fs.Sub(staticFS, "static")
+	fileServer := http.FileServerFS(files)
+	return http.StripPrefix("/static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash, ok := assetHashes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"`+hash+`"`)
+		if r.URL.Query().Get("v") == hash {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+}
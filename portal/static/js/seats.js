@@ -0,0 +1,19 @@
+// Live seat counts: update each dashboard card as the Course Service
+// reports changes, relayed by the portal at /seats/stream.
+const cards = document.querySelectorAll("[data-course]");
+if (cards.length && window.EventSource) {
+    const ids = Array.from(cards, c => c.dataset.course).join(",");
+    const stream = new EventSource("/seats/stream?course_id=" + encodeURIComponent(ids));
+    stream.addEventListener("seats", e => {
+        const u = JSON.parse(e.data);
+        const card = document.querySelector('[data-course="' + CSS.escape(u.course_id) + '"]');
+        if (!card) return;
+        card.querySelector(".slots").textContent = u.open_slots;
+        const enroll = card.querySelector(".enroll-form"), waitlist = card.querySelector(".waitlist-form");
+        if (enroll) enroll.hidden = u.open_slots <= 0;
+        if (waitlist) {
+            waitlist.hidden = u.open_slots > 0;
+            waitlist.querySelector(".waiting").textContent = u.waitlisted;
+        }
+    });
+}
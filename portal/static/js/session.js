@@ -0,0 +1,62 @@
+// Session expiry: the portal refreshes the login token before it lapses
+// whenever a page asks it something, so ask /session when the refresh is
+// due. If the token cannot be refreshed (the Auth Service is down, or the
+// login is too old), count down to the end so the user can save their work.
+const refreshBefore = 600; // Seconds; matches refreshBefore in session.go
+let banner = null, countdown = null, seen = false;
+
+function warn(html) {
+    if (!banner) {
+        banner = document.createElement("div");
+        banner.className = "status-warn session-warning";
+        banner.setAttribute("role", "alert");
+        document.body.prepend(banner);
+    }
+    banner.innerHTML = html;
+}
+
+function ended() {
+    clearInterval(countdown);
+    const next = encodeURIComponent(location.pathname + location.search);
+    warn('<strong>Your session has ended.</strong> <a href="/login?next=' + next + '" target="_blank">Log in again</a> in a new tab, then submit this page.');
+}
+
+function countDown(seconds) {
+    const end = Date.now() + seconds * 1000;
+    clearInterval(countdown);
+    const tick = () => {
+        const left = Math.max(0, Math.round((end - Date.now()) / 1000));
+        if (left === 0) return ended();
+        const m = Math.floor(left / 60), s = String(left % 60).padStart(2, "0");
+        warn("<strong>Your session ends in " + m + ":" + s + ".</strong> Save your work; it could not be extended.");
+    };
+    tick();
+    countdown = setInterval(tick, 1000);
+}
+
+async function check() {
+    let status;
+    try {
+        const resp = await fetch("/session", {cache: "no-store"});
+        if (resp.status === 401) {
+            if (seen) ended();
+            return;
+        }
+        status = await resp.json();
+    } catch {
+        setTimeout(check, 60000);
+        return;
+    }
+    seen = true;
+    if (status.expires_in > refreshBefore) {
+        clearInterval(countdown);
+        if (banner) banner.remove();
+        banner = null;
+        setTimeout(check, (status.refresh_in + 5) * 1000);
+        return;
+    }
+    countDown(status.expires_in);
+    setTimeout(check, Math.min(60, status.expires_in + 1) * 1000);
+}
+
+check();
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// --- Service Status ---
+
+// /status shows admins each backend as the health poller last saw it, with
+// its latency, the last poll that was not "ok" and the recent history. The
+// page reloads itself every poll interval.
+
+type ServiceStatus struct {
+	Name        string
+	URL         string
+	Current     BackendHealth
+	LastFailure *BackendHealth
+	History     []BackendHealth
+	Uptime      float64 // Percent of the history that was "ok"
+	Breaker     string
+}
+
+type StatusData struct {
+	Username string
+	Now      time.Time
+	Services []ServiceStatus
+}
+
+// serviceStatuses snapshots the poller's view of every backend.
+func serviceStatuses() []ServiceStatus {
+	breakers := breakerStates()
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	var list []ServiceStatus
+	for _, b := range backends {
+		s := ServiceStatus{Name: b.Name, URL: b.URL(), Breaker: breakerClosed}
+		if h, ok := backendHealth[b.Name]; ok {
+			s.Current = h
+		} else {
+			s.Current = BackendHealth{Status: "unknown", Hard: b.Hard}
+		}
+		if h, ok := lastFailure[b.Name]; ok {
+			s.LastFailure = &h
+		}
+		s.History = append(s.History, healthHistory[b.Name]...)
+		ok := 0
+		for _, h := range s.History {
+			if h.Status == "ok" {
+				ok++
+			}
+		}
+		if len(s.History) > 0 {
+			s.Uptime = 100 * float64(ok) / float64(len(s.History))
+		}
+		if u, err := url.Parse(s.URL); err == nil && breakers[u.Host] != "" {
+			s.Breaker = breakers[u.Host]
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+
+	data := StatusData{Username: user.Username, Now: time.Now(), Services: serviceStatuses()}
+	render(w, "status", data)
+}
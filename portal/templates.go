@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// --- Templates ---
+
+// Pages live in templates/pages and are built into the binary. Each one is
+// parsed once, at startup, together with the shared layout and partials in
+// templates/layout, so a broken template stops the portal from starting
+// instead of failing on some later request.
+
+//go:embed templates
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"asset":         assetURL,
+	"join":          strings.Join,
+	"inc":           func(n int) int { return n + 1 },
+	"dec":           func(n int) int { return n - 1 },
+	"percent":       func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"creditChoices": func() []int { return []int{1, 2, 3, 4, 5} },
+}
+
+var pages = parsePages()
+
+func parsePages() map[string]*template.Template {
+	files, err := fs.Glob(templateFS, "templates/pages/*.html")
+	if err != nil {
+		log.Fatal(err)
+	}
+	parsed := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		parsed[name] = template.Must(template.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/layout/*.html", file))
+	}
+	return parsed
+}
+
+// render writes a page. It renders into a buffer first, so a template that
+// fails halfway sends the error page instead of half a page.
+func render(w http.ResponseWriter, page string, data interface{}) {
+	var buf bytes.Buffer
+	if err := pages[page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		slog.Error("rendering failed", "page", page, "err", err)
+		renderError(w, http.StatusInternalServerError, "The page could not be shown. Please try again.")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+type ErrorPage struct {
+	Status     int
+	StatusText string
+	Message    string
+}
+
+func renderError(w http.ResponseWriter, status int, message string) {
+	var buf bytes.Buffer
+	data := ErrorPage{Status: status, StatusText: http.StatusText(status), Message: message}
+	if err := pages["error"].ExecuteTemplate(&buf, "layout", data); err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"shared/config"
+	"shared/server"
+)
+
+// --- HTTPS ---
+
+// Setting TLS_CERT_FILE and TLS_KEY_FILE makes the portal serve HTTPS on
+// PORT. HTTP_REDIRECT_PORT then adds a plain HTTP listener that only
+// redirects to HTTPS. Over TLS every cookie is marked Secure; COOKIE_SECURE
+// does the same when TLS ends at a proxy in front of the portal.
+
+var secureCookies bool
+
+// setCookie sets a cookie that scripts cannot read, that is only sent over
+// HTTPS when the portal is behind TLS, and that is Lax unless the caller
+// asked for Strict.
+func setCookie(w http.ResponseWriter, c *http.Cookie) {
+	c.HttpOnly = true
+	c.Secure = secureCookies
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(w, c)
+}
+
+// withHSTS tells browsers to use HTTPS for the portal from now on.
+func withHSTS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHTTPS sends every request to the same path on the HTTPS port.
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// serve runs the portal on port, over TLS when a certificate is configured.
+func serve(port string, handler http.Handler) error {
+	certFile, keyFile := config.Get("TLS_CERT_FILE"), config.Get("TLS_KEY_FILE")
+	secureCookies = config.Bool("COOKIE_SECURE")
+	if certFile == "" && keyFile == "" {
+		slog.Info("Node 1 (Portal) running", "port", port)
+		return server.Run(&http.Server{Addr: "0.0.0.0:" + port, Handler: handler})
+	}
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	secureCookies = true
+
+	if redirectPort := config.Get("HTTP_REDIRECT_PORT"); redirectPort != "" {
+		redirect := &http.Server{Addr: "0.0.0.0:" + redirectPort, Handler: redirectToHTTPS(port)}
+		go func() {
+			if err := redirect.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+		server.OnShutdown("redirect", redirect.Shutdown)
+		slog.Info("Node 1 (Portal) redirecting HTTP to HTTPS", "port", redirectPort)
+	}
+	slog.Info("Node 1 (Portal) running HTTPS", "port", port)
+	return server.RunTLS(&http.Server{Addr: "0.0.0.0:" + port, Handler: withHSTS(handler)}, certFile, keyFile)
+}
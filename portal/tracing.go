@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Support Tracing ---
+
+// An admin can flag a username so the next N portal requests made by that
+// user are logged verbosely under a trace ID. Secrets are redacted before
+// anything is written.
+
+var (
+	traceMu    sync.Mutex
+	traceFlags = make(map[string]int) // Username -> requests left to trace
+)
+
+var redactedFields = map[string]bool{
+	"password":      true,
+	"authorization": true,
+	"cookie":        true,
+	"token":         true,
+	"session_id":    true,
+}
+
+func redactValues(values map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(values))
+	for k, v := range values {
+		if redactedFields[strings.ToLower(k)] {
+			out[k] = []string{"[REDACTED]"}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// takeTrace consumes one traced request from a user's budget.
+func takeTrace(username string) bool {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	left := traceFlags[username]
+	if left <= 0 {
+		return false
+	}
+	if left == 1 {
+		delete(traceFlags, username)
+	} else {
+		traceFlags[username] = left - 1
+	}
+	return true
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach Flush on the real writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// withSupportTracing logs flagged users' requests in full.
+func withSupportTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := currentSession(r)
+		if err != nil || !takeTrace(sess.Username) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		b := make([]byte, 8)
+		rand.Read(b)
+		traceID := hex.EncodeToString(b)
+		w.Header().Set("X-Trace-ID", traceID)
+
+		r.ParseForm()
+		form := url.Values(redactValues(r.PostForm))
+		slog.InfoContext(r.Context(), "trace: request", "trace_id", traceID, "user", sess.Username,
+			"method", r.Method, "path", r.URL.Path, "query", redactValues(r.URL.Query()),
+			"form", form, "headers", redactValues(r.Header))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		slog.InfoContext(r.Context(), "trace: response", "trace_id", traceID, "user", sess.Username,
+			"status", rec.status, "bytes", rec.bytes, "duration", time.Since(start).String(),
+			"location", rec.Header().Get("Location"))
+	})
+}
+
+type TraceFlagRequest struct {
+	Username string `json:"username"`
+	Requests int    `json:"requests"` // 0 clears the flag
+}
+
+// adminTraceHandler lists (GET) or sets (POST) per-user trace flags.
+func adminTraceHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		traceMu.Lock()
+		out := make(map[string]int, len(traceFlags))
+		for k, v := range traceFlags {
+			out[k] = v
+		}
+		traceMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+
+	case http.MethodPost:
+		var req TraceFlagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+			http.Error(w, "Expected {\"username\": ..., \"requests\": N}", http.StatusBadRequest)
+			return
+		}
+		if req.Requests < 0 || req.Requests > 1000 {
+			http.Error(w, "requests must be between 0 and 1000", http.StatusBadRequest)
+			return
+		}
+		traceMu.Lock()
+		if req.Requests == 0 {
+			delete(traceFlags, req.Username)
+		} else {
+			traceFlags[req.Username] = req.Requests
+		}
+		traceMu.Unlock()
+		slog.InfoContext(r.Context(), "support tracing set", "username", req.Username, "requests", req.Requests)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "trace flag updated"}`))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/mtls"
+)
+
+// --- Waitlists ---
+
+// Full courses offer "Join Waitlist" on the student dashboard. While a
+// student is on any waitlist the dashboard shows their position and reloads
+// itself every waitlistRefresh seconds, so the position stays current.
+
+const waitlistRefresh = 30
+
+// waitlistHandler joins (/waitlist) or leaves (/waitlist/leave) a course's
+// waitlist for the logged-in student.
+func waitlistHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := currentSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	courseTarget, courseURL := routeFor("course", r, sess.Username)
+	courseID := r.FormValue("course_id")
+	leaving := r.URL.Path == "/waitlist/leave"
+
+	jsonData, _ := json.Marshal(map[string]string{"course_id": courseID, "student_id": sess.Username})
+	client := http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+	req, _ := http.NewRequest("POST", courseURL+r.URL.Path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sess.Token)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("status code %d", resp.StatusCode)
+		}
+	}
+	trackCall("course", courseTarget, start, err)
+	invalidateCatalog()
+	switch {
+	case err != nil:
+		setFlash(sess, Flash{Kind: "error", Message: "The waitlist is unavailable right now. Please try again."})
+	case resp.StatusCode >= 400:
+		msg, _ := io.ReadAll(resp.Body)
+		setFlash(sess, Flash{Kind: "error", Message: courseID + ": " + strings.TrimSpace(string(msg))})
+	case leaving:
+		setFlash(sess, Flash{Kind: "success", Message: "You left the waitlist for " + courseID + "."})
+	default:
+		var status struct {
+			Position int `json:"position"`
+		}
+		json.NewDecoder(resp.Body).Decode(&status)
+		setFlash(sess, Flash{Kind: "success", Message: fmt.Sprintf("You joined the waitlist for %s at position %d.", courseID, status.Position)})
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
@@ -0,0 +1,150 @@
+// Package auth checks the bearer tokens callers present against the Auth
+// Service, for the services that sit behind it.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"shared/config"
+	"shared/discovery"
+	"shared/httpjson"
+	"shared/logging"
+)
+
+// User is the Auth Service's answer to /validate: who a token belongs to.
+type User struct {
+	Status    string `json:"status"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Program   string `json:"program"`
+	YearLevel int    `json:"year_level"`
+}
+
+// Settings are what a service that checks tokens takes.
+var Settings = []config.Setting{
+	{Name: "AUTH_SERVICE_URL", Default: "http://node_auth:8081", Usage: "Where the Auth Service is: a base URL, a comma-separated list, or srv://name", Check: discovery.ValidTarget},
+	{Name: "AUTH_CACHE_TTL", Default: "10s", Usage: "How long a token check is cached; 0 turns the cache off", Check: config.ValidDuration},
+}
+
+// ServiceURL is the base URL of the Auth Service instance to call next.
+func ServiceURL() string {
+	return discovery.Peer("AUTH_SERVICE_URL").URL()
+}
+
+// BearerToken returns the request's bearer token, if it has one.
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(header, "Bearer "), true
+}
+
+// Validate asks the Auth Service who a token belongs to, unless it has
+// answered for the same token within the cache TTL. ctx is the request
+// being served, if any, so the check joins its trace.
+func Validate(ctx context.Context, token string) (*User, bool) {
+	if user, ok := cachedUser(token); ok {
+		cacheLookups.WithLabelValues("hit").Inc()
+		return user, true
+	}
+	cacheLookups.WithLabelValues("miss").Inc()
+
+	req, err := httpjson.NewRequest(ctx, http.MethodGet, ServiceURL()+"/validate", token, nil)
+	if err != nil {
+		return nil, false
+	}
+	var user User
+	if err := httpjson.Do(httpjson.Client, req, &user); err != nil {
+		return nil, false
+	}
+	cacheUser(token, &user)
+	return &user, true
+}
+
+// --- Validation Cache ---
+
+// Every request to a service checks its token, so valid answers are kept
+// for AUTH_CACHE_TTL. Refused tokens are always asked about again. A token revoked by
+// logging out everywhere can therefore still work for up to the TTL.
+
+const maxCachedTokens = 10000
+
+type cachedAnswer struct {
+	user    *User
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cachedAnswer) // Token -> the Auth Service's answer
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_validation_cache_lookups_total",
+		Help: "Token checks answered from the validation cache (hit) or by the Auth Service (miss).",
+	}, []string{"result"})
+)
+
+func cachedUser(token string) (*User, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	answer, ok := cache[token]
+	if !ok || time.Now().After(answer.expires) {
+		return nil, false
+	}
+	user := *answer.user // Callers get their own copy
+	return &user, true
+}
+
+func cacheUser(token string, user *User) {
+	ttl := config.Duration("AUTH_CACHE_TTL")
+	if ttl == 0 {
+		return
+	}
+	now := time.Now()
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if len(cache) >= maxCachedTokens {
+		for t, answer := range cache {
+			if now.After(answer.expires) {
+				delete(cache, t)
+			}
+		}
+		if len(cache) >= maxCachedTokens {
+			clear(cache) // All live; starting over costs one check per token
+		}
+	}
+	cache[token] = cachedAnswer{user: user, expires: now.Add(ttl)}
+}
+
+// RequireRole authenticates the caller via the Auth Service and checks that
+// their role is one of the allowed roles. It writes the error response itself.
+func RequireRole(w http.ResponseWriter, r *http.Request, allowed ...string) (*User, bool) {
+	token, ok := BearerToken(r)
+	if !ok {
+		http.Error(w, "Unauthorized: Missing token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, valid := Validate(r.Context(), token)
+	if !valid {
+		http.Error(w, "Unauthorized: Invalid Token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	logging.SetUser(r.Context(), user.Username)
+	for _, role := range allowed {
+		if user.Role == role {
+			return user, true
+		}
+	}
+	http.Error(w, "Forbidden: Insufficient role", http.StatusForbidden)
+	return nil, false
+}
@@ -0,0 +1,285 @@
+// Package config reads a service's settings. Each setting has one name,
+// which is its environment variable and its key in the config file, and
+// one default, both declared in the service's settings table. A value is
+// taken from, highest precedence first:
+//
+//   - its command-line flag, the name in lower case with dashes
+//     (-seat-store-url for SEAT_STORE_URL);
+//   - the environment;
+//   - the YAML file named by -config or CONFIG_FILE, a flat map of names to
+//     values;
+//   - the default.
+//
+// Every value is checked at startup, and any mistake, including an unknown
+// key in the file, stops the service with the full list. Running a service
+// with the dump-config command prints the settings it would run with,
+// secrets hidden, and exits.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting declares one configuration value.
+type Setting struct {
+	Name    string
+	Default string
+	Usage   string
+	Flag    string             // Overrides the flag's name
+	Secret  bool               // Hidden by dump-config
+	Check   func(string) error // Validates a value that is set
+}
+
+type resolved struct {
+	Setting
+	value  string
+	source string // "flag", "env", "file" or "default"
+}
+
+var settings map[string]*resolved
+
+// Load resolves and checks settings, and runs dump-config if that is the
+// command. It parses the command line, so it must be the first thing main
+// does, before anything calls Get.
+func Load(service string, defs ...Setting) {
+	settings = make(map[string]*resolved, len(defs))
+	flags := make(map[string]*string, len(defs))
+	configFile := flag.String("config", "", "YAML file of settings (or $CONFIG_FILE)")
+	for _, def := range defs {
+		if _, dup := settings[def.Name]; dup {
+			panic("config: " + def.Name + " declared twice")
+		}
+		settings[def.Name] = &resolved{Setting: def}
+		usage := fmt.Sprintf("%s (or $%s", def.Usage, def.Name)
+		if def.Default != "" {
+			usage += ", default " + def.Default
+		}
+		flags[def.Name] = flag.String(flagName(def), "", usage+")")
+	}
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [dump-config]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var problems []string
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	file, err := readFile(path)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	for name := range file {
+		if settings[name] == nil {
+			problems = append(problems, fmt.Sprintf("%s: %s is not a %s setting", path, name, service))
+		}
+	}
+
+	for name, s := range settings {
+		envValue := os.Getenv(name)
+		fileValue, inFile := file[name]
+		switch {
+		case given[flagName(s.Setting)]:
+			s.value, s.source = *flags[name], "flag"
+		case envValue != "":
+			s.value, s.source = envValue, "env"
+		case inFile:
+			s.value, s.source = fileValue, "file"
+		default:
+			s.value, s.source = s.Default, "default"
+		}
+		if s.value != "" && s.Check != nil {
+			if err := s.Check(s.value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s %q: %v", name, redact(s), err))
+			}
+		}
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "dump-config":
+		if len(problems) == 0 {
+			dump(os.Stdout, service)
+			os.Exit(0)
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown command %q", cmd))
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		log.Fatalf("invalid configuration:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+}
+
+func flagName(def Setting) string {
+	if def.Flag != "" {
+		return def.Flag
+	}
+	return strings.ReplaceAll(strings.ToLower(def.Name), "_", "-")
+}
+
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %v", err)
+	}
+	var file map[string]string
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return file, nil
+}
+
+// dump writes the settings as a config file, each with where its value came from.
+func dump(w io.Writer, service string) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(w, "# %s settings, with where each value came from\n", service)
+	for _, name := range names {
+		s := settings[name]
+		fmt.Fprintf(w, "%s: %s # %s\n", name, strconv.Quote(redact(s)), s.source)
+	}
+}
+
+// redact hides secrets, and the passwords in URLs.
+func redact(s *resolved) string {
+	if s.Secret && s.value != "" {
+		return "<redacted>"
+	}
+	if u, err := url.Parse(s.value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return s.value
+}
+
+// --- Reading Settings ---
+
+// Get returns a setting's value, "" if it is unset with no default.
+func Get(name string) string {
+	s := settings[name]
+	if s == nil {
+		panic("config: " + name + " read before Load or never declared")
+	}
+	return s.value
+}
+
+// Int returns a setting checked with IntBetween or Positive, 0 if it is unset.
+func Int(name string) int {
+	n, _ := strconv.Atoi(Get(name))
+	return n
+}
+
+// Duration returns a setting checked with ValidDuration, 0 if it is unset.
+func Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(Get(name))
+	return d
+}
+
+// Time returns a setting checked with ValidTime, zero if it is unset.
+func Time(name string) time.Time {
+	t, _ := time.Parse(time.RFC3339, Get(name))
+	return t
+}
+
+// Bool returns a setting checked with ValidBool, false if it is unset.
+func Bool(name string) bool {
+	b, _ := strconv.ParseBool(Get(name))
+	return b
+}
+
+// --- Checks ---
+
+// IntBetween accepts whole numbers from min to max.
+func IntBetween(min, max int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("want a whole number from %d to %d", min, max)
+		}
+		return nil
+	}
+}
+
+// AtLeast accepts whole numbers of min or more.
+func AtLeast(min int) func(string) error {
+	return func(v string) error {
+		if n, err := strconv.Atoi(v); err != nil || n < min {
+			return fmt.Errorf("want a whole number of at least %d", min)
+		}
+		return nil
+	}
+}
+
+// Positive accepts whole numbers above 0.
+func Positive(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 1 {
+		return fmt.Errorf("want a whole number above 0")
+	}
+	return nil
+}
+
+// ValidDuration accepts Go durations that are not negative, such as 30s.
+func ValidDuration(v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return fmt.Errorf("want a duration such as 30s")
+	}
+	return nil
+}
+
+// ValidTime accepts RFC3339 times.
+func ValidTime(v string) error {
+	if _, err := time.Parse(time.RFC3339, v); err != nil {
+		return fmt.Errorf("want an RFC3339 time such as 2026-08-01T08:00:00Z")
+	}
+	return nil
+}
+
+// ValidBool accepts true and false.
+func ValidBool(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("want true or false")
+	}
+	return nil
+}
+
+// ValidURL accepts absolute URLs, such as http://localhost:8081.
+func ValidURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("want an absolute URL such as http://localhost:8081")
+	}
+	return nil
+}
+
+// OneOf accepts the values listed.
+func OneOf(values ...string) func(string) error {
+	return func(v string) error {
+		if !slices.Contains(values, v) {
+			return fmt.Errorf("want one of %s", strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
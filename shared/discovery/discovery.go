@@ -0,0 +1,204 @@
+// Package discovery finds the instances of a peer service. A peer's
+// *_SERVICE_URL setting is its target, one of:
+//
+//   - a base URL, http://localhost:8081, used as is;
+//   - several base URLs separated by commas;
+//   - srv://name, or srv+https://name under mutual TLS, to look the
+//     instances up in the DNS SRV records of name, such as
+//     _auth._tcp.enrollment.svc.cluster.local.
+//
+// When there is more than one instance, each is probed at /readyz every
+// refreshInterval, SRV records are looked up again just as often, and calls
+// go round-robin to the instances that answered, best SRV priority first.
+// If none did, calls still go to some instance, so callers see the failure
+// and their own retries and circuit breakers apply.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/config"
+	"shared/health"
+)
+
+const refreshInterval = 5 * time.Second
+
+type instance struct {
+	url      string
+	priority uint16 // From the SRV record; 0 for listed URLs
+	healthy  bool
+}
+
+// Pool is the known instances of one peer service.
+type Pool struct {
+	setting string
+	srvName string // Set for srv:// targets
+	scheme  string
+
+	mu        sync.Mutex
+	instances []instance
+	next      int
+}
+
+var (
+	poolsMu sync.Mutex
+	pools   = make(map[string]*Pool) // Key: setting name
+)
+
+// ValidTarget accepts the targets described in the package comment.
+func ValidTarget(v string) error {
+	if u, err := url.Parse(v); err == nil && (u.Scheme == "srv" || u.Scheme == "srv+https") {
+		if u.Host == "" {
+			return fmt.Errorf("want srv://name")
+		}
+		return nil
+	}
+	for _, target := range strings.Split(v, ",") {
+		if err := config.ValidURL(strings.TrimSpace(target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Peer returns the pool for the peer service named by setting, finding its
+// instances the first time it is asked for.
+func Peer(setting string) *Pool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	if p := pools[setting]; p != nil {
+		return p
+	}
+	p := newPool(setting, config.Get(setting))
+	pools[setting] = p
+	return p
+}
+
+func newPool(setting, target string) *Pool {
+	p := &Pool{setting: setting}
+	if u, err := url.Parse(target); err == nil && (u.Scheme == "srv" || u.Scheme == "srv+https") {
+		p.srvName, p.scheme = u.Host, strings.TrimPrefix(strings.TrimPrefix(u.Scheme, "srv"), "+")
+		if p.scheme == "" {
+			p.scheme = "http"
+		}
+		p.resolve()
+	} else {
+		for _, base := range strings.Split(target, ",") {
+			p.instances = append(p.instances, instance{url: strings.TrimSuffix(strings.TrimSpace(base), "/"), healthy: true})
+		}
+	}
+	if p.srvName != "" || len(p.instances) > 1 {
+		p.probe()
+		go p.watch()
+	}
+	return p
+}
+
+// URL returns the base URL of the instance to call next.
+func (p *Pool) URL() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.instances) == 0 {
+		return ""
+	}
+	candidates := p.instances
+	if healthy := bestHealthy(p.instances); len(healthy) > 0 {
+		candidates = healthy
+	}
+	p.next++
+	return candidates[p.next%len(candidates)].url
+}
+
+// bestHealthy returns the healthy instances with the best SRV priority.
+func bestHealthy(instances []instance) []instance {
+	var best []instance
+	for _, in := range instances {
+		switch {
+		case !in.healthy:
+		case len(best) == 0 || in.priority < best[0].priority:
+			best = []instance{in}
+		case in.priority == best[0].priority:
+			best = append(best, in)
+		}
+	}
+	return best
+}
+
+func (p *Pool) watch() {
+	for range time.Tick(refreshInterval) {
+		if p.srvName != "" {
+			p.resolve()
+		}
+		p.probe()
+	}
+}
+
+// resolve replaces the instances with the ones in the SRV records, keeping
+// what is known of their health. A failed lookup keeps the old list.
+func (p *Pool) resolve() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", p.srvName)
+	if err != nil {
+		slog.Warn("discovery: SRV lookup failed", "setting", p.setting, "name", p.srvName, "err", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	instances := make([]instance, 0, len(records))
+	for _, r := range records {
+		base := p.scheme + "://" + net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+		healthy := true // Until a probe says otherwise
+		if i := slices.IndexFunc(p.instances, func(in instance) bool { return in.url == base }); i >= 0 {
+			healthy = p.instances[i].healthy
+		}
+		instances = append(instances, instance{url: base, priority: r.Priority, healthy: healthy})
+	}
+	p.instances = instances
+}
+
+// probe checks every instance's readiness at once.
+func (p *Pool) probe() {
+	p.mu.Lock()
+	urls := make([]string, len(p.instances))
+	for i, in := range p.instances {
+		urls[i] = in.url
+	}
+	p.mu.Unlock()
+
+	healthy := make(map[string]bool, len(urls))
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	for _, base := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			up := health.Probe(base+"/readyz", true).Status == "up"
+			resultsMu.Lock()
+			healthy[base] = up
+			resultsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, in := range p.instances {
+		if up, ok := healthy[in.url]; ok {
+			if up != in.healthy {
+				slog.Info("discovery: instance health changed", "setting", p.setting, "instance", in.url, "healthy", up)
+			}
+			p.instances[i].healthy = up
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package events is the services' event bus. Services publish domain events
+// on subjects such as enrollment.EnrollmentCreated and subscribe to the
+// ones other services publish, through the broker EVENT_BROKER names: NATS
+// at NATS_URL, or Kafka at KAFKA_BROKERS. Without the broker's address,
+// events are written to the service log and nothing is subscribed to.
+//
+// Events are not published directly. A service writes them to its Outbox
+// together with the state change they announce, and a relay publishes them
+// from there, so an event is neither lost when the service crashes after the
+// change nor sent for a change that never happened. Delivery is at least
+// once: consumers drop repeats by event_id. Every instance of a subscribing
+// service gets every event it subscribes to.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"shared/config"
+)
+
+// Settings are the event bus settings of the services that use it.
+var Settings = []config.Setting{
+	{Name: "EVENT_BROKER", Default: "nats", Usage: "Message broker for domain events", Check: config.OneOf("nats", "kafka")},
+	{Name: "NATS_URL", Usage: "NATS server, when EVENT_BROKER is nats; unset logs events", Check: config.ValidURL},
+	{Name: "KAFKA_BROKERS", Usage: "Comma-separated Kafka brokers (host:port), when EVENT_BROKER is kafka; unset logs events"},
+}
+
+// Event is the envelope every domain event is published in. The schema is
+// documented in the README; bump Version on any incompatible change.
+type Event struct {
+	ID        string            `json:"event_id"`
+	Version   int               `json:"version"`
+	Type      string            `json:"type"`
+	StudentID string            `json:"student_id"`
+	CourseID  string            `json:"course_id"`
+	Data      map[string]string `json:"data,omitempty"`
+	At        time.Time         `json:"at"`
+}
+
+const Version = 1
+
+const publishTimeout = 5 * time.Second
+
+// Message is an event and the subject it is published on.
+type Message struct {
+	Subject string `json:"subject"`
+	Event   Event  `json:"event"`
+}
+
+// NewMessage stamps ev with its ID, version and time. The stamp is kept
+// with the message in the outbox, so a message published twice after a
+// crash carries the same event_id both times and consumers can drop the
+// copy.
+func NewMessage(subject string, ev Event) Message {
+	b := make([]byte, 12)
+	rand.Read(b)
+	ev.ID = hex.EncodeToString(b)
+	ev.Version = Version
+	ev.At = time.Now().UTC()
+	return Message{Subject: subject, Event: ev}
+}
+
+// key orders a student's events, or a course's if there is no student.
+func (m Message) key() string {
+	if m.Event.StudentID != "" {
+		return m.Event.StudentID
+	}
+	return m.Event.CourseID
+}
+
+// driver is one broker's client.
+type driver interface {
+	name() string
+	// publish sends msgs in order and returns once the broker has them.
+	publish(msgs []Message) error
+	// subscribe calls handle with each message on a subject matching
+	// pattern, from its own goroutine.
+	subscribe(pattern string, handle func(payload []byte)) error
+	ping() error
+	close(ctx context.Context) error
+}
+
+var broker atomic.Pointer[driver] // nil while events go to the log
+
+// Start connects to the broker, if one is configured, and starts relaying
+// the events written to box. client names this service to the broker.
+func Start(client string, box Outbox) {
+	var d driver
+	var err error
+	switch config.Get("EVENT_BROKER") {
+	case "nats":
+		if url := config.Get("NATS_URL"); url != "" {
+			d, err = dialNATS(url, client)
+		}
+	case "kafka":
+		if brokers := config.Get("KAFKA_BROKERS"); brokers != "" {
+			d, err = dialKafka(strings.Split(brokers, ","), client)
+		}
+	}
+	if err != nil {
+		slog.Warn("cannot connect to the event broker, logging events instead", "broker", config.Get("EVENT_BROKER"), "err", err)
+	} else if d != nil {
+		broker.Store(&d)
+	}
+	outbox = box
+	go relay()
+}
+
+func logEvent(ev Event) {
+	payload, _ := json.Marshal(ev)
+	slog.Info("event", "event", json.RawMessage(payload))
+}
+
+// Subscribe calls handle with each event on a subject matching pattern,
+// decoded into a T. A pattern is a subject, or ends in .* for any one more
+// part or .> for anything after it. Events that do not decode are logged
+// and skipped. Start must have run.
+func Subscribe[T any](pattern string, handle func(ev T)) {
+	d := broker.Load()
+	if d == nil {
+		slog.Info("no event broker, not subscribing", "pattern", pattern)
+		return
+	}
+	err := (*d).subscribe(pattern, func(payload []byte) {
+		var ev T
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			slog.Warn("cannot decode event", "pattern", pattern, "err", err)
+			return
+		}
+		handle(ev)
+	})
+	if err != nil {
+		slog.Error("cannot subscribe to events", "pattern", pattern, "err", err)
+	}
+}
+
+// Broker names the broker events go to, "" while they go to the log.
+func Broker() string {
+	if d := broker.Load(); d != nil {
+		return (*d).name()
+	}
+	return ""
+}
+
+// Ping reports whether the broker is reachable, for /readyz.
+func Ping() error {
+	d := broker.Load()
+	if d == nil {
+		return nil
+	}
+	return (*d).ping()
+}
+
+// Flush relays what is left in the outbox, then flushes the broker
+// connection and closes it. It runs at shutdown; anything it cannot send
+// stays in the outbox for the next start, if the outbox is durable.
+func Flush(ctx context.Context) error {
+	stopRelay()
+	if err := relayPending(); err != nil {
+		return err
+	}
+	d := broker.Load()
+	if d == nil {
+		return nil
+	}
+	return (*d).close(ctx)
+}
+
+// matches reports whether subject matches a NATS-style pattern.
+func matches(pattern, subject string) bool {
+	p, s := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, part := range p {
+		switch {
+		case part == ">":
+			return len(s) > i
+		case i >= len(s):
+			return false
+		case part != "*" && part != s[i]:
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
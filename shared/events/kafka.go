@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// --- Kafka ---
+
+// kafkaDriver publishes each event to the topic named by the first part of
+// its subject (enrollment, grades, catalog), keyed by student or course so
+// one student's events stay in order, with the full subject in a header.
+// Subscriptions read the topic and match the header.
+//
+// Each subscribing process reads in a consumer group of its own, starting
+// from the newest events, so every instance gets every event and a
+// restarted one does not replay old ones.
+type kafkaDriver struct {
+	brokers []string
+	client  string
+	writer  *kafka.Writer
+
+	ctx  context.Context // Ends at shutdown, stopping the readers
+	stop context.CancelFunc
+
+	mu      sync.Mutex
+	readers []*kafka.Reader
+}
+
+const subjectHeader = "subject"
+
+func dialKafka(brokers []string, client string) (driver, error) {
+	for i := range brokers {
+		brokers[i] = strings.TrimSpace(brokers[i])
+	}
+	d := &kafkaDriver{brokers: brokers, client: client}
+	d.ctx, d.stop = context.WithCancel(context.Background())
+	d.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		BatchTimeout:           10 * time.Millisecond,
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	if err := d.ping(); err != nil {
+		slog.Warn("kafka: no broker answers yet", "brokers", brokers, "err", err)
+	}
+	return d, nil
+}
+
+func (d *kafkaDriver) name() string { return "kafka" }
+
+func (d *kafkaDriver) publish(msgs []Message) error {
+	batch := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		topic, _, _ := strings.Cut(m.Subject, ".")
+		payload, _ := json.Marshal(m.Event)
+		batch[i] = kafka.Message{
+			Topic:   topic,
+			Key:     []byte(m.key()),
+			Value:   payload,
+			Headers: []kafka.Header{{Key: subjectHeader, Value: []byte(m.Subject)}},
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	return d.writer.WriteMessages(ctx, batch...)
+}
+
+func (d *kafkaDriver) subscribe(pattern string, handle func(payload []byte)) error {
+	topic, _, _ := strings.Cut(pattern, ".")
+	if topic == "*" || topic == ">" {
+		return fmt.Errorf("kafka subscriptions need a topic, not %q", pattern)
+	}
+	b := make([]byte, 6)
+	rand.Read(b)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     d.brokers,
+		GroupID:     d.client + "-" + hex.EncodeToString(b),
+		Topic:       topic,
+		StartOffset: kafka.LastOffset,
+	})
+	d.mu.Lock()
+	d.readers = append(d.readers, r)
+	d.mu.Unlock()
+
+	go func() {
+		for {
+			m, err := r.ReadMessage(d.ctx)
+			if err != nil {
+				if d.ctx.Err() == nil {
+					slog.Error("kafka: reading events stopped", "topic", topic, "err", err)
+				}
+				return
+			}
+			for _, h := range m.Headers {
+				if h.Key == subjectHeader && matches(pattern, string(h.Value)) {
+					handle(m.Value)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// ping succeeds if any broker answers.
+func (d *kafkaDriver) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var errs []error
+	for _, addr := range d.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *kafkaDriver) close(ctx context.Context) error {
+	d.stop()
+	d.mu.Lock()
+	for _, r := range d.readers {
+		r.Close()
+	}
+	d.mu.Unlock()
+	return d.writer.Close()
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- NATS ---
+
+// natsDriver publishes each event on its subject, which NATS subscriptions
+// match natively.
+type natsDriver struct {
+	nc *nats.Conn
+}
+
+// dialNATS keeps reconnecting for as long as the service runs, but never
+// buffers: a publish while the server is away fails at once and the event
+// stays in the outbox, which is where it waits for the broker.
+func dialNATS(url, client string) (driver, error) {
+	nc, err := nats.Connect(url, nats.Name(client), nats.MaxReconnects(-1), nats.RetryOnFailedConnect(true), nats.ReconnectBufSize(-1))
+	if err != nil {
+		return nil, err
+	}
+	return natsDriver{nc: nc}, nil
+}
+
+func (d natsDriver) name() string { return "nats" }
+
+// publish waits for the server to acknowledge a flush after the messages,
+// which it does once it has them all. While reconnecting it refuses, and
+// the connection has no buffer to take them if it drops midway.
+func (d natsDriver) publish(msgs []Message) error {
+	if err := d.ping(); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		payload, _ := json.Marshal(m.Event)
+		if err := d.nc.Publish(m.Subject, payload); err != nil {
+			return err
+		}
+	}
+	return d.nc.FlushTimeout(publishTimeout)
+}
+
+func (d natsDriver) subscribe(pattern string, handle func(payload []byte)) error {
+	_, err := d.nc.Subscribe(pattern, func(m *nats.Msg) { handle(m.Data) })
+	return err
+}
+
+func (d natsDriver) ping() error {
+	if !d.nc.IsConnected() {
+		return errors.New("not connected: " + d.nc.Status().String())
+	}
+	return nil
+}
+
+func (d natsDriver) close(ctx context.Context) error {
+	defer d.nc.Close()
+	return d.nc.FlushWithContext(ctx)
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// --- Outbox ---
+
+// Outbox is where a service keeps the events it has written and the relay
+// has not published yet. A service writes to its outbox in the same
+// transaction as the change the events announce.
+type Outbox interface {
+	// Pending returns up to limit unsent messages, oldest first.
+	Pending(limit int) ([]Entry, error)
+	// Sent drops the messages up to and including seq.
+	Sent(seq int64) error
+}
+
+// Entry is a message waiting in an outbox. Seq grows with every message
+// written.
+type Entry struct {
+	Seq int64 `json:"seq"`
+	Message
+}
+
+const (
+	relayBatch    = 100
+	relayInterval = time.Second // How often the relay looks without a Kick
+)
+
+var (
+	outbox Outbox
+
+	relayMu sync.Mutex // One pass at a time
+	failing bool       // The last pass could not publish; guarded by relayMu
+
+	kick     = make(chan struct{}, 1)
+	stop     = make(chan struct{})
+	stopOnce sync.Once
+)
+
+// Kick tells the relay there are new messages in the outbox, so it does not
+// wait for its next look. Call it once the change that wrote them commits.
+func Kick() {
+	select {
+	case kick <- struct{}{}:
+	default:
+	}
+}
+
+func relay() {
+	tick := time.NewTicker(relayInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-kick:
+		case <-tick.C:
+		}
+		relayPending()
+	}
+}
+
+func stopRelay() {
+	stopOnce.Do(func() { close(stop) })
+}
+
+// relayPending publishes the outbox until it is empty or the broker
+// refuses, oldest first. Messages are dropped from the outbox only once the
+// broker has them; without a broker they go to the log.
+func relayPending() error {
+	relayMu.Lock()
+	defer relayMu.Unlock()
+	if outbox == nil {
+		return nil
+	}
+	for {
+		entries, err := outbox.Pending(relayBatch)
+		if err != nil {
+			return relayFailed(fmt.Errorf("reading the outbox: %w", err))
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if d := broker.Load(); d != nil {
+			msgs := make([]Message, len(entries))
+			for i, e := range entries {
+				msgs[i] = e.Message
+			}
+			if err := (*d).publish(msgs); err != nil {
+				return relayFailed(err)
+			}
+		} else {
+			for _, e := range entries {
+				logEvent(e.Event)
+			}
+		}
+		if err := outbox.Sent(entries[len(entries)-1].Seq); err != nil {
+			// They go out again on the next pass, as repeats
+			return relayFailed(fmt.Errorf("clearing the outbox: %w", err))
+		}
+		if failing {
+			failing = false
+			slog.Info("events: relaying again")
+		}
+	}
+}
+
+// relayFailed logs the first of a run of failures, not every retry.
+func relayFailed(err error) error {
+	if !failing {
+		failing = true
+		slog.Warn("events: cannot relay, events wait in the outbox", "err", err)
+	}
+	return err
+}
+
+// MemoryOutbox keeps messages in memory, for services without a store. Its
+// messages are lost if the service crashes, and past memoryOutboxSize
+// waiting the oldest are dropped.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	seq     int64
+	entries []Entry
+}
+
+const memoryOutboxSize = 10000
+
+// Add writes msgs to the outbox.
+func (o *MemoryOutbox) Add(msgs ...Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, m := range msgs {
+		o.seq++
+		o.entries = append(o.entries, Entry{Seq: o.seq, Message: m})
+	}
+	if over := len(o.entries) - memoryOutboxSize; over > 0 {
+		slog.Warn("event outbox full, dropping the oldest events", "dropped", over)
+		o.entries = append([]Entry(nil), o.entries[over:]...)
+	}
+}
+
+func (o *MemoryOutbox) Pending(limit int) ([]Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := min(limit, len(o.entries))
+	return append([]Entry(nil), o.entries[:n]...), nil
+}
+
+func (o *MemoryOutbox) Sent(seq int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := 0
+	for i < len(o.entries) && o.entries[i].Seq <= seq {
+		i++
+	}
+	o.entries = o.entries[i:]
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package health is what the services answer at /healthz and /readyz.
+//
+// /healthz is liveness: the process is up and serving, nothing more, so an
+// orchestrator restarts a hung node but not one whose dependency is down.
+// /readyz is readiness: the node's own verdict on its dependencies, "ok",
+// "degraded" (a soft dependency is down) or "down" (a hard one is), with a
+// 503 for "down" so load balancers stop sending it traffic.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shared/mtls"
+)
+
+type DependencyStatus struct {
+	Status    string `json:"status"` // "up" or "down"
+	Hard      bool   `json:"hard"`   // A hard dependency being down makes this service "down"
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ReadinessReport struct {
+	Status       string                      `json:"status"` // "ok", "degraded" or "down"
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// Liveness answers /healthz.
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// Check times check, a dependency's own ping.
+func Check(hard bool, check func() error) DependencyStatus {
+	start := time.Now()
+	dep := DependencyStatus{Status: "up", Hard: hard}
+	if err := check(); err != nil {
+		dep.Status, dep.Error = "down", err.Error()
+	}
+	dep.LatencyMS = time.Since(start).Milliseconds()
+	return dep
+}
+
+var probeClient = &http.Client{Timeout: 1 * time.Second, Transport: mtls.Transport}
+
+// Probe checks that a peer answers its readiness endpoint with a non-5xx status.
+func Probe(url string, hard bool) DependencyStatus {
+	return Check(hard, func() error {
+		resp, err := probeClient.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("status code %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+func NewReport(deps map[string]DependencyStatus) ReadinessReport {
+	report := ReadinessReport{Status: "ok", Dependencies: deps}
+	for _, dep := range deps {
+		if dep.Status == "up" {
+			continue
+		}
+		if dep.Hard {
+			report.Status = "down"
+			break
+		}
+		report.Status = "degraded"
+	}
+	return report
+}
+
+func Write(w http.ResponseWriter, report ReadinessReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == "down" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,91 @@
+// Package httpjson is the small JSON-over-HTTP client the services use to
+// call each other: bearer token in, JSON body out, and any refusal turned
+// into a StatusError that carries the other service's message.
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/logging"
+	"shared/mtls"
+)
+
+// Client is for quick calls between services. It goes through
+// mtls.Transport, so it is for the system's own services only.
+var Client = &http.Client{Timeout: 2 * time.Second, Transport: mtls.Transport}
+
+// StatusError is a response with a 4xx or 5xx status. Message is the
+// response body, which for these services is a plain-text reason.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("status code %d", e.StatusCode)
+	}
+	return e.Message
+}
+
+// NewRequest builds a request with the bearer token, if any, and payload
+// encoded as the JSON body, if not nil. A ctx from a request being served
+// passes its request ID along.
+func NewRequest(ctx context.Context, method, url, token string, payload interface{}) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id) // Ties the call to the request that caused it
+	}
+	return req, nil
+}
+
+// Do sends req and decodes a successful JSON answer into target, which may
+// be nil to discard it. A 4xx or 5xx answer comes back as *StatusError.
+func Do(client *http.Client, req *http.Request, target interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// Get fetches url with Client and decodes the JSON answer into target.
+func Get(url, token string, target interface{}) error {
+	req, err := NewRequest(context.Background(), http.MethodGet, url, token, nil)
+	if err != nil {
+		return err
+	}
+	return Do(Client, req, target)
+}
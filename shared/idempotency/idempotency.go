@@ -0,0 +1,220 @@
+// Package idempotency makes the services' write endpoints safe to retry.
+// A client that sends an Idempotency-Key header with a POST, PUT, PATCH or
+// DELETE gets the response of the first request with that key for every
+// retry of it, and the write happens once. Keys are scoped to the caller's
+// token and the service, and each is tied to a fingerprint of the request
+// (method, path, query and body): reusing a key for a different request is
+// refused with 422, and retrying while the first attempt is still running
+// with 409.
+//
+// Responses are kept for IDEMPOTENCY_TTL, in Redis at IDEMPOTENCY_STORE_URL
+// so that every replica honours a key, or in memory for a single instance.
+// Server errors (5xx) are not kept, so a request that failed can be retried
+// for real. Requests without the header are served as before.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"shared/config"
+)
+
+// Settings are what a service that honours idempotency keys takes.
+var Settings = []config.Setting{
+	{Name: "IDEMPOTENCY_STORE_URL", Usage: "Redis URL idempotency keys are shared through between replicas; unset keeps them in memory", Check: config.ValidURL},
+	{Name: "IDEMPOTENCY_TTL", Default: "24h", Usage: "How long a response is kept for retries with the same Idempotency-Key", Check: config.ValidDuration},
+}
+
+// Header is the request header that carries the key.
+const Header = "Idempotency-Key"
+
+const (
+	maxKeyLength = 255
+	// Room for the largest body any service takes, the Grade Service's CSV
+	// bulk upload
+	maxBodyBytes = 5 << 20
+	// A first attempt that has not answered by then is taken to have died
+	inFlightLease = time.Minute
+)
+
+// record is what is kept per key: the request's fingerprint and, once the
+// first attempt has answered, its response.
+type record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+type store interface {
+	// reserve claims key for a first attempt. If the key is taken it
+	// returns the record already there instead.
+	reserve(ctx context.Context, key string, rec record, lease time.Duration) (*record, error)
+	// complete replaces the reservation with the answer.
+	complete(ctx context.Context, key string, rec record, ttl time.Duration) error
+	// release drops a reservation, so the key can be used again.
+	release(ctx context.Context, key string) error
+	close() error
+}
+
+var (
+	service string
+	keys    store = newMemoryStore()
+)
+
+// Setup names the service keys are scoped to and connects to the shared
+// store, if one is configured. It must run before the server starts.
+func Setup(name string) {
+	service = name
+	url := config.Get("IDEMPOTENCY_STORE_URL")
+	if url == "" {
+		return
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		log.Fatalf("invalid IDEMPOTENCY_STORE_URL: %v", err)
+	}
+	keys = redisStore{client: redis.NewClient(opts)}
+	slog.Info("idempotency keys: shared", "addr", opts.Addr)
+}
+
+// Close disconnects from the shared store at shutdown.
+func Close(ctx context.Context) error {
+	return keys.close()
+}
+
+// Middleware honours Idempotency-Key on write requests. It changes the
+// request in place and never copies it, so it may sit between
+// logging.Middleware and the ServeMux.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Cannot read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		storeKey := scopedKey(r, key)
+		fingerprint := fingerprintOf(r, body)
+		first, err := keys.reserve(ctx, storeKey, record{Fingerprint: fingerprint}, inFlightLease)
+		if err != nil {
+			slog.ErrorContext(ctx, "idempotency store: reserve", "err", err)
+			http.Error(w, "Idempotency store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		switch {
+		case first == nil:
+			// This is the first attempt
+		case first.Fingerprint != fingerprint:
+			http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+			return
+		case !first.Done:
+			http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			return
+		default:
+			replay(w, first)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// The request's context may be over by now; the answer must still be kept
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+		defer cancel()
+		if rec.status >= 500 {
+			err = keys.release(ctx, storeKey)
+		} else {
+			err = keys.complete(ctx, storeKey, record{Fingerprint: fingerprint, Done: true, Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}, config.Duration("IDEMPOTENCY_TTL"))
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "idempotency store: cannot keep the response; retries will wait out the lease", "err", err)
+		}
+	})
+}
+
+// scopedKey keeps callers, and services sharing one store, from seeing each
+// other's keys. The token is hashed rather than stored.
+func scopedKey(r *http.Request, key string) string {
+	h := sha256.Sum256([]byte(r.Header.Get("Authorization") + "\x00" + key))
+	return "idempotency:" + service + ":" + hex.EncodeToString(h[:])
+}
+
+func fingerprintOf(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\x00")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a kept response, marked as a replay.
+func replay(w http.ResponseWriter, rec *record) {
+	for name, values := range rec.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Body)
+}
+
+// recorder passes the response through and keeps a copy of it. Headers are
+// taken as they stand when the status is written, less the request ID,
+// which belongs to each attempt.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.wroteHeader = true
+		rec.status = code
+		rec.header = rec.ResponseWriter.Header().Clone()
+		rec.header.Del("X-Request-ID")
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
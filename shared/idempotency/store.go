@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// --- Stores ---
+
+// memoryStore keeps keys in this process, which is enough for a single
+// instance. Every write also queues the key by when it expires, so expired
+// keys are dropped from the front of the queue without scanning the rest.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	expiry  expiryQueue
+}
+
+type memoryRecord struct {
+	record
+	expires time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]memoryRecord)}
+}
+
+// put keeps rec until expires. Callers must hold s.mu.
+func (s *memoryStore) put(key string, rec record, expires time.Time) {
+	s.records[key] = memoryRecord{record: rec, expires: expires}
+	heap.Push(&s.expiry, queuedKey{key: key, expires: expires})
+}
+
+// expire drops the keys that have expired by now. A queued entry whose key
+// was since rewritten or released is skipped. Callers must hold s.mu.
+func (s *memoryStore) expire(now time.Time) {
+	for s.expiry.Len() > 0 && now.After(s.expiry[0].expires) {
+		q := heap.Pop(&s.expiry).(queuedKey)
+		if r, ok := s.records[q.key]; ok && r.expires.Equal(q.expires) {
+			delete(s.records, q.key)
+		}
+	}
+}
+
+func (s *memoryStore) reserve(ctx context.Context, key string, rec record, lease time.Duration) (*record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expire(now)
+	if existing, ok := s.records[key]; ok {
+		return &existing.record, nil
+	}
+	s.put(key, rec, now.Add(lease))
+	return nil, nil
+}
+
+func (s *memoryStore) complete(ctx context.Context, key string, rec record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(key, rec, time.Now().Add(ttl))
+	return nil
+}
+
+func (s *memoryStore) release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memoryStore) close() error {
+	return nil
+}
+
+// expiryQueue is a min-heap of keys by expiry, for container/heap.
+type expiryQueue []queuedKey
+
+type queuedKey struct {
+	key     string
+	expires time.Time
+}
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x any)        { *q = append(*q, x.(queuedKey)) }
+
+func (q *expiryQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// redisStore keeps each key as one JSON value that expires with it, so
+// every replica pointed at the same Redis honours the same keys.
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s redisStore) reserve(ctx context.Context, key string, rec record, lease time.Duration) (*record, error) {
+	value, _ := json.Marshal(rec)
+	ok, err := s.client.SetNX(ctx, key, value, lease).Result()
+	if err != nil || ok {
+		return nil, err
+	}
+	raw, err := s.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		// Expired in between; take it again
+		return s.reserve(ctx, key, rec, lease)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var existing record
+	if err := json.Unmarshal(raw, &existing); err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s redisStore) complete(ctx context.Context, key string, rec record, ttl time.Duration) error {
+	value, _ := json.Marshal(rec)
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s redisStore) release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+func (s redisStore) close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,168 @@
+// Package logging sets the services up to log JSON lines through log/slog,
+// one per request with its ID, user, route, status and latency, plus
+// whatever the handlers log along the way.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"shared/config"
+)
+
+// Settings are the logging settings every service takes.
+var Settings = []config.Setting{
+	{Name: "LOG_LEVEL", Default: "info", Usage: "Lowest level logged: debug, info, warn or error", Check: func(v string) error {
+		var level slog.Level
+		if level.UnmarshalText([]byte(v)) != nil {
+			return fmt.Errorf("want debug, info, warn or error")
+		}
+		return nil
+	}},
+}
+
+// Setup makes a JSON logger tagged with the service's name the default, at
+// LOG_LEVEL. Anything still logged through the log package, such as a fatal
+// startup error, comes out at error level.
+func Setup(service string) {
+	var level slog.Level
+	level.UnmarshalText([]byte(config.Get("LOG_LEVEL")))
+	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(contextHandler{handler}).With("service", service))
+	slog.SetLogLoggerLevel(slog.LevelError)
+}
+
+// --- Request Context ---
+
+type ctxKey struct{}
+
+// requestInfo is what the middleware learns about a request while it runs.
+type requestInfo struct {
+	id   string
+	user string // Set by whoever authenticates the caller
+}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(ctxKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	if info := infoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// SetUser names the authenticated caller in the request's log line.
+func SetUser(ctx context.Context, username string) {
+	if info := infoFrom(ctx); info != nil {
+		info.user = username
+	}
+}
+
+// contextHandler adds the request ID to records logged with a request's
+// context, so a handler's errors can be matched to its request line, and
+// the trace ID when the request is being traced.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// --- Request Logging ---
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming responses working through the recorder.
+func (s *statusRecorder) Flush() {
+	http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Middleware logs one line per request. The request ID comes from the
+// caller's X-Request-ID, so a call between services keeps the ID of the
+// request that caused it, or is made up here; either way it is echoed back.
+// Health checks are logged at debug level to keep pollers out of the way.
+// It learns the route from the ServeMux, so nothing between the two may
+// copy the request, and it goes inside tracing.Middleware.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		info := &requestInfo{id: id}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, info))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern // Set by the ServeMux that handled the request
+		if route == "" {
+			route = r.URL.Path
+		} else {
+			// The request's span started before the route was known
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(attribute.String("http.route", route))
+		}
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case strings.HasSuffix(r.URL.Path, "/healthz") || strings.HasSuffix(r.URL.Path, "/readyz"):
+			level = slog.LevelDebug
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("user", info.user),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
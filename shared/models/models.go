@@ -0,0 +1,80 @@
+// Package models holds the JSON shapes the services exchange, so the one
+// that serves a resource and the ones that read it cannot drift apart.
+package models
+
+import "time"
+
+// Course is one course offering as the Course Service lists it.
+type Course struct {
+	ID         string `json:"id"`   // Unique per offering: the code, or code@term for later terms
+	Code       string `json:"code"` // Catalog code, the same in every term
+	Term       string `json:"term"`
+	Title      string `json:"title"`
+	Credits    int    `json:"credits"`
+	OpenSlots  int    `json:"open_slots"`
+	IsEnrolled bool   `json:"is_enrolled"`
+	Instructor string `json:"instructor,omitempty"`
+	Version    int    `json:"version"` // Bumped on every admin edit; send as If-Match when updating
+
+	DepartmentID string     `json:"department_id,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"` // Archived courses leave the active catalog
+
+	Capacity       int     `json:"capacity"`                  // Physical room size
+	OverbookFactor float64 `json:"overbook_factor,omitempty"` // e.g. 1.1 sells 110% of Capacity
+	PermitSeats    int     `json:"permit_seats,omitempty"`    // Added past the limit by permission numbers
+
+	Waitlisted       int `json:"waitlisted"`
+	WaitlistPosition int `json:"waitlist_position,omitempty"` // 0 when not on the list
+
+	CoRequisites []string `json:"co_requisites,omitempty"` // Sections that must be taken and dropped together
+	CrossListOf  string   `json:"cross_list_of,omitempty"` // Primary code whose seats this listing shares
+
+	Meetings []Meeting `json:"meetings,omitempty"` // Weekly class times
+}
+
+// Meeting is one weekly class time. Times are campus-local "15:04" clock
+// times.
+type Meeting struct {
+	Day   string `json:"day"`   // "Mon" to "Sun"
+	Start string `json:"start"` // e.g. "09:15"
+	End   string `json:"end"`
+	Room  string `json:"room,omitempty"`
+}
+
+// GradeRecord is one grade as the Grade Service stores and returns it.
+type GradeRecord struct {
+	StudentID string `json:"student_id"`
+	CourseID  string `json:"course_id"`
+	Grade     string `json:"grade"`
+	Term      string `json:"term,omitempty"` // The term the grade counts in
+	Type      string `json:"type,omitempty"` // "midterm" or "final" (the default)
+}
+
+// EnrollmentFailure is the body of a refused /enroll or /enroll-batch, with
+// a stable code clients can branch on next to the human-readable message:
+//
+//	{"code": "FULL", "message": "Course full", "course_id": "CCPROG2"}
+type EnrollmentFailure struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	CourseID string `json:"course_id,omitempty"`
+}
+
+// Enrollment failure codes.
+const (
+	CodeInvalidRequest    = "INVALID_REQUEST"
+	CodeNotFound          = "COURSE_NOT_FOUND"
+	CodeRegistrationEarly = "REGISTRATION_NOT_OPEN"
+	CodeDeadlinePassed    = "DEADLINE_PASSED"
+	CodeHoldPresent       = "HOLD_PRESENT"   // A registration hold (financial, advising, ...)
+	CodePrereqMissing     = "PREREQ_MISSING" // A required prerequisite or co-requisite is missing
+	CodeFull              = "FULL"
+	CodeAlreadyEnrolled   = "ALREADY_ENROLLED"
+	CodeSeatHeld          = "SEAT_HELD" // The student already holds a seat and must confirm it
+	CodeCreditLimit       = "CREDIT_LIMIT"
+	CodeArchived          = "COURSE_ARCHIVED"
+	CodePermissionInvalid = "PERMISSION_INVALID"
+	CodeHoldExpired       = "HOLD_EXPIRED"
+	CodePaymentDeclined   = "PAYMENT_DECLINED"
+	CodeUnavailable       = "SERVICE_UNAVAILABLE" // A service enrollment depends on did not answer
+)
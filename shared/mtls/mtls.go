@@ -0,0 +1,242 @@
+// Package mtls secures the calls between services with mutual TLS. When
+// MTLS_CERT_FILE, MTLS_KEY_FILE and MTLS_CA_FILE are set, a service's
+// internal server speaks HTTPS and refuses callers without a certificate
+// from the CA, except on the paths in MTLS_PUBLIC_PATHS, and every call it
+// makes through Transport presents its own certificate and trusts only
+// servers the CA vouches for. Calls to services outside, such as billing,
+// keep the default transport and the system's CAs.
+//
+// The files are read again when they change, so certificates can be
+// rotated in place without a restart. New connections use the new ones
+// within reloadInterval; open connections keep the old ones until they
+// close.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/config"
+	"shared/server"
+)
+
+// Settings are the mutual TLS settings every service takes.
+var Settings = []config.Setting{
+	{Name: "MTLS_CERT_FILE", Usage: "This service's certificate (PEM), for serving and calling the other services"},
+	{Name: "MTLS_KEY_FILE", Usage: "Private key of MTLS_CERT_FILE"},
+	{Name: "MTLS_CA_FILE", Usage: "CA certificates (PEM) that sign the services' certificates"},
+	{Name: "MTLS_PUBLIC_PATHS", Default: "/healthz,/readyz,/metrics,/public/,/oidc/,/.well-known/", Usage: "Paths served to callers without a certificate, comma-separated; a trailing / covers everything below"},
+}
+
+const reloadInterval = 10 * time.Second
+
+var (
+	// certs is nil while mutual TLS is off.
+	certs *certStore
+	// internal carries the calls made through Transport: the default
+	// transport while mutual TLS is off.
+	internal    http.RoundTripper
+	publicPaths []string
+)
+
+// Transport is for calls to the other services of the system. It presents
+// this service's certificate while mutual TLS is on, and is the default
+// transport otherwise.
+var Transport http.RoundTripper = internalTransport{}
+
+type internalTransport struct{}
+
+func (internalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if internal == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return internal.RoundTrip(req)
+}
+
+// Wrap puts wrap around the transport the internal calls go through while
+// mutual TLS is on, as tracing.Setup does for its spans. Without mutual TLS
+// they go through the default transport, which the caller wraps itself.
+func Wrap(wrap func(http.RoundTripper) http.RoundTripper) {
+	if internal != nil {
+		internal = wrap(internal)
+	}
+}
+
+// Setup turns mutual TLS on if it is configured, and makes Transport present
+// this service's certificate. It must run before tracing.Setup wraps the
+// transports.
+func Setup() {
+	publicPaths = nil
+	for _, path := range strings.Split(config.Get("MTLS_PUBLIC_PATHS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			publicPaths = append(publicPaths, path)
+		}
+	}
+	certFile, keyFile, caFile := config.Get("MTLS_CERT_FILE"), config.Get("MTLS_KEY_FILE"), config.Get("MTLS_CA_FILE")
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		log.Fatalf("MTLS_CERT_FILE, MTLS_KEY_FILE and MTLS_CA_FILE must be set together")
+	}
+	store := &certStore{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := store.load(); err != nil {
+		log.Fatalf("cannot load the mutual TLS certificates: %v", err)
+	}
+	certs = store
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = dialTLS
+	internal = transport
+	slog.Info("mutual TLS on", "cert", certFile, "ca", caFile)
+}
+
+// Enabled reports whether mutual TLS is on.
+func Enabled() bool {
+	return certs != nil
+}
+
+// dialTLS opens a connection to another service, checking its certificate
+// against the current CA.
+func dialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	cert, pool := certs.current()
+	dialer := &tls.Dialer{Config: &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		ServerName:   host,
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{*cert},
+	}}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// --- Serving ---
+
+// Run serves srv like server.Run, over mutual TLS when it is on. A caller
+// may connect without a certificate, for the health probes; Middleware
+// turns it away from everything else.
+func Run(srv *http.Server) error {
+	if certs == nil {
+		return server.Run(srv)
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := certs.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+			}, nil
+		},
+	}
+	return server.RunTLS(srv, "", "")
+}
+
+// Middleware refuses requests from callers without a verified certificate
+// while mutual TLS is on, except on MTLS_PUBLIC_PATHS: the probes, metrics
+// scrapes, and the routes meant for callers outside the system, which
+// authenticate by token as before.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if certs == nil || isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "Unauthorized: Client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPublic reports whether path is one of MTLS_PUBLIC_PATHS or below one
+// that ends in /.
+func isPublic(path string) bool {
+	for _, p := range publicPaths {
+		if path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Certificate Reloading ---
+
+type certStore struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.Mutex
+	checked time.Time
+	stamp   string // The files' modification times when last loaded
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+}
+
+// current returns the certificate and CA pool, reloading them first if the
+// files have changed since they were last checked.
+func (s *certStore) current() (*tls.Certificate, *x509.CertPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checked) >= reloadInterval {
+		s.checked = time.Now()
+		if stamp := s.modStamp(); stamp != s.stamp {
+			if err := s.loadLocked(); err != nil {
+				slog.Error("mutual TLS: keeping the old certificates", "err", err)
+			} else {
+				slog.Info("mutual TLS: certificates reloaded")
+			}
+		}
+	}
+	return s.cert, s.pool
+}
+
+func (s *certStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked = time.Now()
+	return s.loadLocked()
+}
+
+func (s *certStore) loadLocked() error {
+	stamp := s.modStamp()
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+	caPEM, err := os.ReadFile(s.caFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates in %s", s.caFile)
+	}
+	s.cert, s.pool, s.stamp = &cert, pool, stamp
+	return nil
+}
+
+func (s *certStore) modStamp() string {
+	var stamp string
+	for _, path := range []string{s.certFile, s.keyFile, s.caFile} {
+		if info, err := os.Stat(path); err == nil {
+			stamp += info.ModTime().String() + ";"
+		}
+	}
+	return stamp
+}
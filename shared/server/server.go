@@ -0,0 +1,98 @@
+// Package server runs a service's HTTP server until the process is told to
+// stop, then shuts it down without dropping the requests it is serving.
+//
+// On SIGINT or SIGTERM the server stops accepting connections, waits for
+// the requests in flight to finish and then runs the service's cleanup
+// hooks, such as flushing queued events and closing its stores, all within
+// SHUTDOWN_TIMEOUT. A second signal kills the process at once.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"shared/config"
+)
+
+// Settings are the server settings every service takes.
+var Settings = []config.Setting{
+	{Name: "SHUTDOWN_TIMEOUT", Default: "15s", Usage: "How long shutting down may take", Check: config.ValidDuration},
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   []hook
+
+	stopping = make(chan struct{})
+)
+
+// OnShutdown adds a cleanup hook, run once the server has drained. Hooks
+// run one at a time in the order they were added; one that fails is logged
+// and the rest still run. ctx ends at the shutdown deadline.
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	hooksMu.Lock()
+	hooks = append(hooks, hook{name: name, fn: fn})
+	hooksMu.Unlock()
+}
+
+// Stopping is closed when shutdown begins. Responses that only end when the
+// client leaves, such as event streams, must end on it too, or the server
+// waits out the whole timeout for them.
+func Stopping() <-chan struct{} {
+	return stopping
+}
+
+// Run serves srv over HTTP until shutdown. It returns nil once the server
+// has shut down, or why it could not serve.
+func Run(srv *http.Server) error {
+	return run(srv, srv.ListenAndServe)
+}
+
+// RunTLS is Run over HTTPS.
+func RunTLS(srv *http.Server, certFile, keyFile string) error {
+	return run(srv, func() error { return srv.ListenAndServeTLS(certFile, keyFile) })
+}
+
+func run(srv *http.Server, listen func() error) error {
+	timeout := config.Duration("SHUTDOWN_TIMEOUT")
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, 1)
+	go func() { failed <- listen() }()
+	select {
+	case err := <-failed:
+		return err
+	case <-signals.Done():
+	}
+	stop() // A second signal now kills the process
+
+	slog.Info("shutting down", "timeout", timeout.String())
+	close(stopping)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("shutdown timed out with requests in flight", "err", err)
+		srv.Close()
+	}
+
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			slog.Error("shutdown hook failed", "hook", h.name, "err", err)
+		}
+	}
+	slog.Info("shut down")
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package studentid knows what a valid student ID looks like for each
+// tenant, so that every service refuses the same malformed IDs.
+package studentid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"shared/config"
+)
+
+// Settings are what a service that checks student IDs takes.
+var Settings = []config.Setting{
+	{Name: "STUDENT_ID_FORMATS", Default: DefaultFormats, Usage: "JSON object of tenant to student ID format", Check: valid},
+}
+
+// Format describes what a valid student ID looks like for one tenant.
+// CheckDigit is either empty or "luhn"; when set, the last digit of the ID
+// must be the Luhn check digit of the digits before it. Prefix and Width
+// are how the Auth Service builds the IDs it issues.
+type Format struct {
+	Pattern    string `json:"pattern"`
+	CheckDigit string `json:"check_digit,omitempty"`
+	Prefix     string `json:"prefix,omitempty"`
+	Width      int    `json:"width,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// DefaultFormats accepts the legacy "student<N>" accounts.
+const DefaultFormats = `{"default": {"pattern": "^student[0-9]+$", "prefix": "student"}}`
+
+var formats map[string]Format // Key: tenant, set by Setup
+
+// parse reads a JSON object of tenant -> Format and compiles each pattern.
+func parse(raw string) (map[string]Format, error) {
+	parsed := map[string]Format{}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, err
+	}
+	for tenant, f := range parsed {
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern for tenant %q: %v", tenant, err)
+		}
+		if f.CheckDigit != "" && f.CheckDigit != "luhn" {
+			return nil, fmt.Errorf("unknown check digit scheme %q for tenant %q", f.CheckDigit, tenant)
+		}
+		f.re = re
+		parsed[tenant] = f
+	}
+	return parsed, nil
+}
+
+func valid(raw string) error {
+	_, err := parse(raw)
+	return err
+}
+
+// Setup applies STUDENT_ID_FORMATS. It must run before the server starts.
+func Setup() {
+	formats, _ = parse(config.Get("STUDENT_ID_FORMATS"))
+}
+
+// Lookup returns a tenant's format.
+func Lookup(tenant string) (Format, bool) {
+	f, ok := formats[tenant]
+	return f, ok
+}
+
+// Tenant is the tenant a request is for, from its X-Tenant-ID header.
+func Tenant(r *http.Request) string {
+	if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" {
+		return tenant
+	}
+	return "default"
+}
+
+// Validate checks id against its tenant's format.
+func Validate(tenant, id string) error {
+	f, ok := formats[tenant]
+	if !ok {
+		return fmt.Errorf("unknown tenant %q", tenant)
+	}
+	if !f.re.MatchString(id) {
+		return fmt.Errorf("student ID %q does not match the %s format", id, tenant)
+	}
+	if f.CheckDigit == "luhn" && !LuhnValid(id) {
+		return fmt.Errorf("student ID %q has an invalid check digit", id)
+	}
+	return nil
+}
+
+// LuhnValid reports whether the trailing digit of s is its Luhn check digit.
+func LuhnValid(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// LuhnDigit returns the check digit that makes payload+digit pass LuhnValid.
+func LuhnDigit(payload string) byte {
+	for d := byte('0'); d <= '9'; d++ {
+		if LuhnValid(payload + string(d)) {
+			return d
+		}
+	}
+	return '0'
+}
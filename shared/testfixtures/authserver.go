@@ -0,0 +1,96 @@
+package testfixtures
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"shared/auth"
+)
+
+// AuthServer is an in-process stand-in for the Auth Service. It speaks the
+// same /login, /validate and /readyz contract, with opaque tokens instead of
+// JWTs, so services under test can point AUTH_SERVICE_URL at it.
+type AuthServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	users  map[string]User
+	tokens map[string]User
+}
+
+// NewAuthServer starts a fake Auth Service that knows the given users.
+// Callers must Close it.
+func NewAuthServer(users ...User) *AuthServer {
+	a := &AuthServer{users: map[string]User{}, tokens: map[string]User{}}
+	for _, u := range users {
+		a.users[u.Username] = u
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", a.login)
+	mux.HandleFunc("/validate", a.validate)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "ok", "dependencies": {}}`))
+	})
+	a.Server = httptest.NewServer(mux)
+	return a
+}
+
+// TokenFor issues a token for a user without going through /login, adding
+// the user if it is not known yet.
+func (a *AuthServer) TokenFor(u User) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users[u.Username] = u
+	a.tokens[token] = u
+	return token
+}
+
+// BearerFor returns a ready-to-use Authorization header value.
+func (a *AuthServer) BearerFor(u User) string {
+	return "Bearer " + a.TokenFor(u)
+}
+
+func (a *AuthServer) login(w http.ResponseWriter, r *http.Request) {
+	var creds User
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	u, ok := a.users[creds.Username]
+	a.mu.Unlock()
+	if !ok || u.Password != creds.Password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": a.TokenFor(u), "role": u.Role})
+}
+
+func (a *AuthServer) validate(w http.ResponseWriter, r *http.Request) {
+	token, _ := auth.BearerToken(r)
+	a.mu.Lock()
+	u, ok := a.tokens[token]
+	a.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.User{
+		Status:    "valid",
+		Username:  u.Username,
+		Role:      u.Role,
+		Program:   u.Program,
+		YearLevel: u.YearLevel,
+	})
+}
@@ -0,0 +1,167 @@
+// Package testfixtures provides builders for the enrollment system's users,
+// courses, sections, enrollments and grades, plus a fake Auth Service, so
+// service tests and cross-service integration tests do not have to spell out
+// literals by hand. It is part of the shared module, which every service
+// already requires.
+//
+// Builders fill every field with a unique, valid default and let tests
+// override only what they care about:
+//
+//	student := testfixtures.NewUser().Student().WithProgram("BSCS").Build()
+//	course := testfixtures.NewCourse().WithSlots(1).TaughtBy("faculty1").Build()
+//	grade := testfixtures.NewGrade().For(student, course).WithGrade("3.5").Build()
+package testfixtures
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"shared/models"
+)
+
+// User is an account as the Auth Service keeps it.
+type User struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Role      string `json:"role"`
+	Program   string `json:"program,omitempty"`
+	YearLevel int    `json:"year_level,omitempty"`
+}
+
+// Enrollment is one student in one course.
+type Enrollment struct {
+	CourseID  string `json:"course_id"`
+	StudentID string `json:"student_id"`
+}
+
+var seq atomic.Int64
+
+// next returns a process-wide unique number so fixtures never collide.
+func next() int64 {
+	return seq.Add(1)
+}
+
+// --- Users ---
+
+type UserBuilder struct{ u User }
+
+// NewUser starts a student with a unique "student<N>" username, which
+// passes the default student ID format.
+func NewUser() *UserBuilder {
+	return &UserBuilder{u: User{
+		Username:  fmt.Sprintf("student%d", 1000+next()),
+		Password:  "pass123",
+		Role:      "student",
+		Program:   "BSCS",
+		YearLevel: 1,
+	}}
+}
+
+func (b *UserBuilder) Student() *UserBuilder   { b.u.Role = "student"; return b }
+func (b *UserBuilder) Faculty() *UserBuilder   { return b.staff("faculty") }
+func (b *UserBuilder) Registrar() *UserBuilder { return b.staff("registrar") }
+func (b *UserBuilder) Admin() *UserBuilder     { return b.staff("admin") }
+
+// staff turns the user into a staff account named after its role.
+func (b *UserBuilder) staff(role string) *UserBuilder {
+	b.u.Role = role
+	b.u.Username = fmt.Sprintf("%s%d", role, 1000+next())
+	b.u.Program, b.u.YearLevel = "", 0
+	return b
+}
+
+func (b *UserBuilder) WithUsername(name string) *UserBuilder { b.u.Username = name; return b }
+func (b *UserBuilder) WithPassword(pw string) *UserBuilder   { b.u.Password = pw; return b }
+func (b *UserBuilder) WithProgram(p string) *UserBuilder     { b.u.Program = p; return b }
+func (b *UserBuilder) WithYearLevel(y int) *UserBuilder      { b.u.YearLevel = y; return b }
+func (b *UserBuilder) Build() User                           { return b.u }
+
+// --- Courses and Sections ---
+
+type CourseBuilder struct{ c models.Course }
+
+// NewCourse starts a 3-credit course in the "test" term with a unique ID
+// and 30 seats, all open.
+func NewCourse() *CourseBuilder {
+	n := next()
+	id := fmt.Sprintf("TEST%03d", n)
+	return &CourseBuilder{c: models.Course{
+		ID:        id,
+		Code:      id,
+		Term:      "test",
+		Title:     fmt.Sprintf("Test Course %d", n),
+		Credits:   3,
+		Capacity:  30,
+		OpenSlots: 30,
+	}}
+}
+
+func (b *CourseBuilder) WithID(id string) *CourseBuilder      { b.c.ID, b.c.Code = id, id; return b }
+func (b *CourseBuilder) WithTitle(t string) *CourseBuilder    { b.c.Title = t; return b }
+func (b *CourseBuilder) InTerm(term string) *CourseBuilder    { b.c.Term = term; return b }
+func (b *CourseBuilder) WithCredits(n int) *CourseBuilder     { b.c.Credits = n; return b }
+func (b *CourseBuilder) TaughtBy(instr string) *CourseBuilder { b.c.Instructor = instr; return b }
+
+// WithSlots sizes the course to n seats, all open.
+func (b *CourseBuilder) WithSlots(n int) *CourseBuilder {
+	b.c.Capacity, b.c.OpenSlots = n, n
+	return b
+}
+
+// Full leaves the course's seats as they are but none of them open.
+func (b *CourseBuilder) Full() *CourseBuilder { b.c.OpenSlots = 0; return b }
+
+func (b *CourseBuilder) WithCoRequisites(ids ...string) *CourseBuilder {
+	b.c.CoRequisites = append(b.c.CoRequisites, ids...)
+	return b
+}
+
+func (b *CourseBuilder) Build() models.Course { return b.c }
+
+// NewSection builds a section of a parent course, e.g. "CCPROG2-S11".
+func NewSection(parent models.Course, section string) *CourseBuilder {
+	b := &CourseBuilder{c: parent}
+	b.c.ID = parent.ID + "-" + section
+	b.c.Title = parent.Title + " (" + section + ")"
+	b.c.CoRequisites = nil
+	return b
+}
+
+// LectureWithLab builds a lecture and its 1-credit lab as mutual co-requisites.
+func LectureWithLab() (lecture, lab models.Course) {
+	lecture = NewCourse().WithCredits(3).Build()
+	lab = NewCourse().WithID(lecture.ID + "L").WithTitle(lecture.Title + " Laboratory").WithCredits(1).Build()
+	lecture.CoRequisites = []string{lab.ID}
+	lab.CoRequisites = []string{lecture.ID}
+	return lecture, lab
+}
+
+// --- Enrollments and Grades ---
+
+func NewEnrollment(student User, course models.Course) Enrollment {
+	return Enrollment{CourseID: course.ID, StudentID: student.Username}
+}
+
+type GradeBuilder struct{ g models.GradeRecord }
+
+// NewGrade starts a final 4.0 for a fresh student and course.
+func NewGrade() *GradeBuilder {
+	course := NewCourse().Build()
+	return &GradeBuilder{g: models.GradeRecord{
+		StudentID: NewUser().Build().Username,
+		CourseID:  course.ID,
+		Grade:     "4.0",
+		Term:      course.Term,
+		Type:      "final",
+	}}
+}
+
+func (b *GradeBuilder) For(student User, course models.Course) *GradeBuilder {
+	b.g.StudentID = student.Username
+	b.g.CourseID = course.ID
+	b.g.Term = course.Term
+	return b
+}
+func (b *GradeBuilder) WithGrade(g string) *GradeBuilder { b.g.Grade = g; return b }
+func (b *GradeBuilder) Midterm() *GradeBuilder           { b.g.Type = "midterm"; return b }
+func (b *GradeBuilder) Build() models.GradeRecord        { return b.g }
@@ -0,0 +1,57 @@
+package testfixtures
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"shared/auth"
+	"shared/config"
+	"shared/studentid"
+)
+
+// authServer answers every test's token checks.
+var authServer *AuthServer
+
+func TestMain(m *testing.M) {
+	authServer = NewAuthServer()
+	os.Setenv("AUTH_SERVICE_URL", authServer.URL)
+	os.Setenv("AUTH_CACHE_TTL", "0")
+	config.Load("testfixtures", append(auth.Settings, studentid.Settings...)...)
+	studentid.Setup()
+	code := m.Run()
+	authServer.Close()
+	os.Exit(code)
+}
+
+func TestBuildersAreUniqueAndValid(t *testing.T) {
+	a, b := NewUser().Build(), NewUser().Build()
+	if a.Username == b.Username {
+		t.Fatalf("two users share the username %q", a.Username)
+	}
+	if err := studentid.Validate("default", a.Username); err != nil {
+		t.Fatalf("default student ID is invalid: %v", err)
+	}
+	if c, d := NewCourse().Build(), NewCourse().Build(); c.ID == d.ID {
+		t.Fatalf("two courses share the ID %q", c.ID)
+	}
+
+	lecture, lab := LectureWithLab()
+	if len(lecture.CoRequisites) != 1 || lecture.CoRequisites[0] != lab.ID || lab.CoRequisites[0] != lecture.ID {
+		t.Fatalf("lecture %v and lab %v are not mutual co-requisites", lecture.CoRequisites, lab.CoRequisites)
+	}
+	if g := NewGrade().For(a, lab).Build(); g.StudentID != a.Username || g.CourseID != lab.ID || g.Term != lab.Term {
+		t.Fatalf("grade %+v is not for %s in %s", g, a.Username, lab.ID)
+	}
+}
+
+func TestAuthServerValidatesItsTokens(t *testing.T) {
+	faculty := NewUser().Faculty().Build()
+	user, ok := auth.Validate(context.Background(), authServer.TokenFor(faculty))
+	if !ok || user.Username != faculty.Username || user.Role != "faculty" {
+		t.Fatalf("Validate = %+v, %v; want %s as faculty", user, ok, faculty.Username)
+	}
+	if _, ok := auth.Validate(context.Background(), "not-a-token"); ok {
+		t.Fatal("an unknown token was accepted")
+	}
+}
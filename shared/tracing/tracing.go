@@ -0,0 +1,73 @@
+// Package tracing follows a request across the services with OpenTelemetry:
+// a span for every request served and every call made, joined up by the W3C
+// traceparent header the calls carry.
+package tracing
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"shared/mtls"
+)
+
+// Setup starts tracing for service. Spans are exported over OTLP/HTTP when
+// OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) names
+// a collector; the other standard OTEL_* variables, such as
+// OTEL_SERVICE_NAME and OTEL_TRACES_SAMPLER, apply as usual. Without a
+// collector nothing is recorded, but trace context is still passed on, so
+// a traced caller's trace continues past this service. Every client that
+// uses the default transport or mtls.Transport, as the services' clients
+// do, gets a span per call and passes the trace on.
+func Setup(service string) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	http.DefaultTransport = otelhttp.NewTransport(http.DefaultTransport)
+	mtls.Wrap(func(rt http.RoundTripper) http.RoundTripper { return otelhttp.NewTransport(rt) })
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return
+	}
+	ctx := context.Background()
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		log.Fatalf("cannot set up the OTLP trace exporter: %v", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	)
+	if err != nil {
+		log.Fatalf("invalid OTEL_RESOURCE_ATTRIBUTES: %v", err)
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+}
+
+// provider is the exporting tracer provider, nil without a collector.
+var provider *sdktrace.TracerProvider
+
+// Shutdown exports the spans still batched and stops tracing.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Middleware gives every request served a span, named after the route that
+// handled it, as the child of the caller's span if it sent a traceparent.
+func Middleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "request")
+}